@@ -0,0 +1,157 @@
+// Package cloudevents encodes the fruit events as CloudEvents 1.0 in
+// structured JSON mode, the envelope carries the attributes and the
+// versioned payload goes in data.
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// SpecVersion is the CloudEvents version of the envelopes.
+const SpecVersion = "1.0"
+
+// dataContentType is the media type of the data of the envelopes.
+const dataContentType = "application/json"
+
+// Event types, the suffix is the major version of the payload. A payload
+// only gains optional fields within a version, any other change is a new
+// type so consumers of the previous one keep working.
+const (
+	FruitCreatedV1Type = "com.github.fernandoocampo.fruits.fruit.created.v1"
+	FruitUpdatedV1Type = "com.github.fernandoocampo.fruits.fruit.updated.v1"
+	FruitDeletedV1Type = "com.github.fernandoocampo.fruits.fruit.deleted.v1"
+)
+
+// schemaBaseURL is where the JSON schemas of the payloads are published.
+const schemaBaseURL = "https://raw.githubusercontent.com/fernandoocampo/fruits/main/internal/adapter/cloudevents/schemas/"
+
+// JSON schemas of the payloads of every event type.
+const (
+	FruitCreatedV1Schema = schemaBaseURL + "fruit.created.v1.json"
+	FruitUpdatedV1Schema = schemaBaseURL + "fruit.updated.v1.json"
+	FruitDeletedV1Schema = schemaBaseURL + "fruit.deleted.v1.json"
+)
+
+var (
+	// ErrUnknownEventType is returned for events whose type has no payload.
+	ErrUnknownEventType = errors.New("unknown event type")
+	// ErrUnsupportedSpecVersion is returned for envelopes of another CloudEvents version.
+	ErrUnsupportedSpecVersion = errors.New("unsupported cloudevents spec version")
+	// ErrMissingData is returned for events without the data of their type.
+	ErrMissingData = errors.New("event has no data")
+)
+
+// Envelope is a CloudEvent in structured JSON mode.
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// New returns the envelope of the event, source identifies the service that
+// produced it.
+func New(event repository.Event, source string) (Envelope, error) {
+	eventType, schema, payload, err := payloadOf(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            eventType,
+		Time:            event.OccurredAt.UTC(),
+		DataContentType: dataContentType,
+		DataSchema:      schema,
+		Data:            data,
+	}, nil
+}
+
+// Marshal returns the event encoded as a structured JSON CloudEvent.
+func Marshal(event repository.Event, source string) ([]byte, error) {
+	envelope, err := New(event, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope)
+}
+
+// Unmarshal decodes a structured JSON CloudEvent. Fields of the payload it
+// does not know are ignored, so messages of newer producers can be read.
+func Unmarshal(message []byte) (repository.Event, error) {
+	var envelope Envelope
+
+	err := json.Unmarshal(message, &envelope)
+	if err != nil {
+		return repository.Event{}, err
+	}
+
+	if envelope.SpecVersion != SpecVersion {
+		return repository.Event{}, fmt.Errorf("%q: %w", envelope.SpecVersion, ErrUnsupportedSpecVersion)
+	}
+
+	event := repository.Event{
+		ID:         envelope.ID,
+		OccurredAt: envelope.Time,
+	}
+
+	switch envelope.Type {
+	case FruitCreatedV1Type:
+		var payload FruitCreatedV1
+
+		err = json.Unmarshal(envelope.Data, &payload)
+		event.Type, event.FruitCreated = repository.EventFruitCreated, payload.toNewFruitEvent()
+	case FruitUpdatedV1Type:
+		var payload FruitUpdatedV1
+
+		err = json.Unmarshal(envelope.Data, &payload)
+		event.Type, event.FruitUpdated = repository.EventFruitUpdated, payload.toFruitUpdatedEvent()
+	case FruitDeletedV1Type:
+		var payload FruitDeletedV1
+
+		err = json.Unmarshal(envelope.Data, &payload)
+		event.Type, event.FruitDeleted = repository.EventFruitDeleted, payload.toFruitDeletedEvent()
+	default:
+		return repository.Event{}, fmt.Errorf("%q: %w", envelope.Type, ErrUnknownEventType)
+	}
+
+	if err != nil {
+		return repository.Event{}, err
+	}
+
+	return event, nil
+}
+
+// payloadOf returns the CloudEvents type of the event, the schema of its
+// data and the versioned payload that goes in it.
+func payloadOf(event repository.Event) (string, string, interface{}, error) {
+	switch {
+	case event.Type == repository.EventFruitCreated && event.FruitCreated != nil:
+		return FruitCreatedV1Type, FruitCreatedV1Schema, newFruitCreatedV1(*event.FruitCreated), nil
+	case event.Type == repository.EventFruitUpdated && event.FruitUpdated != nil:
+		return FruitUpdatedV1Type, FruitUpdatedV1Schema, newFruitUpdatedV1(*event.FruitUpdated), nil
+	case event.Type == repository.EventFruitDeleted && event.FruitDeleted != nil:
+		return FruitDeletedV1Type, FruitDeletedV1Schema, newFruitDeletedV1(*event.FruitDeleted), nil
+	case event.Type == repository.EventFruitCreated, event.Type == repository.EventFruitUpdated, event.Type == repository.EventFruitDeleted:
+		return "", "", nil, fmt.Errorf("%s: %w", event.Type, ErrMissingData)
+	}
+
+	return "", "", nil, fmt.Errorf("%q: %w", event.Type, ErrUnknownEventType)
+}
@@ -0,0 +1,389 @@
+package cloudevents_test
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/cloudevents"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const source = "https://github.com/fernandoocampo/fruits"
+
+// TestMarshal checks the envelope attributes, the data may gain fields so it
+// is checked by TestOldConsumersReadCurrentPayload.
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		event    repository.Event
+		expected string
+	}{
+		"fruit_created": {
+			event:    newMangoCreated(1),
+			expected: "testdata/fruit.created.v1.json",
+		},
+		"fruit_updated": {
+			event:    newMangoUpdated(),
+			expected: "testdata/fruit.updated.v1.json",
+		},
+		"fruit_deleted": {
+			event:    newMangoDeleted(),
+			expected: "testdata/fruit.deleted.v1.json",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			var expectedEnvelope, got cloudevents.Envelope
+
+			assert.NoError(st, json.Unmarshal(readFile(st, test.expected), &expectedEnvelope))
+
+			message, err := cloudevents.Marshal(test.event, source)
+			assert.NoError(st, err)
+			assert.NoError(st, json.Unmarshal(message, &got))
+
+			expectedEnvelope.Data, got.Data = nil, nil
+
+			assert.Equal(st, expectedEnvelope, got)
+		})
+	}
+}
+
+func TestMarshalRejectsInvalidEvents(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		event repository.Event
+		want  error
+	}{
+		"unknown_type": {
+			event: repository.Event{ID: "1", Type: "fruit.peeled"},
+			want:  cloudevents.ErrUnknownEventType,
+		},
+		"created_without_data": {
+			event: repository.Event{ID: "1", Type: repository.EventFruitCreated},
+			want:  cloudevents.ErrMissingData,
+		},
+		"updated_with_data_of_other_type": {
+			event: repository.Event{ID: "1", Type: repository.EventFruitUpdated, FruitDeleted: &repository.FruitDeletedEvent{}},
+			want:  cloudevents.ErrMissingData,
+		},
+		"deleted_without_data": {
+			event: repository.Event{ID: "1", Type: repository.EventFruitDeleted},
+			want:  cloudevents.ErrMissingData,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := cloudevents.Marshal(test.event, source)
+
+			assert.ErrorIs(st, err, test.want)
+			assert.Nil(st, got)
+		})
+	}
+}
+
+// TestUnmarshalIsCompatible checks consumers built with this version read
+// the messages of older and newer producers of the same event type.
+func TestUnmarshalIsCompatible(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		message []byte
+		want    repository.Event
+		err     error
+	}{
+		"created_v1_without_version": {
+			message: readFile(t, "testdata/fruit.created.v1.json"),
+			want:    newMangoCreated(0),
+		},
+		"created_v1": {
+			message: addFields(t, readFile(t, "testdata/fruit.created.v1.json"), map[string]interface{}{
+				"version": 1,
+			}),
+			want: newMangoCreated(1),
+		},
+		"created_v1_with_new_fields": {
+			message: addFields(t, readFile(t, "testdata/fruit.created.v1.json"), map[string]interface{}{
+				"origin":   "Brazil",
+				"calories": 60,
+			}),
+			want: newMangoCreated(0),
+		},
+		"updated_v1": {
+			message: readFile(t, "testdata/fruit.updated.v1.json"),
+			want:    newMangoUpdated(),
+		},
+		"updated_v1_with_new_fields": {
+			message: addFields(t, readFile(t, "testdata/fruit.updated.v1.json"), map[string]interface{}{
+				"updated_by": "admin",
+			}),
+			want: newMangoUpdated(),
+		},
+		"deleted_v1": {
+			message: readFile(t, "testdata/fruit.deleted.v1.json"),
+			want:    newMangoDeleted(),
+		},
+		"deleted_v1_with_new_fields": {
+			message: addFields(t, readFile(t, "testdata/fruit.deleted.v1.json"), map[string]interface{}{
+				"reason": "duplicated",
+			}),
+			want: newMangoDeleted(),
+		},
+		"unknown_type": {
+			message: []byte(`{"specversion":"1.0","id":"1","type":"com.github.fernandoocampo.fruits.fruit.created.v2","data":{}}`),
+			err:     cloudevents.ErrUnknownEventType,
+		},
+		"other_spec_version": {
+			message: []byte(`{"specversion":"0.3","id":"1","type":"com.github.fernandoocampo.fruits.fruit.created.v1","data":{}}`),
+			err:     cloudevents.ErrUnsupportedSpecVersion,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := cloudevents.Unmarshal(test.message)
+
+			assert.ErrorIs(st, err, test.err)
+			assert.Equal(st, test.want, got)
+		})
+	}
+}
+
+// TestOldConsumersReadCurrentPayload checks the payload still has every
+// field the first consumers of the event type read, with the same JSON type.
+func TestOldConsumersReadCurrentPayload(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		event        repository.Event
+		firstVersion string
+	}{
+		"fruit_created_v1": {
+			event:        newMangoCreated(1),
+			firstVersion: "testdata/fruit.created.v1.json",
+		},
+		"fruit_updated_v1": {
+			event:        newMangoUpdated(),
+			firstVersion: "testdata/fruit.updated.v1.json",
+		},
+		"fruit_deleted_v1": {
+			event:        newMangoDeleted(),
+			firstVersion: "testdata/fruit.deleted.v1.json",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			message, err := cloudevents.Marshal(test.event, source)
+			assert.NoError(st, err)
+
+			firstFields := jsonTypes(st, dataOf(st, readFile(st, test.firstVersion)))
+			currentFields := jsonTypes(st, dataOf(st, message))
+
+			for field, fieldType := range firstFields {
+				assert.Equal(st, fieldType, currentFields[field], field)
+			}
+		})
+	}
+}
+
+// TestPayloadsMatchSchemas checks the published data schema describes the
+// payload that goes out and lets it gain fields.
+func TestPayloadsMatchSchemas(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		event  repository.Event
+		schema string
+	}{
+		"fruit_created_v1": {
+			event:  newMangoCreated(1),
+			schema: "schemas/fruit.created.v1.json",
+		},
+		"fruit_updated_v1": {
+			event:  newMangoUpdated(),
+			schema: "schemas/fruit.updated.v1.json",
+		},
+		"fruit_deleted_v1": {
+			event:  newMangoDeleted(),
+			schema: "schemas/fruit.deleted.v1.json",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			var schema struct {
+				ID         string   `json:"$id"`
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+				AdditionalProperties bool `json:"additionalProperties"`
+			}
+
+			assert.NoError(st, json.Unmarshal(readFile(st, test.schema), &schema))
+
+			envelope, err := cloudevents.New(test.event, source)
+			assert.NoError(st, err)
+			assert.Equal(st, schema.ID, envelope.DataSchema)
+			assert.True(st, schema.AdditionalProperties)
+
+			fields := jsonTypes(st, envelope.Data)
+
+			for _, field := range schema.Required {
+				assert.Contains(st, fields, field)
+			}
+
+			for field, fieldType := range fields {
+				schemaType := schema.Properties[field].Type
+				if schemaType == "number" && fieldType == "integer" {
+					continue
+				}
+
+				assert.Equal(st, schemaType, fieldType, field)
+			}
+		})
+	}
+}
+
+func newMangoCreated(version int64) repository.Event {
+	return repository.Event{
+		ID:         "8c1a6f0e-6f2f-4c43-9d5b-1f0c2e3d4a5b",
+		Type:       repository.EventFruitCreated,
+		OccurredAt: time.Date(2026, 10, 17, 8, 0, 0, 500000000, time.UTC),
+		FruitCreated: &repository.NewFruitEvent{
+			SourceID: "1",
+			Version:  version,
+			Name:     "Mango",
+			Variety:  "Tommy Atkins",
+			Price:    2.5,
+		},
+	}
+}
+
+func newMangoUpdated() repository.Event {
+	return repository.Event{
+		ID:         "5d0b2f7e-3c1a-4e8b-9f6d-2a4c6e8b0d1f",
+		Type:       repository.EventFruitUpdated,
+		OccurredAt: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+		FruitUpdated: &repository.FruitUpdatedEvent{
+			SourceID:      "1",
+			Version:       2,
+			ChangedFields: []string{"price", "variety"},
+			Before:        map[string]json.RawMessage{"price": json.RawMessage(`2.5`), "variety": json.RawMessage(`"Tommy Atkins"`)},
+			After:         map[string]json.RawMessage{"price": json.RawMessage(`3`), "variety": json.RawMessage(`"Kent"`)},
+		},
+	}
+}
+
+func newMangoDeleted() repository.Event {
+	return repository.Event{
+		ID:           "9e7c5a3b-1d2f-4a6b-8c0e-4f2a6c8e0b3d",
+		Type:         repository.EventFruitDeleted,
+		OccurredAt:   time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+		FruitDeleted: &repository.FruitDeletedEvent{SourceID: "1", Version: 3},
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", path, err)
+	}
+
+	return content
+}
+
+// addFields returns the message with the given fields added to its data.
+func addFields(t *testing.T, message []byte, fields map[string]interface{}) []byte {
+	t.Helper()
+
+	var envelope map[string]interface{}
+
+	assert.NoError(t, json.Unmarshal(message, &envelope))
+
+	data, _ := envelope["data"].(map[string]interface{})
+
+	for name, value := range fields {
+		data[name] = value
+	}
+
+	newMessage, err := json.Marshal(envelope)
+	assert.NoError(t, err)
+
+	return newMessage
+}
+
+func dataOf(t *testing.T, message []byte) json.RawMessage {
+	t.Helper()
+
+	var envelope cloudevents.Envelope
+
+	assert.NoError(t, json.Unmarshal(message, &envelope))
+
+	return envelope.Data
+}
+
+// jsonTypes returns the JSON schema type of every field of the data, whole
+// numbers are integers.
+func jsonTypes(t *testing.T, data json.RawMessage) map[string]string {
+	t.Helper()
+
+	var fields map[string]interface{}
+
+	assert.NoError(t, json.Unmarshal(data, &fields))
+
+	types := make(map[string]string, len(fields))
+
+	for name, value := range fields {
+		switch value := value.(type) {
+		case string:
+			types[name] = "string"
+		case float64:
+			types[name] = "number"
+
+			if value == math.Trunc(value) {
+				types[name] = "integer"
+			}
+		case bool:
+			types[name] = "boolean"
+		case map[string]interface{}:
+			types[name] = "object"
+		case []interface{}:
+			types[name] = "array"
+		default:
+			types[name] = "null"
+		}
+	}
+
+	return types
+}
@@ -0,0 +1,88 @@
+package cloudevents
+
+import (
+	"encoding/json"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// FruitCreatedV1 is the data of FruitCreatedV1Type events. Its fields must
+// not be renamed, removed or change their type, new fields are optional.
+type FruitCreatedV1 struct {
+	// SourceID is the id of the new fruit.
+	SourceID string `json:"source_id"`
+	// Version was added after the first release, older events don't have it.
+	Version int64   `json:"version,omitempty"`
+	Name    string  `json:"name"`
+	Variety string  `json:"variety"`
+	Price   float32 `json:"price"`
+}
+
+// FruitUpdatedV1 is the data of FruitUpdatedV1Type events, it has the
+// fields the update changed with their values before and after it.
+type FruitUpdatedV1 struct {
+	SourceID string `json:"source_id"`
+	// Version is the version of the fruit after the update.
+	Version       int64                      `json:"version"`
+	ChangedFields []string                   `json:"changed_fields"`
+	Before        map[string]json.RawMessage `json:"before"`
+	After         map[string]json.RawMessage `json:"after"`
+}
+
+// FruitDeletedV1 is the data of FruitDeletedV1Type events.
+type FruitDeletedV1 struct {
+	SourceID string `json:"source_id"`
+	// Version is one more than the version deleted.
+	Version int64 `json:"version"`
+}
+
+func newFruitCreatedV1(fruit repository.NewFruitEvent) FruitCreatedV1 {
+	return FruitCreatedV1{
+		SourceID: fruit.SourceID,
+		Version:  fruit.Version,
+		Name:     fruit.Name,
+		Variety:  fruit.Variety,
+		Price:    fruit.Price,
+	}
+}
+
+func (f FruitCreatedV1) toNewFruitEvent() *repository.NewFruitEvent {
+	return &repository.NewFruitEvent{
+		SourceID: f.SourceID,
+		Version:  f.Version,
+		Name:     f.Name,
+		Variety:  f.Variety,
+		Price:    f.Price,
+	}
+}
+
+func newFruitUpdatedV1(fruit repository.FruitUpdatedEvent) FruitUpdatedV1 {
+	return FruitUpdatedV1{
+		SourceID:      fruit.SourceID,
+		Version:       fruit.Version,
+		ChangedFields: fruit.ChangedFields,
+		Before:        fruit.Before,
+		After:         fruit.After,
+	}
+}
+
+func (f FruitUpdatedV1) toFruitUpdatedEvent() *repository.FruitUpdatedEvent {
+	return &repository.FruitUpdatedEvent{
+		SourceID:      f.SourceID,
+		Version:       f.Version,
+		ChangedFields: f.ChangedFields,
+		Before:        f.Before,
+		After:         f.After,
+	}
+}
+
+func newFruitDeletedV1(fruit repository.FruitDeletedEvent) FruitDeletedV1 {
+	return FruitDeletedV1(fruit)
+}
+
+func (f FruitDeletedV1) toFruitDeletedEvent() *repository.FruitDeletedEvent {
+	return &repository.FruitDeletedEvent{
+		SourceID: f.SourceID,
+		Version:  f.Version,
+	}
+}
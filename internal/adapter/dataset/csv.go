@@ -0,0 +1,388 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fernandoocampo/fruits/internal/fruits"
+)
+
+// Dataset columns.
+const (
+	IDColumn             = "id"
+	CountryColumn        = "country"
+	DescriptionColumn    = "description"
+	ClassificationColumn = "classification"
+	YearColumn           = "year"
+	PriceColumn          = "price"
+	ProvinceColumn       = "province"
+	RegionColumn         = "region"
+	FincaColumn          = "finca"
+	LocalNameColumn      = "local_name"
+	WikiPageColumn       = "wiki_page"
+	NameColumn           = "name"
+	VarietyColumn        = "variety"
+	VaultColumn          = "vault"
+)
+
+// Columns are the columns of a fruit dataset in the order they are written.
+// Readers locate columns by the header, so any order is accepted.
+var Columns = []string{
+	IDColumn,
+	CountryColumn,
+	DescriptionColumn,
+	ClassificationColumn,
+	YearColumn,
+	PriceColumn,
+	ProvinceColumn,
+	RegionColumn,
+	FincaColumn,
+	LocalNameColumn,
+	WikiPageColumn,
+	NameColumn,
+	VarietyColumn,
+	VaultColumn,
+}
+
+var (
+	errNoKnownColumns = errors.New("dataset header doesn't contain any known column")
+	errInvalidYear    = errors.New("year must be an integer")
+	errInvalidPrice   = errors.New("price must be a number")
+)
+
+// CSVReader reads fruits from a csv stream. The first row must be the header.
+type CSVReader struct {
+	reader *csv.Reader
+	// columns position of each known column in the file.
+	columns map[string]int
+}
+
+// CSVFile reads fruits from a csv file, the file is opened on the first read.
+type CSVFile struct {
+	path string
+	file *os.File
+	// temporary is true if the file is removed when it's closed.
+	temporary bool
+	*CSVReader
+}
+
+// CSVWriter writes fruits as a csv dataset with the Columns header, so the
+// output can be read again by CSVReader.
+type CSVWriter struct {
+	writer *csv.Writer
+	// headerWritten is true once the header was written.
+	headerWritten bool
+	record        []string
+}
+
+// NewCSVReader creates a reader of fruits from the given csv stream.
+func NewCSVReader(r io.Reader) *CSVReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	return &CSVReader{
+		reader: reader,
+	}
+}
+
+// NewCSVFile creates a reader of fruits from the csv file in the given path.
+func NewCSVFile(path string) *CSVFile {
+	return &CSVFile{
+		path: path,
+	}
+}
+
+// NewTemporaryCSVFile copies the given csv stream to a temporary file, so it
+// can be read once the stream is gone. The file is removed when it's closed.
+func NewTemporaryCSVFile(r io.Reader) (*CSVFile, error) {
+	file, err := os.CreateTemp("", "fruits-dataset-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create dataset file: %w", err)
+	}
+
+	_, err = io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		return nil, fmt.Errorf("unable to write dataset file: %w", err)
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		return nil, fmt.Errorf("unable to read dataset file: %w", err)
+	}
+
+	return &CSVFile{
+		path:      file.Name(),
+		file:      file,
+		temporary: true,
+		CSVReader: NewCSVReader(file),
+	}, nil
+}
+
+// NewCSVWriter creates a writer of fruits to the given csv stream.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{
+		writer: csv.NewWriter(w),
+		record: make([]string, len(Columns)),
+	}
+}
+
+// Next reads the next fruit of the dataset, it returns io.EOF at the end of
+// the stream. Rows that cannot be parsed are returned with their error.
+func (c *CSVReader) Next() (fruits.DatasetRow, error) {
+	if c.columns == nil {
+		err := c.readHeader()
+		if err != nil {
+			return fruits.DatasetRow{}, err
+		}
+	}
+
+	record, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return fruits.DatasetRow{}, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fruits.DatasetRow{
+			Line: parseErr.StartLine,
+			Err:  parseErr.Err,
+		}, nil
+	}
+
+	if err != nil {
+		return fruits.DatasetRow{}, fmt.Errorf("unable to read dataset: %w", err)
+	}
+
+	line, _ := c.reader.FieldPos(0)
+
+	newFruit, err := c.toNewFruit(record)
+
+	return fruits.DatasetRow{
+		Line:  line,
+		ID:    c.value(record, IDColumn),
+		Fruit: newFruit,
+		Err:   err,
+	}, nil
+}
+
+// Next reads the next fruit of the dataset file, see CSVReader.Next.
+func (c *CSVFile) Next() (fruits.DatasetRow, error) {
+	if c.file == nil {
+		file, err := os.Open(c.path)
+		if err != nil {
+			return fruits.DatasetRow{}, fmt.Errorf("unable to open dataset: %w", err)
+		}
+
+		c.file = file
+		c.CSVReader = NewCSVReader(file)
+	}
+
+	return c.CSVReader.Next()
+}
+
+// Close closes the dataset file, a temporary file is removed.
+func (c *CSVFile) Close() error {
+	if c.file == nil {
+		return nil
+	}
+
+	err := c.file.Close()
+	if err != nil {
+		return fmt.Errorf("unable to close dataset file: %w", err)
+	}
+
+	if c.temporary {
+		err = os.Remove(c.path)
+		if err != nil {
+			return fmt.Errorf("unable to remove dataset file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *CSVReader) readHeader() error {
+	header, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to read dataset header: %w", err)
+	}
+
+	columns := make(map[string]int)
+
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, column := range Columns {
+			if name == column {
+				columns[name] = index
+			}
+		}
+	}
+
+	if len(columns) == 0 {
+		return errNoKnownColumns
+	}
+
+	c.columns = columns
+
+	return nil
+}
+
+func (c *CSVReader) toNewFruit(record []string) (fruits.NewFruit, error) {
+	newFruit := fruits.NewFruit{
+		Name:           c.value(record, NameColumn),
+		Variety:        c.value(record, VarietyColumn),
+		Vault:          c.value(record, VaultColumn),
+		Country:        c.value(record, CountryColumn),
+		Province:       c.value(record, ProvinceColumn),
+		Region:         c.value(record, RegionColumn),
+		Finca:          c.value(record, FincaColumn),
+		Description:    c.value(record, DescriptionColumn),
+		Classification: c.value(record, ClassificationColumn),
+		LocalName:      c.value(record, LocalNameColumn),
+		WikiPage:       c.value(record, WikiPageColumn),
+	}
+
+	if year := c.value(record, YearColumn); year != "" {
+		value, err := strconv.Atoi(year)
+		if err != nil {
+			return newFruit, errInvalidYear
+		}
+
+		newFruit.Year = value
+	}
+
+	if price := c.value(record, PriceColumn); price != "" {
+		value, err := strconv.ParseFloat(price, 32)
+		if err != nil {
+			return newFruit, errInvalidPrice
+		}
+
+		newFruit.Price = float32(value)
+	}
+
+	return newFruit, nil
+}
+
+// value returns the trimmed value of the given column or empty if the
+// column is not part of the dataset.
+func (c *CSVReader) value(record []string, column string) string {
+	index, ok := c.columns[column]
+	if !ok || index >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[index])
+}
+
+// Write writes the given fruit as a row, the header is written before the
+// first one. Rows are buffered, call Flush to write them.
+func (c *CSVWriter) Write(fruit fruits.Fruit) error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	for index, column := range Columns {
+		c.record[index] = columnValue(fruit, column)
+	}
+
+	err = c.writer.Write(c.record)
+	if err != nil {
+		return fmt.Errorf("unable to write dataset row: %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered rows, the header is written even if there are no rows.
+func (c *CSVWriter) Flush() error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+
+	err = c.writer.Error()
+	if err != nil {
+		return fmt.Errorf("unable to write dataset: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CSVWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+
+	err := c.writer.Write(Columns)
+	if err != nil {
+		return fmt.Errorf("unable to write dataset header: %w", err)
+	}
+
+	c.headerWritten = true
+
+	return nil
+}
+
+// columnValue returns the value of the given column of a fruit, zero years
+// and prices are written empty like they are read.
+func columnValue(fruit fruits.Fruit, column string) string {
+	switch column {
+	case IDColumn:
+		return fruit.ID
+	case CountryColumn:
+		return fruit.Country
+	case DescriptionColumn:
+		return fruit.Description
+	case ClassificationColumn:
+		return fruit.Classification
+	case YearColumn:
+		if fruit.Year == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(fruit.Year)
+	case PriceColumn:
+		if fruit.Price == 0 {
+			return ""
+		}
+
+		return strconv.FormatFloat(float64(fruit.Price), 'f', -1, 32)
+	case ProvinceColumn:
+		return fruit.Province
+	case RegionColumn:
+		return fruit.Region
+	case FincaColumn:
+		return fruit.Finca
+	case LocalNameColumn:
+		return fruit.LocalName
+	case WikiPageColumn:
+		return fruit.WikiPage
+	case NameColumn:
+		return fruit.Name
+	case VarietyColumn:
+		return fruit.Variety
+	case VaultColumn:
+		return fruit.Vault
+	default:
+		return ""
+	}
+}
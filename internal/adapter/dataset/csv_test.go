@@ -0,0 +1,253 @@
+package dataset_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/dataset"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+const datasetFixture = `id,country,description,classification,year,price,province,region,finca,local_name,wiki_page,name,variety,vault
+0,Italy,brisk acidity,Vulka Bianco,2013,,Sicily & Sardinia,Etna,,Kerin OaKeefe,@kerinokeefe,Nicosia 2013 Vulka Bianco  (Etna),White Blend,Nicosia
+1,Portugal,"ripe, fruity",Avidagos,2011,15,Douro,,,Roger Voss,@vossroger,Quinta dos Avidagos 2011 Avidagos Red (Douro),Portuguese Red,Quinta dos Avidagos
+2,US,tart,,not-a-year,14,Oregon,Willamette Valley,,Paul Gregutt,,Rainstorm 2013 Pinot Gris,Pinot Gris,Rainstorm
+`
+
+func TestReadDataset(t *testing.T) {
+	t.Parallel()
+
+	expectedRows := []fruits.DatasetRow{
+		{
+			Line: 2,
+			ID:   "0",
+			Fruit: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Variety:        "White Blend",
+				Vault:          "Nicosia",
+				Year:           2013,
+				Country:        "Italy",
+				Province:       "Sicily & Sardinia",
+				Region:         "Etna",
+				Description:    "brisk acidity",
+				Classification: "Vulka Bianco",
+				LocalName:      "Kerin OaKeefe",
+				WikiPage:       "@kerinokeefe",
+			},
+		},
+		{
+			Line: 3,
+			ID:   "1",
+			Fruit: fruits.NewFruit{
+				Name:           "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+				Variety:        "Portuguese Red",
+				Vault:          "Quinta dos Avidagos",
+				Year:           2011,
+				Price:          15,
+				Country:        "Portugal",
+				Province:       "Douro",
+				Description:    "ripe, fruity",
+				Classification: "Avidagos",
+				LocalName:      "Roger Voss",
+				WikiPage:       "@vossroger",
+			},
+		},
+	}
+	reader := dataset.NewCSVReader(strings.NewReader(datasetFixture))
+
+	got := readAll(t, reader)
+
+	assert.Len(t, got, 3)
+	assert.Equal(t, expectedRows, got[:2])
+	assert.Equal(t, 4, got[2].Line)
+	assert.EqualError(t, got[2].Err, "year must be an integer")
+}
+
+func TestReadDatasetColumnsInAnyOrder(t *testing.T) {
+	t.Parallel()
+
+	givenDataset := "Vault,Name,Country,Classification\nNicosia,Vulka,Italy,Bianco\n"
+	expectedFruit := fruits.NewFruit{
+		Name:           "Vulka",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Bianco",
+	}
+	reader := dataset.NewCSVReader(strings.NewReader(givenDataset))
+
+	got := readAll(t, reader)
+
+	assert.Len(t, got, 1)
+	assert.NoError(t, got[0].Err)
+	assert.Equal(t, expectedFruit, got[0].Fruit)
+}
+
+func TestReadDatasetWithoutKnownColumns(t *testing.T) {
+	t.Parallel()
+
+	reader := dataset.NewCSVReader(strings.NewReader("a,b\n1,2\n"))
+
+	_, err := reader.Next()
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, io.EOF))
+}
+
+func TestReadDatasetFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "fruits.csv")
+
+	err := os.WriteFile(path, []byte(datasetFixture), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error writing dataset: %s", err)
+	}
+
+	reader := dataset.NewCSVFile(path)
+	defer reader.Close()
+
+	got := readAll(t, reader)
+
+	assert.Len(t, got, 3)
+}
+
+func TestReadMissingDatasetFile(t *testing.T) {
+	t.Parallel()
+
+	reader := dataset.NewCSVFile(filepath.Join(t.TempDir(), "missing.csv"))
+	defer reader.Close()
+
+	_, err := reader.Next()
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, io.EOF))
+}
+
+func TestReadTemporaryDatasetFile(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	reader, err := dataset.NewTemporaryCSVFile(strings.NewReader(datasetFixture))
+	if err != nil {
+		t.Fatalf("unexpected error copying dataset: %s", err)
+	}
+
+	got := readAll(t, reader)
+	err = reader.Close()
+
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	files, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("unexpected error reading temporary dir: %s", err)
+	}
+
+	assert.Empty(t, files)
+}
+
+func TestWrittenDatasetCanBeReadAgain(t *testing.T) {
+	t.Parallel()
+
+	givenFruits := []fruits.Fruit{
+		{
+			ID:             "1234",
+			Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+			Variety:        "White Blend",
+			Vault:          "Nicosia",
+			Year:           2013,
+			Price:          12.5,
+			Country:        "Italy",
+			Province:       "Sicily & Sardinia",
+			Region:         "Etna",
+			Description:    "brisk acidity, \"lemon\" notes\nand a long finish",
+			Classification: "Vulka Bianco",
+			LocalName:      "Kerin OaKeefe",
+			WikiPage:       "https://twitter.com/kerinokeefe",
+			Version:        3,
+		},
+		{
+			ID:    "1240",
+			Name:  "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+			Vault: "Quinta dos Avidagos",
+		},
+	}
+	expectedRows := []fruits.DatasetRow{
+		{
+			Line: 2,
+			ID:   "1234",
+			Fruit: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Variety:        "White Blend",
+				Vault:          "Nicosia",
+				Year:           2013,
+				Price:          12.5,
+				Country:        "Italy",
+				Province:       "Sicily & Sardinia",
+				Region:         "Etna",
+				Description:    "brisk acidity, \"lemon\" notes\nand a long finish",
+				Classification: "Vulka Bianco",
+				LocalName:      "Kerin OaKeefe",
+				WikiPage:       "https://twitter.com/kerinokeefe",
+			},
+		},
+		{
+			Line: 4,
+			ID:   "1240",
+			Fruit: fruits.NewFruit{
+				Name:  "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+				Vault: "Quinta dos Avidagos",
+			},
+		},
+	}
+
+	var output strings.Builder
+
+	writer := dataset.NewCSVWriter(&output)
+
+	for _, fruit := range givenFruits {
+		err := writer.Write(fruit)
+		assert.NoError(t, err)
+	}
+
+	err := writer.Flush()
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(output.String(), strings.Join(dataset.Columns, ",")+"\n"))
+	assert.Equal(t, expectedRows, readAll(t, dataset.NewCSVReader(strings.NewReader(output.String()))))
+}
+
+func TestWriteEmptyDataset(t *testing.T) {
+	t.Parallel()
+
+	var output strings.Builder
+
+	err := dataset.NewCSVWriter(&output).Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join(dataset.Columns, ",")+"\n", output.String())
+}
+
+func readAll(t *testing.T, source fruits.DatasetSource) []fruits.DatasetRow {
+	t.Helper()
+
+	var rows []fruits.DatasetRow
+
+	for {
+		row, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error reading dataset: %s", err)
+		}
+
+		rows = append(rows, row)
+	}
+}
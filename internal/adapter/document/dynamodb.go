@@ -3,15 +3,16 @@ package document
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
 	"github.com/fernandoocampo/fruits/internal/adapter/repository"
-	"github.com/google/uuid"
 )
 
 const fruitsTable = "fruits"
@@ -21,8 +22,44 @@ var (
 	errCreatingDynamodb = errors.New("unable to connect to DynamoDB")
 	errSavingFruit      = errors.New("unable to save fruit")
 	errGettingFruit     = errors.New("unable to get fruit")
+	errSearchingFruits  = errors.New("unable to search fruits")
+	errUpdatingFruit    = errors.New("unable to update fruit")
+	errDeletingFruit    = errors.New("unable to delete fruit")
 )
 
+// BatchGetItem and TransactWriteItems limits, a transaction takes up to 100
+// writes and every new fruit takes two: the fruit and its event.
+const (
+	maxBatchGetKeys   = 100
+	maxTransactFruits = 25
+	maxBatchAttempts  = 5
+	batchRetryDelay   = 50 * time.Millisecond
+)
+
+// Conditions to change only fruits that exist with the expected version, fruits
+// stored before versions were tracked have no version and are in the first one.
+const (
+	fruitHasVersion      = "attribute_exists(id) AND #version = :version"
+	fruitHasFirstVersion = "attribute_exists(id) AND (attribute_not_exists(#version) OR #version = :version)"
+)
+
+// fruitIsNew is the condition to store a new fruit only if its id is not taken.
+const fruitIsNew = "attribute_not_exists(id)"
+
+// conditionalCheckFailed is the cancellation reason of transaction writes whose condition failed.
+const conditionalCheckFailed = "ConditionalCheckFailed"
+
+// transientCancellations are the cancellation reasons of transaction writes
+// that can be written if the transaction is tried again, None is the reason
+// of the writes that didn't cancel it.
+var transientCancellations = map[string]bool{
+	"":                              true,
+	"None":                          true,
+	"TransactionConflict":           true,
+	"ThrottlingError":               true,
+	"ProvisionedThroughputExceeded": true,
+}
+
 // Setup contains dynamodb settings.
 type Setup struct {
 	Logger   *loggers.Logger
@@ -83,6 +120,12 @@ func (d *DynamoDB) getConfig(ctx context.Context, region, endpoint string) (aws.
 }
 
 func (d *DynamoDB) FindByID(ctx context.Context, fruitID repository.FruitID) (*repository.Fruit, error) {
+	return d.getFruit(ctx, fruitID, false)
+}
+
+// getFruit returns the fruit with the given id or nil if it doesn't exist, a
+// consistent read returns the last version stored.
+func (d *DynamoDB) getFruit(ctx context.Context, fruitID repository.FruitID, consistent bool) (*repository.Fruit, error) {
 	selectedKeys := map[string]string{
 		"id": string(fruitID),
 	}
@@ -94,9 +137,10 @@ func (d *DynamoDB) FindByID(ctx context.Context, fruitID repository.FruitID) (*r
 		return nil, errGettingFruit
 	}
 
-	data, err := d.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(fruitsTable),
-		Key:       key,
+	data, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(fruitsTable),
+		Key:            key,
+		ConsistentRead: aws.Bool(consistent),
 	})
 	if err != nil {
 		d.logger.Error("unable to get fruit", loggers.Fields{"error": err})
@@ -124,22 +168,26 @@ func (d *DynamoDB) FindByID(ctx context.Context, fruitID repository.FruitID) (*r
 	return fruit, nil
 }
 
+// Save stores a new fruit and the event that announces it in one transaction,
+// then returns its id. It returns repository.ErrFruitExists if a fruit with
+// its id is stored.
 func (d *DynamoDB) Save(ctx context.Context, fruit repository.NewFruit) (repository.FruitID, error) {
-	newid := uuid.New().String()
+	newid := repository.FruitIDValue(repository.NewFruitID(fruit))
 
 	newFruit := transformFruit(newid, fruit)
 
-	data, err := attributevalue.MarshalMap(newFruit)
+	writes, err := newFruitWrites(newFruit)
 	if err != nil {
 		d.logger.Error("unable to marshal new fruit", loggers.Fields{"error": err})
 
 		return repository.FruitID(""), errSavingFruit
 	}
 
-	_, err = d.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(fruitsTable),
-		Item:      data,
-	})
+	err = d.transactWrite(ctx, writes)
+	if isConditionCanceled(err) {
+		return repository.FruitID(""), repository.ErrFruitExists
+	}
+
 	if err != nil {
 		d.logger.Error("unable to store fruit", loggers.Fields{"error": err})
 
@@ -157,14 +205,833 @@ func (d *DynamoDB) Save(ctx context.Context, fruit repository.NewFruit) (reposit
 	return repository.FruitID(newid), nil
 }
 
+// SaveAll stores every new fruit and the events that announce them, the
+// results are in the same order as the fruits. Every chunk is written in
+// one transaction, a chunk that cannot be written doesn't stop the rest. If
+// the transaction of a chunk is cancelled, its fruits are written one by one
+// so a fruit that cannot be stored doesn't reject the others.
+func (d *DynamoDB) SaveAll(ctx context.Context, fruits []repository.NewFruit) []repository.SaveResult {
+	results := make([]repository.SaveResult, len(fruits))
+
+	for start := 0; start < len(fruits); start += maxTransactFruits {
+		end := start + maxTransactFruits
+		if end > len(fruits) {
+			end = len(fruits)
+		}
+
+		d.transactSaveFruits(ctx, fruits[start:end], results[start:end])
+	}
+
+	return results
+}
+
+// transactSaveFruits writes the given fruits with their events and sets their results.
+func (d *DynamoDB) transactSaveFruits(ctx context.Context, fruits []repository.NewFruit, results []repository.SaveResult) {
+	writes := make([]types.TransactWriteItem, 0, 2*len(fruits))
+	fruitWrites := make(map[int][]types.TransactWriteItem, len(fruits))
+	written := make([]int, 0, len(fruits))
+
+	for index, fruit := range fruits {
+		newid := repository.FruitIDValue(repository.NewFruitID(fruit))
+
+		newWrites, err := newFruitWrites(transformFruit(newid, fruit))
+		if err != nil {
+			d.logger.Error("unable to marshal new fruit", loggers.Fields{"error": err})
+
+			results[index].Err = errSavingFruit
+
+			continue
+		}
+
+		results[index].ID = repository.FruitID(newid)
+		writes = append(writes, newWrites...)
+		fruitWrites[index] = newWrites
+		written = append(written, index)
+	}
+
+	if len(writes) == 0 {
+		return
+	}
+
+	err := d.transactWrite(ctx, writes)
+	if err == nil {
+		d.logger.Debug("new fruits stored", loggers.Fields{"fruits": len(written)})
+
+		return
+	}
+
+	if !isTransactionCanceled(err) || len(written) == 1 {
+		d.logger.Error("unable to store fruits", loggers.Fields{"error": err})
+
+		for _, index := range written {
+			results[index] = repository.SaveResult{Err: errSavingFruit}
+		}
+
+		return
+	}
+
+	d.logger.Warn("fruits transaction was cancelled, they are stored one by one", loggers.Fields{"fruits": len(written), "error": err})
+
+	for _, index := range written {
+		err := d.transactWrite(ctx, fruitWrites[index])
+		if err != nil {
+			d.logger.Error("unable to store fruit", loggers.Fields{"id": results[index].ID, "error": err})
+
+			results[index] = repository.SaveResult{Err: errSavingFruit}
+		}
+	}
+}
+
+// transactWrite runs the writes in one transaction, transactions cancelled
+// because of conflicts or throttling are tried again with a backoff.
+func (d *DynamoDB) transactWrite(ctx context.Context, writes []types.TransactWriteItem) error {
+	var err error
+
+	for attempt := 1; attempt <= maxBatchAttempts; attempt++ {
+		_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: writes,
+		})
+		if !isTransientCancellation(err) {
+			return err
+		}
+
+		if attempt < maxBatchAttempts {
+			sleepErr := sleep(ctx, time.Duration(attempt)*batchRetryDelay)
+			if sleepErr != nil {
+				return sleepErr
+			}
+		}
+	}
+
+	return err
+}
+
+// newFruitWrites returns the transaction writes that store a new fruit, if
+// its id is not taken, and the event that announces it.
+func newFruitWrites(newFruit Fruit) ([]types.TransactWriteItem, error) {
+	data, err := attributevalue.MarshalMap(newFruit)
+	if err != nil {
+		return nil, err
+	}
+
+	eventWrite, err := newOutboxPut(repository.NewOutboxEvent(repository.NewFruitCreated(*newFruit.toRepositoryFruit(), newFruit.LastModified)))
+	if err != nil {
+		return nil, err
+	}
+
+	fruitWrite := types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(fruitsTable),
+			Item:                data,
+			ConditionExpression: aws.String(fruitIsNew),
+		},
+	}
+
+	return []types.TransactWriteItem{fruitWrite, eventWrite}, nil
+}
+
+// Update replaces the fruit with the same id if its version is fruit.Version,
+// the stored fruit gets the next version. The fruit and the event that
+// announces the change are written in one transaction. It returns
+// repository.ErrFruitNotFound if the fruit doesn't exist and
+// repository.ErrVersionConflict if the version differs.
+func (d *DynamoDB) Update(ctx context.Context, fruit repository.Fruit) error {
+	storedFruit, err := d.getFruitWithVersion(ctx, fruit.ID, fruit.Version)
+	if err != nil {
+		return err
+	}
+
+	fruitToStore := fromRepositoryFruit(fruit)
+	fruitToStore.Version++
+
+	data, err := attributevalue.MarshalMap(fruitToStore)
+	if err != nil {
+		d.logger.Error("unable to marshal fruit", loggers.Fields{"error": err})
+
+		return errUpdatingFruit
+	}
+
+	expectedVersion, err := attributevalue.Marshal(fruit.Version)
+	if err != nil {
+		d.logger.Error("unable to marshal fruit version", loggers.Fields{"error": err})
+
+		return errUpdatingFruit
+	}
+
+	event, err := repository.NewFruitUpdated(*storedFruit, *fruitToStore.toRepositoryFruit(), fruit.LastModified)
+	if err != nil {
+		d.logger.Error("unable to build fruit updated event", loggers.Fields{"error": err})
+
+		return errUpdatingFruit
+	}
+
+	eventWrite, err := newOutboxPut(repository.NewOutboxEvent(event))
+	if err != nil {
+		d.logger.Error("unable to marshal fruit updated event", loggers.Fields{"error": err})
+
+		return errUpdatingFruit
+	}
+
+	fruitWrite := types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:                 aws.String(fruitsTable),
+			Item:                      data,
+			ConditionExpression:       aws.String(versionCondition(fruit.Version)),
+			ExpressionAttributeNames:  map[string]string{"#version": "version"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":version": expectedVersion},
+		},
+	}
+
+	err = d.transactWrite(ctx, []types.TransactWriteItem{fruitWrite, eventWrite})
+	if isConditionCanceled(err) {
+		return d.conditionFailure(ctx, fruit.ID)
+	}
+
+	if err != nil {
+		d.logger.Error("unable to update fruit", loggers.Fields{"error": err})
+
+		return errUpdatingFruit
+	}
+
+	d.logger.Debug(
+		"fruit updated",
+		loggers.Fields{
+			"id":     fruit.ID,
+			"output": fruitToStore,
+		},
+	)
+
+	return nil
+}
+
+// Delete deletes the fruit with the given id if its version is the given one,
+// the deletion and the event that announces it are written in one transaction.
+// It returns repository.ErrFruitNotFound if the fruit doesn't exist and
+// repository.ErrVersionConflict if the version differs.
+func (d *DynamoDB) Delete(ctx context.Context, fruitID repository.FruitID, version int64) error {
+	storedFruit, err := d.getFruitWithVersion(ctx, fruitID, version)
+	if err != nil {
+		return err
+	}
+
+	key, err := attributevalue.MarshalMap(map[string]string{
+		"id": repository.FruitIDValue(fruitID),
+	})
+	if err != nil {
+		d.logger.Error("unable to marshal fruit keys", loggers.Fields{"error": err})
+
+		return errDeletingFruit
+	}
+
+	expectedVersion, err := attributevalue.Marshal(version)
+	if err != nil {
+		d.logger.Error("unable to marshal fruit version", loggers.Fields{"error": err})
+
+		return errDeletingFruit
+	}
+
+	eventWrite, err := newOutboxPut(repository.NewOutboxEvent(repository.NewFruitDeleted(*storedFruit, time.Now().UTC())))
+	if err != nil {
+		d.logger.Error("unable to marshal fruit deleted event", loggers.Fields{"error": err})
+
+		return errDeletingFruit
+	}
+
+	fruitWrite := types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName:                 aws.String(fruitsTable),
+			Key:                       key,
+			ConditionExpression:       aws.String(versionCondition(version)),
+			ExpressionAttributeNames:  map[string]string{"#version": "version"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":version": expectedVersion},
+		},
+	}
+
+	err = d.transactWrite(ctx, []types.TransactWriteItem{fruitWrite, eventWrite})
+	if isConditionCanceled(err) {
+		return d.conditionFailure(ctx, fruitID)
+	}
+
+	if err != nil {
+		d.logger.Error("unable to delete fruit", loggers.Fields{"error": err})
+
+		return errDeletingFruit
+	}
+
+	d.logger.Debug("fruit deleted", loggers.Fields{"id": fruitID})
+
+	return nil
+}
+
+// getFruitWithVersion returns the stored fruit to change, the events of the
+// change need its values. It returns repository.ErrFruitNotFound if the fruit
+// doesn't exist and repository.ErrVersionConflict if the version differs.
+func (d *DynamoDB) getFruitWithVersion(ctx context.Context, fruitID repository.FruitID, version int64) (*repository.Fruit, error) {
+	storedFruit, err := d.getFruit(ctx, fruitID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if storedFruit == nil {
+		return nil, repository.ErrFruitNotFound
+	}
+
+	if storedFruit.Version != version {
+		return nil, repository.ErrVersionConflict
+	}
+
+	return storedFruit, nil
+}
+
+// SearchWithFilters scans the fruits table page by page and returns the fruits
+// that match the filter within the window it describes, start is 1-based.
+// Total is the number of fruits that match the filter.
 func (d *DynamoDB) SearchWithFilters(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
-	return repository.FindFruitsResult{}, nil
+	if filter.IDs != nil {
+		return d.searchIDs(ctx, filter)
+	}
+
+	if len(filter.Sort) > 0 {
+		return d.searchSorted(ctx, filter)
+	}
+
+	if filter.After != nil {
+		return d.searchAfter(ctx, filter)
+	}
+
+	result := repository.FindFruitsResult{
+		Fruits: make([]repository.Fruit, 0),
+		Start:  filter.Start,
+		Count:  filter.Count,
+	}
+
+	first := filter.Start - 1
+	if first < 0 {
+		first = 0
+	}
+
+	last := first + filter.Count
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	paginator := dynamodb.NewScanPaginator(d.client, newScanInput(filter))
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			d.logger.Error("unable to scan fruits", loggers.Fields{"error": err})
+
+			return repository.FindFruitsResult{}, errSearchingFruits
+		}
+
+		for _, item := range page.Items {
+			inPage := result.Total >= first && result.Total < last
+
+			if inPage || len(filter.Facets) > 0 {
+				var fruit Fruit
+
+				err = attributevalue.UnmarshalMap(item, &fruit)
+				if err != nil {
+					d.logger.Error("unable to unmarshal fruit", loggers.Fields{"error": err})
+
+					return repository.FindFruitsResult{}, errSearchingFruits
+				}
+
+				repositoryFruit := *fruit.toRepositoryFruit()
+				facets.Add(repositoryFruit)
+
+				if inPage {
+					result.Fruits = append(result.Fruits, repositoryFruit)
+				}
+			}
+
+			result.Total++
+		}
+	}
+
+	result.Facets = facets.Facets()
+
+	if len(result.Fruits) > 0 && result.Total > first+len(result.Fruits) {
+		lastFruit := result.Fruits[len(result.Fruits)-1]
+		result.Next = &repository.Cursor{Key: repository.FruitIDValue(lastFruit.ID), Total: result.Total}
+	}
+
+	d.logger.Debug(
+		"fruits found",
+		loggers.Fields{
+			"filter": filter,
+			"total":  result.Total,
+			"found":  len(result.Fruits),
+		},
+	)
+
+	return result, nil
+}
+
+// ListFruits reads one scan page of up to count fruits after the cursor.
+func (d *DynamoDB) ListFruits(ctx context.Context, after *repository.Cursor, count int) (repository.FruitPage, error) {
+	scanInput := &dynamodb.ScanInput{
+		TableName: aws.String(fruitsTable),
+		Limit:     aws.Int32(int32(count)),
+	}
+
+	if after != nil {
+		if after.Key == "" {
+			return repository.FruitPage{}, repository.ErrInvalidCursor
+		}
+
+		scanInput.ExclusiveStartKey = map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: after.Key},
+		}
+	}
+
+	output, err := d.client.Scan(ctx, scanInput)
+	if err != nil {
+		d.logger.Error("unable to scan fruits", loggers.Fields{"error": err})
+
+		return repository.FruitPage{}, errSearchingFruits
+	}
+
+	var items []Fruit
+
+	err = attributevalue.UnmarshalListOfMaps(output.Items, &items)
+	if err != nil {
+		d.logger.Error("unable to unmarshal fruits", loggers.Fields{"error": err})
+
+		return repository.FruitPage{}, errSearchingFruits
+	}
+
+	page := repository.FruitPage{
+		Fruits: make([]repository.Fruit, 0, len(items)),
+	}
+
+	for index := range items {
+		page.Fruits = append(page.Fruits, *items[index].toRepositoryFruit())
+	}
+
+	if lastKey, ok := output.LastEvaluatedKey["id"].(*types.AttributeValueMemberS); ok {
+		page.Next = &repository.Cursor{Key: lastKey.Value}
+	}
+
+	return page, nil
+}
+
+// searchAfter continues the scan right after the fruit whose id is the cursor
+// key and reads only the pages it needs to fill the window. Total is the one
+// of the first page, the fruits are only counted again to get facets.
+func (d *DynamoDB) searchAfter(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	if filter.After.Key == "" {
+		return repository.FindFruitsResult{}, repository.ErrInvalidCursor
+	}
+
+	result := repository.FindFruitsResult{
+		Fruits: make([]repository.Fruit, 0, filter.Count),
+		Total:  filter.After.Total,
+		Start:  filter.Start,
+		Count:  filter.Count,
+	}
+
+	if len(filter.Facets) > 0 {
+		total, facets, err := d.countFruits(ctx, filter)
+		if err != nil {
+			return repository.FindFruitsResult{}, err
+		}
+
+		result.Total, result.Facets = total, facets
+	}
+
+	scanInput := newScanInput(filter)
+	scanInput.ExclusiveStartKey = map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: filter.After.Key},
+	}
+	scanInput.Limit = aws.Int32(int32(filter.Count))
+
+	paginator := dynamodb.NewScanPaginator(d.client, scanInput)
+
+	for paginator.HasMorePages() && len(result.Fruits) < filter.Count {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			d.logger.Error("unable to scan fruits", loggers.Fields{"error": err})
+
+			return repository.FindFruitsResult{}, errSearchingFruits
+		}
+
+		for index, item := range page.Items {
+			var fruit Fruit
+
+			err = attributevalue.UnmarshalMap(item, &fruit)
+			if err != nil {
+				d.logger.Error("unable to unmarshal fruit", loggers.Fields{"error": err})
+
+				return repository.FindFruitsResult{}, errSearchingFruits
+			}
+
+			result.Fruits = append(result.Fruits, *fruit.toRepositoryFruit())
+
+			if len(result.Fruits) == filter.Count {
+				if index < len(page.Items)-1 || page.LastEvaluatedKey != nil {
+					result.Next = &repository.Cursor{Key: fruit.ID, Total: result.Total}
+				}
+
+				break
+			}
+		}
+	}
+
+	d.logger.Debug(
+		"fruits found after cursor",
+		loggers.Fields{
+			"filter": filter,
+			"total":  result.Total,
+			"found":  len(result.Fruits),
+		},
+	)
+
+	return result, nil
+}
+
+// searchIDs gets the fruits with the filter ids and returns the ones that
+// match the filter. If every fruit matches it and they are neither sorted nor
+// counted by facets only the fruits of the page are read, otherwise all of
+// them are read to filter, sort and count them.
+func (d *DynamoDB) searchIDs(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	if filter.After != nil {
+		return repository.FindFruitsResult{}, repository.ErrInvalidCursor
+	}
+
+	if !filter.HasConditions() && len(filter.Sort) == 0 && len(filter.Facets) == 0 {
+		return d.searchIDsPage(ctx, filter)
+	}
+
+	fruitsFound, err := d.getFruits(ctx, filter.IDs)
+	if err != nil {
+		return repository.FindFruitsResult{}, err
+	}
+
+	matches := make([]repository.Fruit, 0, len(fruitsFound))
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	for _, fruitID := range filter.IDs {
+		fruit, ok := fruitsFound[fruitID]
+		if ok && filter.Match(fruit) {
+			matches = append(matches, fruit)
+			facets.Add(fruit)
+		}
+	}
+
+	if len(filter.Sort) > 0 {
+		repository.SortFruits(matches, filter.Sort)
+	}
+
+	page, _ := filter.Page(matches)
+
+	return repository.FindFruitsResult{
+		Fruits: page,
+		Total:  len(matches),
+		Start:  filter.Start,
+		Count:  filter.Count,
+		Facets: facets.Facets(),
+	}, nil
+}
+
+// searchIDsPage reads only the fruits whose ids are in the page of the filter
+// ids, Total is the number of ids because every fruit matches the filter.
+func (d *DynamoDB) searchIDsPage(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	first := filter.Start - 1
+	if first < 0 {
+		first = 0
+	}
+
+	if first > len(filter.IDs) {
+		first = len(filter.IDs)
+	}
+
+	last := first + filter.Count
+	if last > len(filter.IDs) {
+		last = len(filter.IDs)
+	}
+
+	fruitsFound, err := d.getFruits(ctx, filter.IDs[first:last])
+	if err != nil {
+		return repository.FindFruitsResult{}, err
+	}
+
+	page := make([]repository.Fruit, 0, last-first)
+
+	for _, fruitID := range filter.IDs[first:last] {
+		if fruit, ok := fruitsFound[fruitID]; ok {
+			page = append(page, fruit)
+		}
+	}
+
+	return repository.FindFruitsResult{
+		Fruits: page,
+		Total:  len(filter.IDs),
+		Start:  filter.Start,
+		Count:  filter.Count,
+	}, nil
+}
+
+// getFruits gets the fruits with the given ids that exist, maxBatchGetKeys at a time.
+func (d *DynamoDB) getFruits(ctx context.Context, fruitIDs []repository.FruitID) (map[repository.FruitID]repository.Fruit, error) {
+	fruitsFound := make(map[repository.FruitID]repository.Fruit, len(fruitIDs))
+
+	for first := 0; first < len(fruitIDs); first += maxBatchGetKeys {
+		last := first + maxBatchGetKeys
+		if last > len(fruitIDs) {
+			last = len(fruitIDs)
+		}
+
+		err := d.batchGetFruits(ctx, fruitIDs[first:last], fruitsFound)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fruitsFound, nil
+}
+
+// batchGetFruits gets the fruits with the given ids and adds them to fruitsFound,
+// keys that DynamoDB leaves unprocessed are requested again with a backoff.
+func (d *DynamoDB) batchGetFruits(ctx context.Context, fruitIDs []repository.FruitID, fruitsFound map[repository.FruitID]repository.Fruit) error {
+	keys := make([]map[string]types.AttributeValue, 0, len(fruitIDs))
+
+	for _, fruitID := range fruitIDs {
+		keys = append(keys, map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: repository.FruitIDValue(fruitID)},
+		})
+	}
+
+	requestItems := map[string]types.KeysAndAttributes{
+		fruitsTable: {Keys: keys},
+	}
+
+	for attempt := 1; len(requestItems) > 0; attempt++ {
+		if attempt > maxBatchAttempts {
+			d.logger.Error("fruits were left unprocessed", loggers.Fields{"attempts": maxBatchAttempts})
+
+			return errSearchingFruits
+		}
+
+		output, err := d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			d.logger.Error("unable to get fruits", loggers.Fields{"error": err})
+
+			return errSearchingFruits
+		}
+
+		var fruits []Fruit
+
+		err = attributevalue.UnmarshalListOfMaps(output.Responses[fruitsTable], &fruits)
+		if err != nil {
+			d.logger.Error("unable to unmarshal fruits", loggers.Fields{"error": err})
+
+			return errSearchingFruits
+		}
+
+		for index := range fruits {
+			fruitsFound[repository.FruitID(fruits[index].ID)] = *fruits[index].toRepositoryFruit()
+		}
+
+		requestItems = output.UnprocessedKeys
+
+		if len(requestItems) > 0 {
+			err = sleep(ctx, time.Duration(attempt)*batchRetryDelay)
+			if err != nil {
+				return errSearchingFruits
+			}
+		}
+	}
+
+	return nil
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// countFruits counts the fruits that match the filter, fruits are only read
+// if the filter has facets to count them by.
+func (d *DynamoDB) countFruits(ctx context.Context, filter repository.FruitFilter) (int, repository.Facets, error) {
+	scanInput := newScanInput(filter)
+	if len(filter.Facets) == 0 {
+		scanInput.Select = types.SelectCount
+	}
+
+	var total int
+
+	facets := repository.NewFacetCounter(filter.Facets)
+	paginator := dynamodb.NewScanPaginator(d.client, scanInput)
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			d.logger.Error("unable to count fruits", loggers.Fields{"error": err})
+
+			return 0, nil, errSearchingFruits
+		}
+
+		total += int(page.Count)
+
+		var fruits []Fruit
+
+		err = attributevalue.UnmarshalListOfMaps(page.Items, &fruits)
+		if err != nil {
+			d.logger.Error("unable to unmarshal fruits", loggers.Fields{"error": err})
+
+			return 0, nil, errSearchingFruits
+		}
+
+		for index := range fruits {
+			facets.Add(*fruits[index].toRepositoryFruit())
+		}
+	}
+
+	return total, facets.Facets(), nil
+}
+
+// searchSorted scans every fruit that matches the filter, scans are not
+// ordered so fruits must be sorted before the window is taken.
+func (d *DynamoDB) searchSorted(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	matches := make([]repository.Fruit, 0)
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	paginator := dynamodb.NewScanPaginator(d.client, newScanInput(filter))
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			d.logger.Error("unable to scan fruits", loggers.Fields{"error": err})
+
+			return repository.FindFruitsResult{}, errSearchingFruits
+		}
+
+		var fruits []Fruit
+
+		err = attributevalue.UnmarshalListOfMaps(page.Items, &fruits)
+		if err != nil {
+			d.logger.Error("unable to unmarshal fruits", loggers.Fields{"error": err})
+
+			return repository.FindFruitsResult{}, errSearchingFruits
+		}
+
+		for index := range fruits {
+			matches = append(matches, *fruits[index].toRepositoryFruit())
+			facets.Add(matches[len(matches)-1])
+		}
+	}
+
+	repository.SortFruits(matches, filter.Sort)
+
+	page, next := filter.SortedPage(matches)
+
+	result := repository.FindFruitsResult{
+		Fruits: page,
+		Total:  len(matches),
+		Start:  filter.Start,
+		Count:  filter.Count,
+		Next:   next,
+		Facets: facets.Facets(),
+	}
+
+	d.logger.Debug(
+		"fruits found",
+		loggers.Fields{
+			"filter": filter,
+			"total":  result.Total,
+			"found":  len(result.Fruits),
+		},
+	)
+
+	return result, nil
 }
 
 func (d *DynamoDB) DatasetStatus(ctx context.Context) (repository.FruitDatasetStatus, error) {
 	return repository.FruitDatasetStatus{Ok: true}, nil
 }
 
+// versionCondition returns the condition to change the fruit if it has the given version.
+func versionCondition(version int64) string {
+	if version == repository.FirstVersion {
+		return fruitHasFirstVersion
+	}
+
+	return fruitHasVersion
+}
+
+// conditionFailure tells why a fruit didn't meet the version condition.
+func (d *DynamoDB) conditionFailure(ctx context.Context, fruitID repository.FruitID) error {
+	fruit, err := d.FindByID(ctx, fruitID)
+	if err != nil {
+		return err
+	}
+
+	if fruit == nil {
+		return repository.ErrFruitNotFound
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// isConditionalCheckFailed checks if the error was caused by a condition expression.
+func isConditionalCheckFailed(err error) bool {
+	var conditionalErr *types.ConditionalCheckFailedException
+
+	return errors.As(err, &conditionalErr)
+}
+
+func isTransactionCanceled(err error) bool {
+	var canceledErr *types.TransactionCanceledException
+
+	return errors.As(err, &canceledErr)
+}
+
+// isTransientCancellation checks if the transaction was cancelled only
+// because of conflicts or throttling.
+func isTransientCancellation(err error) bool {
+	var canceledErr *types.TransactionCanceledException
+
+	if !errors.As(err, &canceledErr) {
+		return false
+	}
+
+	for _, reason := range canceledErr.CancellationReasons {
+		if !transientCancellations[aws.ToString(reason.Code)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isConditionCanceled checks if the transaction was cancelled because the
+// condition expression of a write failed.
+func isConditionCanceled(err error) bool {
+	var canceledErr *types.TransactionCanceledException
+
+	if !errors.As(err, &canceledErr) {
+		return false
+	}
+
+	for _, reason := range canceledErr.CancellationReasons {
+		if aws.ToString(reason.Code) == conditionalCheckFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (d *DynamoDB) Count() int {
 	return 1
 }
@@ -0,0 +1,967 @@
+package document_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/document"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWithFiltersPaginates(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		filter    repository.FruitFilter
+		wantIDs   []string
+		wantTotal int
+	}{
+		"first_page": {
+			filter:    repository.FruitFilter{Start: 1, Count: 2},
+			wantIDs:   []string{"01", "02"},
+			wantTotal: 5,
+		},
+		"across_dynamodb_pages": {
+			filter:    repository.FruitFilter{Start: 2, Count: 3},
+			wantIDs:   []string{"02", "03", "04"},
+			wantTotal: 5,
+		},
+		"last_page": {
+			filter:    repository.FruitFilter{Start: 5, Count: 10},
+			wantIDs:   []string{"05"},
+			wantTotal: 5,
+		},
+		"out_of_range": {
+			filter:    repository.FruitFilter{Start: 10, Count: 10},
+			wantIDs:   []string{},
+			wantTotal: 5,
+		},
+	}
+
+	fakeDB := newFakeDynamoDB(2)
+	for i := 1; i <= 5; i++ {
+		id := "0" + strconv.Itoa(i)
+		fakeDB.put(map[string]interface{}{
+			"id":   map[string]string{"S": id},
+			"name": map[string]string{"S": "fruit " + id},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := repo.SearchWithFilters(context.TODO(), test.filter)
+
+			assert.NoError(st, err)
+			assert.Equal(st, test.wantTotal, got.Total)
+			assert.Equal(st, test.filter.Start, got.Start)
+			assert.Equal(st, test.filter.Count, got.Count)
+			assert.Equal(st, test.wantIDs, fruitIDs(got.Fruits))
+		})
+	}
+}
+
+func TestSearchWithFiltersEmptyTable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(newFakeDynamoDB(2))
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+
+	got, err := repo.SearchWithFilters(context.TODO(), repository.FruitFilter{Start: 1, Count: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, got.Total)
+	assert.Empty(t, got.Fruits)
+}
+
+func TestSearchWithFiltersSorted(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(2)
+	for i, name := range []string{"Nicosia", "Avidagos", "Tasca", "Stemmari", "Rainstorm"} {
+		fakeDB.put(map[string]interface{}{
+			"id":   map[string]string{"S": "0" + strconv.Itoa(i)},
+			"name": map[string]string{"S": name},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	got, err := repo.SearchWithFilters(context.TODO(), repository.FruitFilter{
+		Start: 2,
+		Count: 3,
+		Sort:  []repository.SortField{{Field: repository.SortByName, Descending: true}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, got.Total)
+	assert.Equal(t, []string{"03", "04", "00"}, fruitIDs(got.Fruits))
+}
+
+func TestSearchWithFiltersAfterCursor(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(2)
+	for i := 1; i <= 5; i++ {
+		fakeDB.put(map[string]interface{}{
+			"id":   map[string]string{"S": "0" + strconv.Itoa(i)},
+			"name": map[string]string{"S": "fruit"},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	var gotIDs []string
+
+	filter := repository.FruitFilter{Start: 1, Count: 2}
+
+	for page := 0; page < 5; page++ {
+		got, err := repo.SearchWithFilters(ctx, filter)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, got.Total)
+
+		gotIDs = append(gotIDs, fruitIDs(got.Fruits)...)
+
+		if got.Next == nil {
+			break
+		}
+
+		filter.After = got.Next
+	}
+
+	assert.Equal(t, []string{"01", "02", "03", "04", "05"}, gotIDs)
+
+	firstPageScans := 3
+	assert.Len(t, fakeDB.scans, firstPageScans+2)
+
+	for _, scan := range fakeDB.scans[firstPageScans:] {
+		assert.Contains(t, scan, "ExclusiveStartKey")
+		assert.Equal(t, float64(2), scan["Limit"])
+	}
+}
+
+func TestListFruits(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	for i := 1; i <= 5; i++ {
+		fakeDB.put(map[string]interface{}{
+			"id":   map[string]string{"S": "0" + strconv.Itoa(i)},
+			"name": map[string]string{"S": "fruit"},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	var gotIDs []string
+
+	var after *repository.Cursor
+
+	for page := 0; page < 5; page++ {
+		got, err := repo.ListFruits(context.TODO(), after, 2)
+		assert.NoError(t, err)
+
+		gotIDs = append(gotIDs, fruitIDs(got.Fruits)...)
+
+		if got.Next == nil {
+			break
+		}
+
+		after = got.Next
+	}
+
+	assert.Equal(t, []string{"01", "02", "03", "04", "05"}, gotIDs)
+	assert.Len(t, fakeDB.scans, 3)
+
+	for _, scan := range fakeDB.scans {
+		assert.Equal(t, float64(2), scan["Limit"])
+		assert.NotContains(t, scan, "Select")
+	}
+}
+
+func TestSearchWithFiltersFacets(t *testing.T) {
+	t.Parallel()
+
+	expectedFacets := repository.Facets{
+		"country": {{Value: "Italy", Count: 3}, {Value: "Portugal", Count: 2}},
+	}
+	fakeDB := newFakeDynamoDB(2)
+	for i, country := range []string{"Italy", "Portugal", "Italy", "Portugal", "Italy"} {
+		fakeDB.put(map[string]interface{}{
+			"id":      map[string]string{"S": "0" + strconv.Itoa(i)},
+			"country": map[string]string{"S": country},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+	filter := repository.FruitFilter{Start: 1, Count: 2, Facets: []string{repository.FacetByCountry}}
+
+	firstPage, err := repo.SearchWithFilters(ctx, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"00", "01"}, fruitIDs(firstPage.Fruits))
+	assert.Equal(t, expectedFacets, firstPage.Facets)
+
+	filter.After = firstPage.Next
+
+	secondPage, err := repo.SearchWithFilters(ctx, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"02", "03"}, fruitIDs(secondPage.Fruits))
+	assert.Equal(t, 5, secondPage.Total)
+	assert.Equal(t, expectedFacets, secondPage.Facets)
+}
+
+func TestSearchWithFiltersByIDs(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(2)
+	for i, country := range []string{"Italy", "Portugal", "Italy", "Italy"} {
+		fakeDB.put(map[string]interface{}{
+			"id":      map[string]string{"S": "0" + strconv.Itoa(i)},
+			"country": map[string]string{"S": country},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	got, err := repo.SearchWithFilters(context.TODO(), repository.FruitFilter{
+		Start:   1,
+		Count:   10,
+		Country: "Italy",
+		IDs:     []repository.FruitID{"03", "missing", "01", "00", "02"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got.Total)
+	assert.Equal(t, []string{"03", "00", "02"}, fruitIDs(got.Fruits))
+	assert.Empty(t, fakeDB.scans)
+}
+
+func TestSearchWithFiltersByIDsReadsOnlyThePage(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(2)
+	ids := make([]repository.FruitID, 0, 250)
+
+	for i := 0; i < 250; i++ {
+		id := strconv.Itoa(i)
+		ids = append(ids, repository.FruitID(id))
+		fakeDB.put(map[string]interface{}{
+			"id":   map[string]string{"S": id},
+			"name": map[string]string{"S": "fruit " + id},
+		})
+	}
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	got, err := repo.SearchWithFilters(context.TODO(), repository.FruitFilter{
+		Start: 101,
+		Count: 3,
+		IDs:   ids,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 250, got.Total)
+	assert.Equal(t, []string{"100", "101", "102"}, fruitIDs(got.Fruits))
+	assert.Equal(t, map[string]bool{"100": true, "101": true, "102": true}, fakeDB.requestedKeys)
+	assert.Empty(t, fakeDB.scans)
+}
+
+func TestSearchWithFiltersSendsFilterExpression(t *testing.T) {
+	t.Parallel()
+
+	minYear := 2010
+	maxPrice := float32(19.5)
+	expectedScan := map[string]interface{}{
+		"TableName":        "fruits",
+		"FilterExpression": "#country = :country AND #variety = :variety AND #year >= :min_year AND #price <= :max_price",
+		"ExpressionAttributeNames": map[string]interface{}{
+			"#country": "country",
+			"#variety": "variety",
+			"#year":    "year",
+			"#price":   "price",
+		},
+		"ExpressionAttributeValues": map[string]interface{}{
+			":country":   map[string]interface{}{"S": "Italy"},
+			":variety":   map[string]interface{}{"S": "White Blend"},
+			":min_year":  map[string]interface{}{"N": "2010"},
+			":max_price": map[string]interface{}{"N": "19.5"},
+		},
+	}
+	fakeDB := newFakeDynamoDB(2)
+
+	server := httptest.NewServer(fakeDB)
+	t.Cleanup(server.Close)
+
+	repo := newDynamoDB(t, server.URL)
+
+	_, err := repo.SearchWithFilters(context.TODO(), repository.FruitFilter{
+		Start:    1,
+		Count:    10,
+		Country:  "Italy",
+		Variety:  "White Blend",
+		MinYear:  &minYear,
+		MaxPrice: &maxPrice,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{expectedScan}, fakeDB.scans)
+}
+
+func TestSaveWithTakenID(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(100)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	fruitID, err := repo.Save(ctx, repository.NewFruit{ID: "1234", Name: "Mango"})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.FruitID("1234"), fruitID)
+
+	fruitID, err = repo.Save(ctx, repository.NewFruit{ID: "1234", Name: "Pear"})
+
+	assert.ErrorIs(t, err, repository.ErrFruitExists)
+	assert.Empty(t, fruitID)
+	assert.Equal(t, map[string]interface{}{"S": "Mango"}, fakeDB.items["1234"]["name"])
+	assert.Len(t, fakeDB.events, 1)
+}
+
+func TestSaveAll(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(100)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	newFruits := make([]repository.NewFruit, 0, 30)
+
+	for index := 0; index < 30; index++ {
+		newFruits = append(newFruits, repository.NewFruit{Name: "fruit " + strconv.Itoa(index)})
+	}
+
+	fakeDB.cancelTransactions = 1
+
+	results := repo.SaveAll(context.TODO(), newFruits)
+
+	assert.Len(t, results, 30)
+	assert.Equal(t, []int{50, 50, 10}, fakeDB.transactions)
+	assert.Len(t, fakeDB.items, 30)
+	assert.Len(t, fakeDB.events, 30)
+
+	for index, result := range results {
+		assert.NoError(t, result.Err)
+		assert.Equal(t, map[string]interface{}{"S": newFruits[index].Name}, fakeDB.items[repository.FruitIDValue(result.ID)]["name"])
+	}
+
+	for _, item := range fakeDB.events {
+		var event repository.Event
+
+		assert.NoError(t, json.Unmarshal([]byte(stringAttribute(item, "event")), &event))
+		assert.NotEmpty(t, fakeDB.items[event.FruitID()])
+	}
+}
+
+func TestSaveAllStoresFruitsOneByOneWhenChunkIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(100)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	fakeDB.rejectedName = "Pear"
+
+	results := repo.SaveAll(context.TODO(), []repository.NewFruit{{Name: "Mango"}, {Name: "Pear"}, {Name: "Apple"}})
+
+	assert.Len(t, results, 3)
+	// the chunk is not tried again, its fruits are written one by one.
+	assert.Equal(t, []int{6, 2, 2, 2}, fakeDB.transactions)
+	assert.Len(t, fakeDB.items, 2)
+	assert.Len(t, fakeDB.events, 2)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, map[string]interface{}{"S": "Mango"}, fakeDB.items[repository.FruitIDValue(results[0].ID)]["name"])
+	assert.Error(t, results[1].Err)
+	assert.Empty(t, results[1].ID)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, map[string]interface{}{"S": "Apple"}, fakeDB.items[repository.FruitIDValue(results[2].ID)]["name"])
+}
+
+func TestSaveAllFailsWhenTransactionsKeepBeingCancelled(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(100)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	// the chunk and then every fruit are tried five times.
+	fakeDB.cancelTransactions = 15
+
+	results := repo.SaveAll(context.TODO(), []repository.NewFruit{{Name: "Mango"}, {Name: "Pear"}})
+
+	assert.Len(t, results, 2)
+	assert.Empty(t, fakeDB.items)
+	assert.Empty(t, fakeDB.events)
+
+	for _, result := range results {
+		assert.Error(t, result.Err)
+		assert.Empty(t, result.ID)
+	}
+}
+
+func TestChangeFruitStoredWithoutVersion(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	fakeDB.put(map[string]interface{}{
+		"id":   map[string]string{"S": "legacy"},
+		"name": map[string]string{"S": "Mango"},
+	})
+
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	legacyMango, err := repo.FindByID(ctx, "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, repository.FirstVersion, legacyMango.Version)
+
+	err = repo.Delete(ctx, "legacy", 2)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	legacyMango.Variety = "Kent"
+
+	err = repo.Update(ctx, *legacyMango)
+	assert.NoError(t, err)
+
+	mango, err := repo.FindByID(ctx, "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), mango.Version)
+	assert.Equal(t, "Kent", mango.Variety)
+
+	err = repo.Delete(ctx, "legacy", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, fakeDB.items)
+}
+
+func newDynamoDB(t *testing.T, endpoint string) *document.DynamoDB {
+	t.Helper()
+
+	setup := document.Setup{
+		Logger:   loggers.NewLoggerWithStdout("", loggers.Error),
+		Region:   "us-east-1",
+		Endpoint: endpoint,
+	}
+
+	repo, err := document.NewDynamoDBClient(context.TODO(), setup)
+	if err != nil {
+		t.Fatalf("unexpected error creating dynamodb client: %s", err)
+	}
+
+	return repo
+}
+
+func fruitIDs(fruits []repository.Fruit) []string {
+	ids := make([]string, 0, len(fruits))
+	for _, fruit := range fruits {
+		ids = append(ids, repository.FruitIDValue(fruit.ID))
+	}
+
+	return ids
+}
+
+// fakeDynamoDB is a minimal DynamoDB stand-in that speaks the json protocol.
+// Items are kept ordered by id and scans return pageSize items per page,
+// filter expressions are not evaluated but every scan input is recorded.
+type fakeDynamoDB struct {
+	mu       sync.Mutex
+	pageSize int
+	items    map[string]map[string]interface{}
+	scans    []map[string]interface{}
+	// batchGets number of BatchGetItem calls.
+	batchGets int
+	// requestedKeys ids of the fruits requested by BatchGetItem calls.
+	requestedKeys map[string]bool
+	// transactions number of writes of every TransactWriteItems call.
+	transactions []int
+	// cancelTransactions number of the next TransactWriteItems calls that are cancelled.
+	cancelTransactions int
+	// rejectedName is the name of the fruits whose transaction writes are
+	// cancelled with a validation error.
+	rejectedName string
+	// keys items of the idempotency keys table by key.
+	keys map[string]map[string]interface{}
+	// events items of the outbox table by id.
+	events map[string]map[string]interface{}
+}
+
+func newFakeDynamoDB(pageSize int) *fakeDynamoDB {
+	return &fakeDynamoDB{
+		pageSize:      pageSize,
+		items:         make(map[string]map[string]interface{}),
+		requestedKeys: make(map[string]bool),
+		keys:          make(map[string]map[string]interface{}),
+		events:        make(map[string]map[string]interface{}),
+	}
+}
+
+func (f *fakeDynamoDB) put(item map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, _ := item["id"].(map[string]string)
+	f.items[id["S"]] = item
+}
+
+func (f *fakeDynamoDB) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	operation := strings.TrimPrefix(req.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+
+	var input map[string]interface{}
+
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var output interface{}
+
+	switch operation {
+	case "Scan":
+		output = f.scan(input)
+	case "BatchGetItem":
+		output = f.batchGetItem(input)
+	case "TransactWriteItems":
+		result, reasons := f.transactWriteItems(input)
+		if result == nil {
+			res.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			res.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(res).Encode(map[string]interface{}{
+				"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+				"message":             "Transaction cancelled",
+				"CancellationReasons": cancellationReasons(reasons),
+			})
+
+			return
+		}
+
+		output = result
+	case "PutItem", "GetItem", "UpdateItem", "DeleteItem":
+		var ok bool
+
+		switch input["TableName"] {
+		case "fruit_outbox":
+			output, ok = f.eventItem(operation, input)
+		case "fruits":
+			output, ok = f.fruitItem(operation, input)
+		default:
+			output, ok = f.keyItem(operation, input)
+		}
+
+		if !ok {
+			res.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			res.WriteHeader(http.StatusBadRequest)
+			_, _ = res.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`))
+
+			return
+		}
+	default:
+		http.Error(res, "unsupported operation "+operation, http.StatusBadRequest)
+
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/x-amz-json-1.0")
+
+	_ = json.NewEncoder(res).Encode(output)
+}
+
+func (f *fakeDynamoDB) scan(input map[string]interface{}) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.scans = append(f.scans, input)
+
+	items := f.items
+	if input["TableName"] == "fruit_outbox" {
+		items = f.events
+	}
+
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	var startAfter string
+
+	if key, ok := input["ExclusiveStartKey"].(map[string]interface{}); ok {
+		id, _ := key["id"].(map[string]interface{})
+		startAfter, _ = id["S"].(string)
+	}
+
+	pageSize := f.pageSize
+	if limit, ok := input["Limit"].(float64); ok && int(limit) < pageSize {
+		pageSize = int(limit)
+	}
+
+	page := make([]interface{}, 0, pageSize)
+
+	var lastID string
+
+	for _, id := range ids {
+		if startAfter != "" && id <= startAfter {
+			continue
+		}
+
+		if len(page) == pageSize {
+			break
+		}
+
+		page = append(page, items[id])
+		lastID = id
+	}
+
+	output := map[string]interface{}{
+		"Items":        page,
+		"Count":        len(page),
+		"ScannedCount": len(page),
+	}
+
+	if input["Select"] == "COUNT" {
+		delete(output, "Items")
+	}
+
+	if lastID != "" && lastID != ids[len(ids)-1] {
+		output["LastEvaluatedKey"] = map[string]interface{}{
+			"id": map[string]string{"S": lastID},
+		}
+	}
+
+	return output
+}
+
+// batchGetItem returns the requested items that exist, every other call
+// leaves the last requested key unprocessed.
+func (f *fakeDynamoDB) batchGetItem(input map[string]interface{}) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.batchGets++
+
+	requestItems, _ := input["RequestItems"].(map[string]interface{})
+	table, _ := requestItems["fruits"].(map[string]interface{})
+	keys, _ := table["Keys"].([]interface{})
+
+	var unprocessed []interface{}
+
+	for _, key := range keys {
+		attributes, _ := key.(map[string]interface{})
+		f.requestedKeys[stringAttribute(attributes, "id")] = true
+	}
+
+	if f.batchGets%2 == 1 && len(keys) > 1 {
+		unprocessed = keys[len(keys)-1:]
+		keys = keys[:len(keys)-1]
+	}
+
+	items := make([]interface{}, 0, len(keys))
+
+	for _, key := range keys {
+		attributes, _ := key.(map[string]interface{})
+		id, _ := attributes["id"].(map[string]interface{})
+
+		if item, ok := f.items[id["S"].(string)]; ok {
+			items = append(items, item)
+		}
+	}
+
+	output := map[string]interface{}{
+		"Responses": map[string]interface{}{"fruits": items},
+	}
+
+	if len(unprocessed) > 0 {
+		output["UnprocessedKeys"] = map[string]interface{}{
+			"fruits": map[string]interface{}{"Keys": unprocessed},
+		}
+	}
+
+	return output
+}
+
+// transactWriteItems runs the puts and deletes of the transaction if every
+// version condition holds. It returns no output and the cancellation reason
+// of every write while there are transactions to cancel or a condition fails.
+func (f *fakeDynamoDB) transactWriteItems(input map[string]interface{}) (map[string]interface{}, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transactItems, _ := input["TransactItems"].([]interface{})
+	reasons := make([]string, len(transactItems))
+	canceled := false
+
+	f.transactions = append(f.transactions, len(transactItems))
+
+	for index, transactItem := range transactItems {
+		reasons[index] = "None"
+
+		write := transactionWrite(transactItem)
+		item, _ := write["Item"].(map[string]interface{})
+
+		switch {
+		case !f.meetsVersionCondition(write):
+			reasons[index] = "ConditionalCheckFailed"
+			canceled = true
+		case f.rejectedName != "" && stringAttribute(item, "name") == f.rejectedName:
+			reasons[index] = "ValidationError"
+			canceled = true
+		}
+	}
+
+	if f.cancelTransactions > 0 {
+		f.cancelTransactions--
+
+		return nil, nil
+	}
+
+	if canceled {
+		return nil, reasons
+	}
+
+	for _, transactItem := range transactItems {
+		write, _ := transactItem.(map[string]interface{})
+
+		if del, ok := write["Delete"].(map[string]interface{}); ok {
+			key, _ := del["Key"].(map[string]interface{})
+			delete(f.items, stringAttribute(key, "id"))
+
+			continue
+		}
+
+		put, _ := write["Put"].(map[string]interface{})
+		item, _ := put["Item"].(map[string]interface{})
+
+		if put["TableName"] == "fruit_outbox" {
+			f.events[stringAttribute(item, "id")] = item
+
+			continue
+		}
+
+		f.items[stringAttribute(item, "id")] = item
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+// meetsVersionCondition checks if the fruit of a write with a version
+// condition exists with the expected version, a fruit without version meets
+// it only if the condition allows it. The fruit of a new fruit write must
+// not exist. The caller must hold the lock.
+func (f *fakeDynamoDB) meetsVersionCondition(write map[string]interface{}) bool {
+	if write["ConditionExpression"] == nil {
+		return true
+	}
+
+	if write["ConditionExpression"] == "attribute_not_exists(id)" {
+		item, _ := write["Item"].(map[string]interface{})
+		_, exists := f.items[stringAttribute(item, "id")]
+
+		return !exists
+	}
+
+	key, _ := write["Key"].(map[string]interface{})
+	if key == nil {
+		key, _ = write["Item"].(map[string]interface{})
+	}
+
+	values, _ := write["ExpressionAttributeValues"].(map[string]interface{})
+	current, exists := f.items[stringAttribute(key, "id")]
+
+	if _, versioned := current["version"]; exists && !versioned {
+		condition, _ := write["ConditionExpression"].(string)
+
+		return strings.Contains(condition, "attribute_not_exists(#version)")
+	}
+
+	return exists && numberAttribute(current, "version") == numberAttribute(values, ":version")
+}
+
+// transactionWrite returns the put or delete of a transaction item.
+func transactionWrite(transactItem interface{}) map[string]interface{} {
+	write, _ := transactItem.(map[string]interface{})
+
+	if del, ok := write["Delete"].(map[string]interface{}); ok {
+		return del
+	}
+
+	put, _ := write["Put"].(map[string]interface{})
+
+	return put
+}
+
+func cancellationReasons(codes []string) []map[string]string {
+	reasons := make([]map[string]string, 0, len(codes))
+
+	for _, code := range codes {
+		reasons = append(reasons, map[string]string{"Code": code})
+	}
+
+	return reasons
+}
+
+// fruitItem runs item operations on the fruits table.
+func (f *fakeDynamoDB) fruitItem(operation string, input map[string]interface{}) (map[string]interface{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, _ := input["Key"].(map[string]interface{})
+
+	if current, exists := f.items[stringAttribute(key, "id")]; exists && operation == "GetItem" {
+		return map[string]interface{}{"Item": current}, true
+	}
+
+	return map[string]interface{}{}, true
+}
+
+// keyItem runs item operations on the idempotency keys table, it returns
+// false if the key condition of the operation fails.
+func (f *fakeDynamoDB) keyItem(operation string, input map[string]interface{}) (map[string]interface{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	item, _ := input["Item"].(map[string]interface{})
+	if item == nil {
+		item, _ = input["Key"].(map[string]interface{})
+	}
+
+	key := stringAttribute(item, "key")
+	current, exists := f.keys[key]
+	values, _ := input["ExpressionAttributeValues"].(map[string]interface{})
+
+	switch operation {
+	case "PutItem":
+		if exists && numberAttribute(current, "expires_at") > numberAttribute(values, ":now") {
+			return nil, false
+		}
+
+		f.keys[key] = item
+	case "GetItem":
+		if exists {
+			return map[string]interface{}{"Item": current}, true
+		}
+	case "UpdateItem":
+		if !exists || !keyIsHeld(current, values) {
+			return nil, false
+		}
+
+		current["fruit_id"] = values[":fruit_id"]
+		current["expires_at"] = values[":expires_at"]
+	case "DeleteItem":
+		if !exists || !keyIsHeld(current, values) {
+			return nil, false
+		}
+
+		delete(f.keys, key)
+	}
+
+	return map[string]interface{}{}, true
+}
+
+// keyIsHeld checks the condition of the key updates and deletes: the key has
+// the reservation fingerprint and lease and is not completed nor expired.
+func keyIsHeld(current, values map[string]interface{}) bool {
+	_, completed := current["fruit_id"]
+
+	return stringAttribute(current, "fingerprint") == stringAttribute(values, ":fingerprint") &&
+		numberAttribute(current, "expires_at") == numberAttribute(values, ":reserved_until") &&
+		numberAttribute(current, "expires_at") > numberAttribute(values, ":now") &&
+		!completed
+}
+
+// eventItem runs item operations on the outbox table, it returns false if
+// the event of an update doesn't exist.
+func (f *fakeDynamoDB) eventItem(operation string, input map[string]interface{}) (map[string]interface{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, _ := input["Key"].(map[string]interface{})
+	id := stringAttribute(key, "id")
+	current, exists := f.events[id]
+	values, _ := input["ExpressionAttributeValues"].(map[string]interface{})
+
+	switch operation {
+	case "UpdateItem":
+		if !exists {
+			return nil, false
+		}
+
+		current["attempts"] = values[":attempts"]
+		current["next_attempt_at"] = values[":next_attempt_at"]
+		current["last_error"] = values[":last_error"]
+	case "DeleteItem":
+		delete(f.events, id)
+	}
+
+	return map[string]interface{}{}, true
+}
+
+func stringAttribute(item map[string]interface{}, name string) string {
+	attribute, _ := item[name].(map[string]interface{})
+	value, _ := attribute["S"].(string)
+
+	return value
+}
+
+func numberAttribute(item map[string]interface{}, name string) int64 {
+	attribute, _ := item[name].(map[string]interface{})
+	value, _ := attribute["N"].(string)
+	number, _ := strconv.ParseInt(value, 10, 64)
+
+	return number
+}
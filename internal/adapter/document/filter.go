@@ -0,0 +1,86 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// scanFilter collects the conditions of a scan filter expression.
+type scanFilter struct {
+	conditions []string
+	names      map[string]string
+	values     map[string]types.AttributeValue
+}
+
+// newScanInput creates the scan of the fruits table that only returns the
+// fruits that match the given filter.
+func newScanInput(filter repository.FruitFilter) *dynamodb.ScanInput {
+	scanInput := dynamodb.ScanInput{
+		TableName: aws.String(fruitsTable),
+	}
+
+	expression := scanFilter{
+		names:  make(map[string]string),
+		values: make(map[string]types.AttributeValue),
+	}
+
+	expression.equal("country", filter.Country)
+	expression.equal("province", filter.Province)
+	expression.equal("region", filter.Region)
+	expression.equal("variety", filter.Variety)
+	expression.equal("classification", filter.Classification)
+	expression.equal("vault", filter.Vault)
+
+	if filter.MinYear != nil {
+		expression.compare("year", ">=", "min_year", strconv.Itoa(*filter.MinYear))
+	}
+
+	if filter.MaxYear != nil {
+		expression.compare("year", "<=", "max_year", strconv.Itoa(*filter.MaxYear))
+	}
+
+	if filter.MinPrice != nil {
+		expression.compare("price", ">=", "min_price", formatPrice(*filter.MinPrice))
+	}
+
+	if filter.MaxPrice != nil {
+		expression.compare("price", "<=", "max_price", formatPrice(*filter.MaxPrice))
+	}
+
+	if len(expression.conditions) == 0 {
+		return &scanInput
+	}
+
+	scanInput.FilterExpression = aws.String(strings.Join(expression.conditions, " AND "))
+	scanInput.ExpressionAttributeNames = expression.names
+	scanInput.ExpressionAttributeValues = expression.values
+
+	return &scanInput
+}
+
+// equal adds an exact match condition on the given attribute, empty values are ignored.
+func (s *scanFilter) equal(attribute, value string) {
+	if value == "" {
+		return
+	}
+
+	s.names["#"+attribute] = attribute
+	s.values[":"+attribute] = &types.AttributeValueMemberS{Value: value}
+	s.conditions = append(s.conditions, "#"+attribute+" = :"+attribute)
+}
+
+// compare adds a numeric condition on the given attribute.
+func (s *scanFilter) compare(attribute, operator, placeholder, number string) {
+	s.names["#"+attribute] = attribute
+	s.values[":"+placeholder] = &types.AttributeValueMemberN{Value: number}
+	s.conditions = append(s.conditions, "#"+attribute+" "+operator+" :"+placeholder)
+}
+
+func formatPrice(price float32) string {
+	return strconv.FormatFloat(float64(price), 'f', -1, 32)
+}
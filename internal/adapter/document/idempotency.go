@@ -0,0 +1,275 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// idempotencyKeysTable keeps the idempotency keys, its time to live
+// attribute must be expires_at so DynamoDB deletes the expired keys.
+const idempotencyKeysTable = "fruit_idempotency_keys"
+
+// keyIsFree condition to reserve keys that are unknown or expired.
+const keyIsFree = "attribute_not_exists(#key) OR expires_at <= :now"
+
+// keyIsHeld condition to change keys only while the request that reserved
+// them holds them: same fingerprint and lease, not completed nor expired.
+const keyIsHeld = "fingerprint = :fingerprint AND expires_at = :reserved_until AND expires_at > :now AND attribute_not_exists(fruit_id)"
+
+// maxReserveAttempts is the number of times a key is reserved while the
+// record that holds it is deleted in between.
+const maxReserveAttempts = 3
+
+var (
+	errReservingKey  = errors.New("unable to reserve idempotency key")
+	errCompletingKey = errors.New("unable to complete idempotency key")
+	errReleasingKey  = errors.New("unable to release idempotency key")
+)
+
+// IdempotencyKeys defines logic for a dynamodb store of idempotency keys.
+type IdempotencyKeys struct {
+	client *dynamodb.Client
+	logger *loggers.Logger
+}
+
+// idempotencyRecord is the item of an idempotency key.
+type idempotencyRecord struct {
+	Key         string `dynamodbav:"key"`
+	Fingerprint string `dynamodbav:"fingerprint"`
+	FruitID     string `dynamodbav:"fruit_id,omitempty"`
+	// ExpiresAt is the unix time in seconds the key is forgotten.
+	ExpiresAt int64 `dynamodbav:"expires_at"`
+}
+
+// IdempotencyKeys returns a store of idempotency keys that shares the client of the repository.
+func (d *DynamoDB) IdempotencyKeys() *IdempotencyKeys {
+	return &IdempotencyKeys{
+		client: d.client,
+		logger: d.logger,
+	}
+}
+
+// Reserve keeps the record if its key is unknown or expired, otherwise it
+// returns the record already kept for the key.
+func (i *IdempotencyKeys) Reserve(ctx context.Context, record repository.IdempotencyRecord) (*repository.IdempotencyRecord, error) {
+	data, err := attributevalue.MarshalMap(toIdempotencyRecord(record))
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency record", loggers.Fields{"error": err})
+
+		return nil, errReservingKey
+	}
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		now, err := attributevalue.Marshal(time.Now().Unix())
+		if err != nil {
+			i.logger.Error("unable to marshal current time", loggers.Fields{"error": err})
+
+			return nil, errReservingKey
+		}
+
+		_, err = i.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:                 aws.String(idempotencyKeysTable),
+			Item:                      data,
+			ConditionExpression:       aws.String(keyIsFree),
+			ExpressionAttributeNames:  map[string]string{"#key": "key"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":now": now},
+		})
+		if err == nil {
+			return nil, nil
+		}
+
+		if !isConditionalCheckFailed(err) {
+			i.logger.Error("unable to reserve idempotency key", loggers.Fields{"error": err})
+
+			return nil, errReservingKey
+		}
+
+		current, err := i.find(ctx, record.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		if current != nil {
+			return current, nil
+		}
+	}
+
+	i.logger.Error("idempotency key kept changing while it was reserved", loggers.Fields{"key": record.Key})
+
+	return nil, errReservingKey
+}
+
+// Complete sets the fruit created by the request that holds the reservation
+// and keeps the key until expiresAt. It returns repository.ErrIdempotencyKeyLost
+// if the key expired or another request reserved it.
+func (i *IdempotencyKeys) Complete(ctx context.Context, reservation repository.IdempotencyRecord, fruitID repository.FruitID, expiresAt time.Time) error {
+	itemKey, err := attributevalue.MarshalMap(map[string]string{"key": reservation.Key})
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency key", loggers.Fields{"error": err})
+
+		return errCompletingKey
+	}
+
+	values, err := heldKeyValues(reservation)
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency reservation", loggers.Fields{"error": err})
+
+		return errCompletingKey
+	}
+
+	values[":fruit_id"], err = attributevalue.Marshal(repository.FruitIDValue(fruitID))
+	if err != nil {
+		i.logger.Error("unable to marshal fruit id", loggers.Fields{"error": err})
+
+		return errCompletingKey
+	}
+
+	values[":expires_at"], err = attributevalue.Marshal(expiresAt.Unix())
+	if err != nil {
+		i.logger.Error("unable to marshal expiration time", loggers.Fields{"error": err})
+
+		return errCompletingKey
+	}
+
+	_, err = i.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(idempotencyKeysTable),
+		Key:                       itemKey,
+		UpdateExpression:          aws.String("SET fruit_id = :fruit_id, expires_at = :expires_at"),
+		ConditionExpression:       aws.String(keyIsHeld),
+		ExpressionAttributeValues: values,
+	})
+	if isConditionalCheckFailed(err) {
+		return repository.ErrIdempotencyKeyLost
+	}
+
+	if err != nil {
+		i.logger.Error("unable to complete idempotency key", loggers.Fields{"error": err})
+
+		return errCompletingKey
+	}
+
+	return nil
+}
+
+// Release forgets the key if the reservation still holds it.
+func (i *IdempotencyKeys) Release(ctx context.Context, reservation repository.IdempotencyRecord) error {
+	itemKey, err := attributevalue.MarshalMap(map[string]string{"key": reservation.Key})
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency key", loggers.Fields{"error": err})
+
+		return errReleasingKey
+	}
+
+	values, err := heldKeyValues(reservation)
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency reservation", loggers.Fields{"error": err})
+
+		return errReleasingKey
+	}
+
+	_, err = i.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(idempotencyKeysTable),
+		Key:                       itemKey,
+		ConditionExpression:       aws.String(keyIsHeld),
+		ExpressionAttributeValues: values,
+	})
+	if isConditionalCheckFailed(err) {
+		return nil
+	}
+
+	if err != nil {
+		i.logger.Error("unable to release idempotency key", loggers.Fields{"error": err})
+
+		return errReleasingKey
+	}
+
+	return nil
+}
+
+// heldKeyValues returns the values of the keyIsHeld condition for the reservation.
+func heldKeyValues(reservation repository.IdempotencyRecord) (map[string]types.AttributeValue, error) {
+	fingerprint, err := attributevalue.Marshal(reservation.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedUntil, err := attributevalue.Marshal(reservation.ExpiresAt.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := attributevalue.Marshal(time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]types.AttributeValue{
+		":fingerprint":    fingerprint,
+		":reserved_until": reservedUntil,
+		":now":            now,
+	}, nil
+}
+
+// find returns the record of the key, nil if it doesn't exist.
+func (i *IdempotencyKeys) find(ctx context.Context, key string) (*repository.IdempotencyRecord, error) {
+	itemKey, err := attributevalue.MarshalMap(map[string]string{"key": key})
+	if err != nil {
+		i.logger.Error("unable to marshal idempotency key", loggers.Fields{"error": err})
+
+		return nil, errReservingKey
+	}
+
+	data, err := i.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(idempotencyKeysTable),
+		Key:            itemKey,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		i.logger.Error("unable to get idempotency key", loggers.Fields{"error": err})
+
+		return nil, errReservingKey
+	}
+
+	if data.Item == nil {
+		return nil, nil
+	}
+
+	var item idempotencyRecord
+
+	err = attributevalue.UnmarshalMap(data.Item, &item)
+	if err != nil {
+		i.logger.Error("unable to unmarshal idempotency record", loggers.Fields{"error": err})
+
+		return nil, errReservingKey
+	}
+
+	record := item.toRepositoryRecord()
+
+	return &record, nil
+}
+
+func toIdempotencyRecord(record repository.IdempotencyRecord) idempotencyRecord {
+	return idempotencyRecord{
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		FruitID:     repository.FruitIDValue(record.FruitID),
+		ExpiresAt:   record.ExpiresAt.Unix(),
+	}
+}
+
+func (i idempotencyRecord) toRepositoryRecord() repository.IdempotencyRecord {
+	return repository.IdempotencyRecord{
+		Key:         i.Key,
+		Fingerprint: i.Fingerprint,
+		FruitID:     repository.FruitID(i.FruitID),
+		ExpiresAt:   time.Unix(i.ExpiresAt, 0),
+	}
+}
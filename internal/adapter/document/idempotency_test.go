@@ -0,0 +1,142 @@
+package document_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	keys := newDynamoDB(t, server.URL).IdempotencyKeys()
+	ctx := context.TODO()
+	record := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Unix(time.Now().Add(time.Minute).Unix(), 0),
+	}
+	expectedRecord := record
+	expectedRecord.FruitID = "1234"
+	expectedRecord.ExpiresAt = time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+
+	reserved, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+
+	err = keys.Complete(ctx, record, "1234", expectedRecord.ExpiresAt)
+	assert.NoError(t, err)
+
+	reserved, err = keys.Reserve(ctx, repository.IdempotencyRecord{Key: record.Key, Fingerprint: "def", ExpiresAt: record.ExpiresAt})
+	assert.NoError(t, err)
+	assert.Equal(t, &expectedRecord, reserved)
+
+	// a completed key is kept until it expires.
+	err = keys.Release(ctx, record)
+	assert.NoError(t, err)
+
+	reserved, err = keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Equal(t, &expectedRecord, reserved)
+}
+
+func TestReleasedIdempotencyKeyCanBeReservedAgain(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	keys := newDynamoDB(t, server.URL).IdempotencyKeys()
+	ctx := context.TODO()
+	record := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Unix(time.Now().Add(time.Minute).Unix(), 0),
+	}
+
+	_, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+
+	err = keys.Release(ctx, record)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+}
+
+func TestCompleteLostIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	keys := newDynamoDB(t, server.URL).IdempotencyKeys()
+	ctx := context.TODO()
+	expiredRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Unix(time.Now().Add(-time.Minute).Unix(), 0),
+	}
+	retryRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Unix(time.Now().Add(time.Minute).Unix(), 0),
+	}
+
+	err := keys.Complete(ctx, retryRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	_, err = keys.Reserve(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	err = keys.Complete(ctx, expiredRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	_, err = keys.Reserve(ctx, retryRecord)
+	assert.NoError(t, err)
+
+	err = keys.Complete(ctx, expiredRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	err = keys.Release(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, retryRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, &retryRecord, reserved)
+}
+
+func TestReserveExpiredIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	keys := newDynamoDB(t, server.URL).IdempotencyKeys()
+	ctx := context.TODO()
+	expiredRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+
+	_, err := keys.Reserve(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, repository.IdempotencyRecord{Key: "3a5f7c1e", Fingerprint: "def", ExpiresAt: time.Now().Add(time.Hour)})
+
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+}
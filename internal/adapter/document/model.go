@@ -1,6 +1,10 @@
 package document
 
-import "github.com/fernandoocampo/fruits/internal/adapter/repository"
+import (
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
 
 // Fruit contains data to create a new fruit.
 type Fruit struct {
@@ -18,6 +22,11 @@ type Fruit struct {
 	Classification string   `json:"classification" dynamodbav:"classification"`
 	LocalName      string   `json:"local_name" dynamodbav:"local_name"`
 	WikiPage       string   `json:"wiki_page,omitempty" dynamodbav:"wiki_page"`
+	// Version is missing on fruits stored before it was tracked, they are
+	// read in repository.FirstVersion.
+	Version int64 `json:"version" dynamodbav:"version"`
+	// LastModified is empty on fruits stored before it was tracked.
+	LastModified time.Time `json:"last_modified" dynamodbav:"last_modified"`
 }
 
 // transformFruit transforms new fruit to a repository fruit.
@@ -26,6 +35,11 @@ func (f *Fruit) toRepositoryFruit() *repository.Fruit {
 		return nil
 	}
 
+	version := f.Version
+	if version == 0 {
+		version = repository.FirstVersion
+	}
+
 	return &repository.Fruit{
 		ID:             repository.FruitID(f.ID),
 		Name:           f.Name,
@@ -41,6 +55,30 @@ func (f *Fruit) toRepositoryFruit() *repository.Fruit {
 		Classification: f.Classification,
 		LocalName:      f.LocalName,
 		WikiPage:       f.WikiPage,
+		Version:        version,
+		LastModified:   f.LastModified,
+	}
+}
+
+// fromRepositoryFruit transforms a repository fruit to a dynamodb fruit.
+func fromRepositoryFruit(fruit repository.Fruit) Fruit {
+	return Fruit{
+		ID:             repository.FruitIDValue(fruit.ID),
+		Name:           fruit.Name,
+		Variety:        fruit.Variety,
+		Year:           fruit.Year,
+		Price:          fruit.Price,
+		Vault:          fruit.Vault,
+		Country:        fruit.Country,
+		Province:       fruit.Province,
+		Region:         fruit.Region,
+		Finca:          fruit.Finca,
+		Description:    fruit.Description,
+		Classification: fruit.Classification,
+		LocalName:      fruit.LocalName,
+		WikiPage:       fruit.WikiPage,
+		Version:        fruit.Version,
+		LastModified:   fruit.LastModified,
 	}
 }
 
@@ -61,5 +99,7 @@ func transformFruit(fruitID string, fruit repository.NewFruit) Fruit {
 		Classification: fruit.Classification,
 		LocalName:      fruit.LocalName,
 		WikiPage:       fruit.WikiPage,
+		Version:        repository.FirstVersion,
+		LastModified:   time.Now().UTC(),
 	}
 }
@@ -0,0 +1,250 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// outboxTable keeps the events written in the same transaction as the
+// fruits they announce until they are published.
+const outboxTable = "fruit_outbox"
+
+// eventIsDue filters the events whose next attempt already passed.
+const eventIsDue = "next_attempt_at <= :now"
+
+var (
+	errReadingOutbox      = errors.New("unable to read outbox events")
+	errAcknowledgingEvent = errors.New("unable to acknowledge outbox event")
+	errRetryingEvent      = errors.New("unable to retry outbox event")
+	// errInvalidOutboxRecord is returned for records without event id.
+	errInvalidOutboxRecord = errors.New("outbox record has no event id")
+)
+
+// outboxRecord is the item of an outbox event.
+type outboxRecord struct {
+	ID string `dynamodbav:"id"`
+	// Event is the event encoded in JSON, it holds the data of every type of
+	// event. It is empty on the new fruit events recorded before it existed,
+	// their data is in the legacy fields.
+	Event string `dynamodbav:"event,omitempty"`
+	// SourceID, Name, Variety and Price are the legacy fields.
+	SourceID string  `dynamodbav:"source_id,omitempty"`
+	Name     string  `dynamodbav:"name,omitempty"`
+	Variety  string  `dynamodbav:"variety,omitempty"`
+	Price    float32 `dynamodbav:"price,omitempty"`
+	// RecordedAt is the unix time in milliseconds the event was recorded.
+	RecordedAt int64 `dynamodbav:"recorded_at"`
+	Attempts   int   `dynamodbav:"attempts"`
+	// NextAttemptAt is the unix time in milliseconds the event is due.
+	NextAttemptAt int64  `dynamodbav:"next_attempt_at"`
+	LastError     string `dynamodbav:"last_error,omitempty"`
+}
+
+// PendingEvents returns up to limit events of the outbox that are due at the
+// given moment. DynamoDB scans in no particular order, the events read are
+// returned the oldest first. Events that cannot be decoded are logged and
+// skipped, so they don't hold back the rest of the outbox.
+func (d *DynamoDB) PendingEvents(ctx context.Context, now time.Time, limit int) ([]repository.OutboxEvent, error) {
+	nowValue, err := attributevalue.Marshal(now.UnixMilli())
+	if err != nil {
+		d.logger.Error("unable to marshal current time", loggers.Fields{"error": err})
+
+		return nil, errReadingOutbox
+	}
+
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:                 aws.String(outboxTable),
+		FilterExpression:          aws.String(eventIsDue),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":now": nowValue},
+		ConsistentRead:            aws.Bool(true),
+	})
+
+	pending := make([]repository.OutboxEvent, 0)
+
+	for paginator.HasMorePages() && len(pending) < limit {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			d.logger.Error("unable to scan outbox events", loggers.Fields{"error": err})
+
+			return nil, errReadingOutbox
+		}
+
+		for _, item := range page.Items {
+			var record outboxRecord
+
+			err = attributevalue.UnmarshalMap(item, &record)
+			if err != nil {
+				d.logger.Error("unable to unmarshal outbox event, it is skipped", loggers.Fields{"error": err})
+
+				continue
+			}
+
+			event, err := record.toRepositoryEvent()
+			if err != nil {
+				d.logger.Error("unable to decode outbox event, it is skipped", loggers.Fields{"id": record.ID, "error": err})
+
+				continue
+			}
+
+			if event.Due(now) && len(pending) < limit {
+				pending = append(pending, event)
+			}
+		}
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Event.OccurredAt.Before(pending[j].Event.OccurredAt)
+	})
+
+	return pending, nil
+}
+
+// AcknowledgeEvent removes a published event from the outbox.
+func (d *DynamoDB) AcknowledgeEvent(ctx context.Context, eventID string) error {
+	key, err := attributevalue.MarshalMap(map[string]string{"id": eventID})
+	if err != nil {
+		d.logger.Error("unable to marshal outbox event key", loggers.Fields{"error": err})
+
+		return errAcknowledgingEvent
+	}
+
+	_, err = d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(outboxTable),
+		Key:       key,
+	})
+	if err != nil {
+		d.logger.Error("unable to delete outbox event", loggers.Fields{"error": err})
+
+		return errAcknowledgingEvent
+	}
+
+	d.logger.Debug("outbox event acknowledged", loggers.Fields{"id": eventID})
+
+	return nil
+}
+
+// RetryEvent stores the attempts of an event that could not be published,
+// it does nothing if the event was already acknowledged.
+func (d *DynamoDB) RetryEvent(ctx context.Context, event repository.OutboxEvent) error {
+	key, err := attributevalue.MarshalMap(map[string]string{"id": event.ID()})
+	if err != nil {
+		d.logger.Error("unable to marshal outbox event key", loggers.Fields{"error": err})
+
+		return errRetryingEvent
+	}
+
+	values, err := attributevalue.MarshalMap(map[string]interface{}{
+		":attempts":        event.Attempts,
+		":next_attempt_at": event.NextAttemptAt.UnixMilli(),
+		":last_error":      event.LastError,
+	})
+	if err != nil {
+		d.logger.Error("unable to marshal outbox event attempts", loggers.Fields{"error": err})
+
+		return errRetryingEvent
+	}
+
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(outboxTable),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET attempts = :attempts, next_attempt_at = :next_attempt_at, last_error = :last_error"),
+		ConditionExpression:       aws.String("attribute_exists(id)"),
+		ExpressionAttributeValues: values,
+	})
+	if isConditionalCheckFailed(err) {
+		return nil
+	}
+
+	if err != nil {
+		d.logger.Error("unable to update outbox event", loggers.Fields{"error": err})
+
+		return errRetryingEvent
+	}
+
+	return nil
+}
+
+// newOutboxPut returns the transaction write that records the event.
+func newOutboxPut(event repository.OutboxEvent) (types.TransactWriteItem, error) {
+	record, err := toOutboxRecord(event)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+
+	data, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(outboxTable),
+			Item:      data,
+		},
+	}, nil
+}
+
+func toOutboxRecord(event repository.OutboxEvent) (outboxRecord, error) {
+	data, err := json.Marshal(event.Event)
+	if err != nil {
+		return outboxRecord{}, err
+	}
+
+	return outboxRecord{
+		ID:            event.ID(),
+		Event:         string(data),
+		RecordedAt:    event.Event.OccurredAt.UnixMilli(),
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt.UnixMilli(),
+		LastError:     event.LastError,
+	}, nil
+}
+
+func (o outboxRecord) toRepositoryEvent() (repository.OutboxEvent, error) {
+	event := o.legacyEvent()
+
+	if o.Event != "" {
+		event = repository.Event{}
+
+		err := json.Unmarshal([]byte(o.Event), &event)
+		if err != nil {
+			return repository.OutboxEvent{}, err
+		}
+	}
+
+	if event.ID == "" {
+		return repository.OutboxEvent{}, errInvalidOutboxRecord
+	}
+
+	return repository.OutboxEvent{
+		Event:         event,
+		Attempts:      o.Attempts,
+		NextAttemptAt: time.UnixMilli(o.NextAttemptAt).UTC(),
+		LastError:     o.LastError,
+	}, nil
+}
+
+// legacyEvent returns the new fruit event of a record without encoded event.
+func (o outboxRecord) legacyEvent() repository.Event {
+	return repository.Event{
+		ID:         o.ID,
+		Type:       repository.EventFruitCreated,
+		OccurredAt: time.UnixMilli(o.RecordedAt).UTC(),
+		FruitCreated: &repository.NewFruitEvent{
+			SourceID: o.SourceID,
+			Name:     o.Name,
+			Variety:  o.Variety,
+			Price:    o.Price,
+		},
+	}
+}
@@ -0,0 +1,240 @@
+package document_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/document"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveRecordsEventInOutbox(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+	expectedFruit := repository.NewFruitEvent{
+		Version: 1,
+		Name:    "Mango",
+		Variety: "Tommy Atkins",
+		Price:   2.5,
+	}
+
+	fruitID, err := repo.Save(ctx, repository.NewFruit{Name: "Mango", Variety: "Tommy Atkins", Price: repository.FruitPrice(2.5)})
+	assert.NoError(t, err)
+
+	expectedFruit.SourceID = repository.FruitIDValue(fruitID)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, []int{2}, fakeDB.transactions)
+	assert.Equal(t, repository.EventFruitCreated, pending[0].Event.Type)
+	assert.NotEmpty(t, pending[0].Event.ID)
+	assert.WithinDuration(t, time.Now(), pending[0].Event.OccurredAt, time.Minute)
+	assert.Equal(t, &expectedFruit, pending[0].Event.FruitCreated)
+	assert.Zero(t, pending[0].Attempts)
+}
+
+func TestRetryAndAcknowledgeEvent(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	_, err := repo.Save(ctx, repository.NewFruit{Name: "Mango"})
+	assert.NoError(t, err)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	retriedEvent := pending[0]
+	retriedEvent.Attempts = 1
+	retriedEvent.NextAttemptAt = time.UnixMilli(time.Now().Add(time.Minute).UnixMilli()).UTC()
+	retriedEvent.LastError = "topic not found"
+
+	err = repo.RetryEvent(ctx, retriedEvent)
+	assert.NoError(t, err)
+
+	pending, err = repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	pending, err = repo.PendingEvents(ctx, time.Now().Add(2*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.OutboxEvent{retriedEvent}, pending)
+
+	err = repo.AcknowledgeEvent(ctx, retriedEvent.ID())
+	assert.NoError(t, err)
+	assert.Empty(t, fakeDB.events)
+
+	err = repo.RetryEvent(ctx, retriedEvent)
+	assert.NoError(t, err)
+	assert.Empty(t, fakeDB.events)
+}
+
+func TestUpdateAndDeleteRecordEvents(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	mangoID, err := repo.Save(ctx, repository.NewFruit{Name: "Mango", Variety: "Tommy Atkins", Price: repository.FruitPrice(2.5)})
+	assert.NoError(t, err)
+
+	mango, err := repo.FindByID(ctx, mangoID)
+	assert.NoError(t, err)
+
+	mango.Variety = "Kent"
+	mango.Price = nil
+	mango.LastModified = time.UnixMilli(time.Now().UnixMilli()).UTC()
+
+	err = repo.Update(ctx, *mango)
+	assert.NoError(t, err)
+
+	err = repo.Delete(ctx, mangoID, 1)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	err = repo.Delete(ctx, mangoID, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, fakeDB.items)
+	assert.Equal(t, []int{2, 2, 2}, fakeDB.transactions)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	events := make(map[repository.EventType]repository.Event, len(pending))
+	for _, outboxEvent := range pending {
+		events[outboxEvent.Event.Type] = outboxEvent.Event
+	}
+
+	updated := events[repository.EventFruitUpdated]
+	assert.Equal(t, mango.LastModified, updated.OccurredAt.UTC())
+	assert.Equal(t, &repository.FruitUpdatedEvent{
+		SourceID:      string(mangoID),
+		Version:       2,
+		ChangedFields: []string{"price", "variety"},
+		Before:        map[string]json.RawMessage{"price": json.RawMessage(`2.5`), "variety": json.RawMessage(`"Tommy Atkins"`)},
+		After:         map[string]json.RawMessage{"price": json.RawMessage(`null`), "variety": json.RawMessage(`"Kent"`)},
+	}, updated.FruitUpdated)
+	assert.Equal(t, &repository.FruitDeletedEvent{SourceID: string(mangoID), Version: 3}, events[repository.EventFruitDeleted].FruitDeleted)
+}
+
+func TestUpdateConflictRecordsNoEvent(t *testing.T) {
+	t.Parallel()
+
+	fakeDB := newFakeDynamoDB(10)
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	mangoID, err := repo.Save(ctx, repository.NewFruit{Name: "Mango"})
+	assert.NoError(t, err)
+
+	mango, err := repo.FindByID(ctx, mangoID)
+	assert.NoError(t, err)
+
+	err = repo.AcknowledgeEvent(ctx, firstPendingID(t, repo))
+	assert.NoError(t, err)
+
+	staleMango := *mango
+	mango.Variety = "Kent"
+
+	err = repo.Update(ctx, *mango)
+	assert.NoError(t, err)
+
+	err = repo.AcknowledgeEvent(ctx, firstPendingID(t, repo))
+	assert.NoError(t, err)
+
+	staleMango.Variety = "Tommy Atkins"
+
+	err = repo.Update(ctx, staleMango)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	err = repo.Update(ctx, repository.Fruit{ID: "missing", Version: 1})
+	assert.ErrorIs(t, err, repository.ErrFruitNotFound)
+
+	assert.Empty(t, fakeDB.events)
+}
+
+func firstPendingID(t *testing.T, repo *document.DynamoDB) string {
+	t.Helper()
+
+	pending, err := repo.PendingEvents(context.TODO(), time.Now().Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	return pending[0].ID()
+}
+
+func TestPendingEventsReadsLegacyAndSkipsCorruptRecords(t *testing.T) {
+	t.Parallel()
+
+	recordedAt := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	fakeDB := newFakeDynamoDB(10)
+	fakeDB.events["legacy"] = map[string]interface{}{
+		"id":              map[string]string{"S": "legacy"},
+		"source_id":       map[string]string{"S": "1"},
+		"name":            map[string]string{"S": "Mango"},
+		"variety":         map[string]string{"S": "Tommy Atkins"},
+		"price":           map[string]string{"N": "2.5"},
+		"recorded_at":     map[string]string{"N": strconv.FormatInt(recordedAt.UnixMilli(), 10)},
+		"attempts":        map[string]string{"N": "0"},
+		"next_attempt_at": map[string]string{"N": strconv.FormatInt(recordedAt.UnixMilli(), 10)},
+	}
+	fakeDB.events["corrupt"] = map[string]interface{}{
+		"id":              map[string]string{"S": "corrupt"},
+		"event":           map[string]string{"S": "{not json"},
+		"attempts":        map[string]string{"N": "0"},
+		"next_attempt_at": map[string]string{"N": strconv.FormatInt(recordedAt.UnixMilli(), 10)},
+	}
+
+	server := httptest.NewServer(fakeDB)
+	defer server.Close()
+
+	repo := newDynamoDB(t, server.URL)
+	ctx := context.TODO()
+
+	_, err := repo.Save(ctx, repository.NewFruit{Name: "Pear"})
+	assert.NoError(t, err)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, repository.OutboxEvent{
+		Event: repository.Event{
+			ID:         "legacy",
+			Type:       repository.EventFruitCreated,
+			OccurredAt: recordedAt,
+			FruitCreated: &repository.NewFruitEvent{
+				SourceID: "1",
+				Name:     "Mango",
+				Variety:  "Tommy Atkins",
+				Price:    2.5,
+			},
+		},
+		NextAttemptAt: recordedAt,
+	}, pending[0])
+	assert.Equal(t, "Pear", pending[1].Event.FruitCreated.Name)
+}
@@ -0,0 +1,334 @@
+package memorydb
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// Setup contains in-memory database settings.
+type Setup struct {
+	Logger *loggers.Logger
+}
+
+// MemoryDB defines logic for an in-memory fruit repository. It is safe
+// for concurrent use.
+type MemoryDB struct {
+	mu     sync.RWMutex
+	fruits map[repository.FruitID]repository.Fruit
+	// order keeps the insertion order so paging is stable.
+	order []repository.FruitID
+	// sequences keeps the insertion number of each fruit, it is the
+	// position search cursors continue from.
+	sequences    map[repository.FruitID]uint64
+	lastSequence uint64
+	// events is the outbox of the events recorded with the fruits.
+	events map[string]repository.OutboxEvent
+	// eventOrder keeps the events in recording order.
+	eventOrder []string
+	logger     *loggers.Logger
+}
+
+// New creates an empty in-memory fruit repository.
+func New(setup Setup) *MemoryDB {
+	newMemoryDB := MemoryDB{
+		fruits:    make(map[repository.FruitID]repository.Fruit),
+		order:     make([]repository.FruitID, 0),
+		sequences: make(map[repository.FruitID]uint64),
+		events:    make(map[string]repository.OutboxEvent),
+		logger:    setup.Logger,
+	}
+
+	return &newMemoryDB
+}
+
+// FindByID looks for the fruit with the given id, it returns nil if it does not exist.
+func (m *MemoryDB) FindByID(_ context.Context, fruitID repository.FruitID) (*repository.Fruit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fruit, ok := m.fruits[fruitID]
+	if !ok {
+		return nil, nil
+	}
+
+	fruitFound := copyFruit(fruit)
+
+	return &fruitFound, nil
+}
+
+// Save stores a new fruit and the event that announces it, then returns its
+// id. It returns repository.ErrFruitExists if a fruit with its id is stored.
+func (m *MemoryDB) Save(_ context.Context, fruit repository.NewFruit) (repository.FruitID, error) {
+	newid := repository.NewFruitID(fruit)
+	newFruit := copyFruit(fruit.ToFruit(newid))
+	newFruit.LastModified = time.Now().UTC()
+	newEvent := repository.NewOutboxEvent(repository.NewFruitCreated(newFruit, newFruit.LastModified))
+
+	m.mu.Lock()
+	if _, ok := m.fruits[newid]; ok {
+		m.mu.Unlock()
+
+		return "", repository.ErrFruitExists
+	}
+
+	m.fruits[newid] = newFruit
+	m.order = append(m.order, newid)
+	m.lastSequence++
+	m.sequences[newid] = m.lastSequence
+	m.recordEvent(newEvent)
+	m.mu.Unlock()
+
+	m.logger.Debug(
+		"new fruit stored",
+		loggers.Fields{
+			"id":     newid,
+			"output": newFruit,
+		},
+	)
+
+	return newid, nil
+}
+
+// SaveAll stores every new fruit, the results are in the same order as the fruits.
+func (m *MemoryDB) SaveAll(ctx context.Context, fruits []repository.NewFruit) []repository.SaveResult {
+	results := make([]repository.SaveResult, 0, len(fruits))
+
+	for _, fruit := range fruits {
+		newid, err := m.Save(ctx, fruit)
+		results = append(results, repository.SaveResult{ID: newid, Err: err})
+	}
+
+	return results
+}
+
+// Update replaces the fruit with the same id if its version is fruit.Version,
+// the stored fruit gets the next version and the event that announces the
+// change is recorded. It returns repository.ErrFruitNotFound
+// if the fruit doesn't exist and repository.ErrVersionConflict if the version differs.
+func (m *MemoryDB) Update(_ context.Context, fruit repository.Fruit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.checkVersion(fruit.ID, fruit.Version)
+	if err != nil {
+		return err
+	}
+
+	fruitToStore := copyFruit(fruit)
+	fruitToStore.Version++
+
+	event, err := repository.NewFruitUpdated(m.fruits[fruit.ID], fruitToStore, fruit.LastModified)
+	if err != nil {
+		return err
+	}
+
+	m.fruits[fruit.ID] = fruitToStore
+	m.recordEvent(repository.NewOutboxEvent(event))
+
+	return nil
+}
+
+// Delete deletes the fruit with the given id if its version is the given one
+// and records the event that announces it. It returns repository.ErrFruitNotFound if the fruit doesn't exist and
+// repository.ErrVersionConflict if the version differs.
+func (m *MemoryDB) Delete(_ context.Context, fruitID repository.FruitID, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.checkVersion(fruitID, version)
+	if err != nil {
+		return err
+	}
+
+	m.recordEvent(repository.NewOutboxEvent(repository.NewFruitDeleted(m.fruits[fruitID], time.Now().UTC())))
+	delete(m.fruits, fruitID)
+	delete(m.sequences, fruitID)
+
+	for index, id := range m.order {
+		if id == fruitID {
+			m.order = append(m.order[:index], m.order[index+1:]...)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// recordEvent adds the event to the outbox, the caller must hold the lock.
+func (m *MemoryDB) recordEvent(event repository.OutboxEvent) {
+	m.events[event.ID()] = event
+	m.eventOrder = append(m.eventOrder, event.ID())
+}
+
+// checkVersion checks that the fruit exists with the given version, the caller must hold the lock.
+func (m *MemoryDB) checkVersion(fruitID repository.FruitID, version int64) error {
+	storedFruit, ok := m.fruits[fruitID]
+	if !ok {
+		return repository.ErrFruitNotFound
+	}
+
+	if storedFruit.Version != version {
+		return repository.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// SearchWithFilters returns the fruits that match the filter within the window
+// it describes, start is 1-based. Fruits are sorted by the filter sort fields
+// or kept in insertion order if there are none. Total is the number of fruits
+// that match the filter.
+func (m *MemoryDB) SearchWithFilters(_ context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if filter.IDs != nil {
+		return m.searchIDs(filter)
+	}
+
+	if len(filter.Sort) > 0 {
+		return m.searchSorted(filter), nil
+	}
+
+	var after uint64
+
+	if filter.After != nil {
+		sequence, err := strconv.ParseUint(filter.After.Key, 10, 64)
+		if err != nil {
+			return repository.FindFruitsResult{}, repository.ErrInvalidCursor
+		}
+
+		after = sequence
+	}
+
+	result := repository.FindFruitsResult{
+		Start: filter.Start,
+		Count: filter.Count,
+	}
+
+	matches := make([]repository.Fruit, 0)
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	for _, fruitID := range m.order {
+		fruit := m.fruits[fruitID]
+		if !filter.Match(fruit) {
+			continue
+		}
+
+		result.Total++
+		facets.Add(fruit)
+
+		if m.sequences[fruitID] > after {
+			matches = append(matches, copyFruit(fruit))
+		}
+	}
+
+	page, more := filter.Page(matches)
+	result.Fruits = page
+	result.Facets = facets.Facets()
+
+	if more {
+		lastSequence := m.sequences[page[len(page)-1].ID]
+		result.Next = &repository.Cursor{Key: strconv.FormatUint(lastSequence, 10)}
+	}
+
+	return result, nil
+}
+
+// ListFruits returns up to count fruits after the cursor in insertion order.
+func (m *MemoryDB) ListFruits(ctx context.Context, after *repository.Cursor, count int) (repository.FruitPage, error) {
+	result, err := m.SearchWithFilters(ctx, repository.FruitFilter{Start: 1, Count: count, After: after})
+	if err != nil {
+		return repository.FruitPage{}, err
+	}
+
+	return repository.FruitPage{Fruits: result.Fruits, Next: result.Next}, nil
+}
+
+// searchIDs searches among the fruits with the filter ids, the caller must hold the lock.
+func (m *MemoryDB) searchIDs(filter repository.FruitFilter) (repository.FindFruitsResult, error) {
+	if filter.After != nil {
+		return repository.FindFruitsResult{}, repository.ErrInvalidCursor
+	}
+
+	matches := make([]repository.Fruit, 0, len(filter.IDs))
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	for _, fruitID := range filter.IDs {
+		fruit, ok := m.fruits[fruitID]
+		if ok && filter.Match(fruit) {
+			matches = append(matches, copyFruit(fruit))
+			facets.Add(fruit)
+		}
+	}
+
+	if len(filter.Sort) > 0 {
+		repository.SortFruits(matches, filter.Sort)
+	}
+
+	page, _ := filter.Page(matches)
+
+	return repository.FindFruitsResult{
+		Fruits: page,
+		Total:  len(matches),
+		Start:  filter.Start,
+		Count:  filter.Count,
+		Facets: facets.Facets(),
+	}, nil
+}
+
+// searchSorted searches the fruits that match the filter sorted by the filter
+// sort fields, the caller must hold the lock.
+func (m *MemoryDB) searchSorted(filter repository.FruitFilter) repository.FindFruitsResult {
+	matches := make([]repository.Fruit, 0)
+	facets := repository.NewFacetCounter(filter.Facets)
+
+	for _, fruitID := range m.order {
+		fruit := m.fruits[fruitID]
+		if filter.Match(fruit) {
+			matches = append(matches, copyFruit(fruit))
+			facets.Add(fruit)
+		}
+	}
+
+	repository.SortFruits(matches, filter.Sort)
+
+	page, next := filter.SortedPage(matches)
+
+	return repository.FindFruitsResult{
+		Fruits: page,
+		Total:  len(matches),
+		Start:  filter.Start,
+		Count:  filter.Count,
+		Next:   next,
+		Facets: facets.Facets(),
+	}
+}
+
+// DatasetStatus the in-memory database is always available.
+func (m *MemoryDB) DatasetStatus(_ context.Context) (repository.FruitDatasetStatus, error) {
+	return repository.FruitDatasetStatus{Ok: true}, nil
+}
+
+// Count returns the number of fruits stored.
+func (m *MemoryDB) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.fruits)
+}
+
+// copyFruit returns a copy of the given fruit that doesn't share memory with it.
+func copyFruit(fruit repository.Fruit) repository.Fruit {
+	if fruit.Price != nil {
+		fruit.Price = repository.FruitPrice(*fruit.Price)
+	}
+
+	return fruit
+}
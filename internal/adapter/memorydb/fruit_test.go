@@ -0,0 +1,576 @@
+package memorydb_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/memorydb"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndFindByID(t *testing.T) {
+	t.Parallel()
+
+	newFruit := repository.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Year:           2013,
+		Price:          repository.FruitPrice(12.5),
+		Country:        "Italy",
+		Province:       "Sicily & Sardinia",
+		Region:         "Etna",
+		Description:    "brisk acidity",
+		Classification: "Vulka Bianco",
+		LocalName:      "Kerin OaKeefe",
+		WikiPage:       "@kerinokeefe",
+	}
+	db := newMemoryDB()
+	ctx := context.TODO()
+	savedAfter := time.Now()
+
+	fruitID, err := db.Save(ctx, newFruit)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fruitID)
+
+	got, err := db.FindByID(ctx, fruitID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedFruit := newFruit.ToFruit(fruitID)
+	expectedFruit.LastModified = got.LastModified
+
+	assert.Equal(t, expectedFruit, *got)
+	assert.False(t, got.LastModified.Before(savedAfter))
+	assert.Equal(t, 1, db.Count())
+}
+
+func TestSaveWithTakenID(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	fruitID, err := db.Save(ctx, repository.NewFruit{ID: "1234", Name: "Mango"})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.FruitID("1234"), fruitID)
+
+	fruitID, err = db.Save(ctx, repository.NewFruit{ID: "1234", Name: "Pear"})
+
+	assert.ErrorIs(t, err, repository.ErrFruitExists)
+	assert.Empty(t, fruitID)
+	assert.Equal(t, 1, db.Count())
+}
+
+func TestSaveAll(t *testing.T) {
+	t.Parallel()
+
+	newFruits := []repository.NewFruit{
+		{Name: "Nicosia 2013 Vulka Bianco  (Etna)"},
+		{Name: "Quinta dos Avidagos 2011 Avidagos Red (Douro)"},
+	}
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	results := db.SaveAll(ctx, newFruits)
+
+	assert.Len(t, results, 2)
+
+	for index, result := range results {
+		assert.NoError(t, result.Err)
+
+		got, err := db.FindByID(ctx, result.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, newFruits[index].Name, got.Name)
+	}
+}
+
+func TestFindByIDNotFound(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+
+	got, err := db.FindByID(context.TODO(), repository.FruitID("1234"))
+
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestFoundFruitDoesNotShareMemory(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	fruitID, err := db.Save(ctx, repository.NewFruit{Name: "Avidagos", Price: repository.FruitPrice(15)})
+	assert.NoError(t, err)
+
+	got, err := db.FindByID(ctx, fruitID)
+	assert.NoError(t, err)
+
+	*got.Price = 1
+
+	again, err := db.FindByID(ctx, fruitID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, float32(15), repository.FruitPriceValue(again.Price))
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	fruitID, err := db.Save(ctx, repository.NewFruit{Name: "Avidagos"})
+	assert.NoError(t, err)
+
+	err = db.Update(ctx, repository.Fruit{ID: fruitID, Name: "Avidagos Red", Version: repository.FirstVersion})
+	assert.NoError(t, err)
+
+	got, err := db.FindByID(ctx, fruitID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Avidagos Red", got.Name)
+	assert.Equal(t, repository.FirstVersion+1, got.Version)
+
+	err = db.Delete(ctx, fruitID, got.Version)
+	assert.NoError(t, err)
+
+	got, err = db.FindByID(ctx, fruitID)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, 0, db.Count())
+
+	search, err := db.SearchWithFilters(ctx, repository.FruitFilter{Start: 1, Count: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, search.Total)
+}
+
+func TestUpdateAndDeleteNotFound(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	err := db.Update(ctx, repository.Fruit{ID: "1234", Name: "Avidagos Red"})
+	assert.Equal(t, repository.ErrFruitNotFound, err)
+
+	err = db.Delete(ctx, "1234", repository.FirstVersion)
+	assert.Equal(t, repository.ErrFruitNotFound, err)
+}
+
+func TestUpdateAndDeleteWithStaleVersion(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	fruitID, err := db.Save(ctx, repository.NewFruit{Name: "Avidagos"})
+	assert.NoError(t, err)
+
+	err = db.Update(ctx, repository.Fruit{ID: fruitID, Name: "Avidagos Red", Version: repository.FirstVersion})
+	assert.NoError(t, err)
+
+	err = db.Update(ctx, repository.Fruit{ID: fruitID, Name: "Avidagos White", Version: repository.FirstVersion})
+	assert.Equal(t, repository.ErrVersionConflict, err)
+
+	err = db.Delete(ctx, fruitID, repository.FirstVersion)
+	assert.Equal(t, repository.ErrVersionConflict, err)
+
+	got, err := db.FindByID(ctx, fruitID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Avidagos Red", got.Name)
+}
+
+func TestSearchWithFilters(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		filter    repository.FruitFilter
+		wantNames []string
+	}{
+		"first_page": {
+			filter:    repository.FruitFilter{Start: 1, Count: 2},
+			wantNames: []string{"fruit 1", "fruit 2"},
+		},
+		"middle_page": {
+			filter:    repository.FruitFilter{Start: 3, Count: 2},
+			wantNames: []string{"fruit 3", "fruit 4"},
+		},
+		"last_page": {
+			filter:    repository.FruitFilter{Start: 5, Count: 10},
+			wantNames: []string{"fruit 5"},
+		},
+		"out_of_range": {
+			filter:    repository.FruitFilter{Start: 10, Count: 10},
+			wantNames: []string{},
+		},
+	}
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	for i := 1; i <= 5; i++ {
+		_, err := db.Save(ctx, repository.NewFruit{Name: "fruit " + strconv.Itoa(i)})
+		assert.NoError(t, err)
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := db.SearchWithFilters(ctx, test.filter)
+
+			assert.NoError(st, err)
+			assert.Equal(st, 5, got.Total)
+			assert.Equal(st, test.filter.Start, got.Start)
+			assert.Equal(st, test.filter.Count, got.Count)
+			assert.Equal(st, test.wantNames, fruitNames(got.Fruits))
+		})
+	}
+}
+
+func TestSearchWithMatchingFilters(t *testing.T) {
+	t.Parallel()
+
+	minYear := 2012
+	maxPrice := float32(20)
+	cases := map[string]struct {
+		filter    repository.FruitFilter
+		wantNames []string
+	}{
+		"country_and_variety": {
+			filter:    repository.FruitFilter{Start: 1, Count: 10, Country: "Italy", Variety: "White Blend"},
+			wantNames: []string{"Nicosia", "Stemmari", "Tasca"},
+		},
+		"under_price": {
+			filter:    repository.FruitFilter{Start: 1, Count: 10, Country: "Italy", Variety: "White Blend", MaxPrice: &maxPrice},
+			wantNames: []string{"Stemmari"},
+		},
+		"from_year": {
+			filter:    repository.FruitFilter{Start: 1, Count: 10, MinYear: &minYear},
+			wantNames: []string{"Nicosia", "Tasca"},
+		},
+		"second_page": {
+			filter:    repository.FruitFilter{Start: 2, Count: 1, Country: "Italy"},
+			wantNames: []string{"Stemmari"},
+		},
+	}
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	for _, newFruit := range []repository.NewFruit{
+		{Name: "Nicosia", Country: "Italy", Variety: "White Blend", Year: 2013},
+		{Name: "Avidagos", Country: "Portugal", Variety: "Portuguese Red", Year: 2011, Price: repository.FruitPrice(15)},
+		{Name: "Stemmari", Country: "Italy", Variety: "White Blend", Year: 2011, Price: repository.FruitPrice(13)},
+		{Name: "Tasca", Country: "Italy", Variety: "White Blend", Year: 2014, Price: repository.FruitPrice(27)},
+	} {
+		_, err := db.Save(ctx, newFruit)
+		assert.NoError(t, err)
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := db.SearchWithFilters(ctx, test.filter)
+
+			assert.NoError(st, err)
+			assert.Equal(st, test.wantNames, fruitNames(got.Fruits))
+		})
+	}
+}
+
+func TestSearchSorted(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		filter    repository.FruitFilter
+		wantNames []string
+	}{
+		"price_descending_without_price_last": {
+			filter:    repository.FruitFilter{Start: 1, Count: 10, Sort: []repository.SortField{{Field: repository.SortByPrice, Descending: true}}},
+			wantNames: []string{"Tasca", "Avidagos", "Stemmari", "Nicosia"},
+		},
+		"country_then_year_descending": {
+			filter: repository.FruitFilter{Start: 1, Count: 10, Sort: []repository.SortField{
+				{Field: repository.SortByCountry},
+				{Field: repository.SortByYear, Descending: true},
+			}},
+			wantNames: []string{"Tasca", "Nicosia", "Stemmari", "Avidagos"},
+		},
+		"second_page": {
+			filter:    repository.FruitFilter{Start: 3, Count: 2, Sort: []repository.SortField{{Field: repository.SortByName}}},
+			wantNames: []string{"Stemmari", "Tasca"},
+		},
+	}
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	for _, newFruit := range []repository.NewFruit{
+		{Name: "Nicosia", Country: "Italy", Year: 2013},
+		{Name: "Avidagos", Country: "Portugal", Year: 2011, Price: repository.FruitPrice(15)},
+		{Name: "Stemmari", Country: "Italy", Year: 2011, Price: repository.FruitPrice(13)},
+		{Name: "Tasca", Country: "Italy", Year: 2014, Price: repository.FruitPrice(27)},
+	} {
+		_, err := db.Save(ctx, newFruit)
+		assert.NoError(t, err)
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got, err := db.SearchWithFilters(ctx, test.filter)
+
+			assert.NoError(st, err)
+			assert.Equal(st, 4, got.Total)
+			assert.Equal(st, test.wantNames, fruitNames(got.Fruits))
+		})
+	}
+}
+
+func TestSearchSortedPagesAreStable(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	for i := 0; i < 9; i++ {
+		_, err := db.Save(ctx, repository.NewFruit{Name: "fruit " + strconv.Itoa(i), Country: "Italy"})
+		assert.NoError(t, err)
+	}
+
+	sortByCountry := []repository.SortField{{Field: repository.SortByCountry}}
+	seen := make(map[repository.FruitID]bool)
+
+	for start := 1; start <= 9; start += 2 {
+		got, err := db.SearchWithFilters(ctx, repository.FruitFilter{Start: start, Count: 2, Sort: sortByCountry})
+		assert.NoError(t, err)
+
+		for _, fruit := range got.Fruits {
+			assert.False(t, seen[fruit.ID], "fruit %s was repeated", fruit.ID)
+			seen[fruit.ID] = true
+		}
+	}
+
+	assert.Len(t, seen, 9)
+}
+
+func TestSearchAfterCursor(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		sort      []repository.SortField
+		wantNames []string
+	}{
+		"insertion_order": {
+			wantNames: []string{"fruit 1", "fruit 3", "fruit 4", "fruit 5"},
+		},
+		"sorted_by_name_descending": {
+			sort:      []repository.SortField{{Field: repository.SortByName, Descending: true}},
+			wantNames: []string{"fruit 5", "fruit 3", "fruit 2", "fruit 1"},
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			db := newMemoryDB()
+			ctx := context.TODO()
+			fruitIDs := make(map[string]repository.FruitID)
+
+			for i := 1; i <= 5; i++ {
+				fruitName := "fruit " + strconv.Itoa(i)
+
+				fruitID, err := db.Save(ctx, repository.NewFruit{Name: fruitName})
+				assert.NoError(st, err)
+
+				fruitIDs[fruitName] = fruitID
+			}
+
+			filter := repository.FruitFilter{Start: 1, Count: 2, Sort: test.sort}
+			firstPage, err := db.SearchWithFilters(ctx, filter)
+			assert.NoError(st, err)
+			assert.NotNil(st, firstPage.Next)
+
+			// the last fruit of the first page is deleted, the cursor must still work.
+			lastFruit := firstPage.Fruits[len(firstPage.Fruits)-1]
+			assert.NoError(st, db.Delete(ctx, lastFruit.ID, lastFruit.Version))
+
+			gotNames := fruitNames(firstPage.Fruits[:1])
+			filter.After = firstPage.Next
+
+			for filter.After != nil {
+				page, err := db.SearchWithFilters(ctx, filter)
+				assert.NoError(st, err)
+				assert.Equal(st, 4, page.Total)
+
+				gotNames = append(gotNames, fruitNames(page.Fruits)...)
+				filter.After = page.Next
+			}
+
+			assert.Equal(st, test.wantNames, gotNames)
+		})
+	}
+}
+
+func TestSearchWithFacets(t *testing.T) {
+	t.Parallel()
+
+	expectedFacets := repository.Facets{
+		"country": {{Value: "Italy", Count: 3}},
+		"variety": {{Value: "White Blend", Count: 2}, {Value: "Frappato", Count: 1}},
+		"region":  {{Value: "Etna", Count: 1}},
+	}
+	cases := map[string]repository.FruitFilter{
+		"unsorted": {Start: 1, Count: 1, Country: "Italy"},
+		"sorted":   {Start: 1, Count: 1, Country: "Italy", Sort: []repository.SortField{{Field: repository.SortByName}}},
+	}
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+	fruitIDs := make([]repository.FruitID, 0, 4)
+
+	for _, newFruit := range []repository.NewFruit{
+		{Name: "Nicosia", Country: "Italy", Variety: "White Blend", Region: "Etna"},
+		{Name: "Avidagos", Country: "Portugal", Variety: "Portuguese Red"},
+		{Name: "Stemmari", Country: "Italy", Variety: "Frappato"},
+		{Name: "Tasca", Country: "Italy", Variety: "White Blend"},
+	} {
+		fruitID, err := db.Save(ctx, newFruit)
+		assert.NoError(t, err)
+
+		fruitIDs = append(fruitIDs, fruitID)
+	}
+
+	cases["by_ids"] = repository.FruitFilter{Start: 1, Count: 1, Country: "Italy", IDs: fruitIDs}
+
+	for name, filter := range cases {
+		name, filter := name, filter
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			filter.Facets = []string{repository.FacetByCountry, repository.FacetByVariety, repository.FacetByRegion}
+
+			got, err := db.SearchWithFilters(ctx, filter)
+
+			assert.NoError(st, err)
+			assert.Len(st, got.Fruits, 1)
+			assert.Equal(st, expectedFacets, got.Facets)
+		})
+	}
+
+	got, err := db.SearchWithFilters(ctx, repository.FruitFilter{Start: 1, Count: 10})
+	assert.NoError(t, err)
+	assert.Nil(t, got.Facets)
+}
+
+func TestSearchIDs(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+	fruitIDs := make([]repository.FruitID, 0, 4)
+
+	for _, newFruit := range []repository.NewFruit{
+		{Name: "Nicosia", Country: "Italy"},
+		{Name: "Avidagos", Country: "Portugal"},
+		{Name: "Stemmari", Country: "Italy"},
+		{Name: "Tasca", Country: "Italy"},
+	} {
+		fruitID, err := db.Save(ctx, newFruit)
+		assert.NoError(t, err)
+
+		fruitIDs = append(fruitIDs, fruitID)
+	}
+
+	filter := repository.FruitFilter{
+		Start:   1,
+		Count:   10,
+		Country: "Italy",
+		IDs:     []repository.FruitID{fruitIDs[3], "missing", fruitIDs[1], fruitIDs[0]},
+	}
+
+	got, err := db.SearchWithFilters(ctx, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Total)
+	assert.Equal(t, []string{"Tasca", "Nicosia"}, fruitNames(got.Fruits))
+
+	filter.Sort = []repository.SortField{{Field: repository.SortByName}}
+
+	got, err = db.SearchWithFilters(ctx, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Nicosia", "Tasca"}, fruitNames(got.Fruits))
+}
+
+func TestSearchWithInvalidCursor(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+
+	_, err := db.SearchWithFilters(context.TODO(), repository.FruitFilter{
+		Start: 1,
+		Count: 2,
+		After: &repository.Cursor{Key: "not-a-position"},
+	})
+
+	assert.Equal(t, repository.ErrInvalidCursor, err)
+}
+
+func TestConcurrentSaves(t *testing.T) {
+	t.Parallel()
+
+	db := newMemoryDB()
+	ctx := context.TODO()
+
+	var waitGroup sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		waitGroup.Add(1)
+
+		go func(i int) {
+			defer waitGroup.Done()
+
+			_, err := db.Save(ctx, repository.NewFruit{Name: "fruit " + strconv.Itoa(i)})
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	waitGroup.Wait()
+
+	got, err := db.SearchWithFilters(ctx, repository.FruitFilter{Start: 1, Count: 100})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 50, got.Total)
+	assert.Len(t, got.Fruits, 50)
+	assert.Equal(t, 50, db.Count())
+}
+
+func newMemoryDB() *memorydb.MemoryDB {
+	return memorydb.New(memorydb.Setup{
+		Logger: loggers.NewLoggerWithStdout("", loggers.Error),
+	})
+}
+
+func fruitNames(fruits []repository.Fruit) []string {
+	names := make([]string, 0, len(fruits))
+	for _, fruit := range fruits {
+		names = append(names, fruit.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,112 @@
+package memorydb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// IdempotencyKeys defines logic for an in-memory store of idempotency keys.
+// It is safe for concurrent use.
+type IdempotencyKeys struct {
+	mu      sync.Mutex
+	records map[string]repository.IdempotencyRecord
+	// order keeps the keys in reservation order, so the oldest records
+	// are the first ones checked to be forgotten.
+	order  []string
+	logger *loggers.Logger
+}
+
+// NewIdempotencyKeys creates an empty in-memory store of idempotency keys.
+func NewIdempotencyKeys(setup Setup) *IdempotencyKeys {
+	newIdempotencyKeys := IdempotencyKeys{
+		records: make(map[string]repository.IdempotencyRecord),
+		logger:  setup.Logger,
+	}
+
+	return &newIdempotencyKeys
+}
+
+// Reserve keeps the record if its key is unknown or expired, otherwise it
+// returns the record already kept for the key.
+func (i *IdempotencyKeys) Reserve(_ context.Context, record repository.IdempotencyRecord) (*repository.IdempotencyRecord, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	i.forgetExpired(now)
+
+	current, ok := i.records[record.Key]
+	if ok && !current.Expired(now) {
+		return &current, nil
+	}
+
+	i.records[record.Key] = record
+	i.order = append(i.order, record.Key)
+
+	i.logger.Debug(
+		"idempotency key reserved",
+		loggers.Fields{
+			"key":        record.Key,
+			"expires_at": record.ExpiresAt,
+		},
+	)
+
+	return nil, nil
+}
+
+// Complete sets the fruit created by the request that holds the reservation
+// and keeps the key until expiresAt. It returns repository.ErrIdempotencyKeyLost
+// if the key expired or another request reserved it.
+func (i *IdempotencyKeys) Complete(_ context.Context, reservation repository.IdempotencyRecord, fruitID repository.FruitID, expiresAt time.Time) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	record, ok := i.records[reservation.Key]
+	if !ok || !record.Holds(reservation, time.Now()) {
+		return repository.ErrIdempotencyKeyLost
+	}
+
+	record.FruitID = fruitID
+	record.ExpiresAt = expiresAt
+	i.records[reservation.Key] = record
+
+	return nil
+}
+
+// Release forgets the key if the reservation still holds it.
+func (i *IdempotencyKeys) Release(_ context.Context, reservation repository.IdempotencyRecord) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	record, ok := i.records[reservation.Key]
+	if ok && record.Holds(reservation, time.Now()) {
+		delete(i.records, reservation.Key)
+	}
+
+	return nil
+}
+
+// forgetExpired deletes the oldest records until it finds one that is not
+// expired. Keys of released records are skipped. A completed key is kept
+// longer than its reservation, so the keys reserved after it are forgotten
+// when it is, Reserve checks their expiration anyway.
+func (i *IdempotencyKeys) forgetExpired(now time.Time) {
+	var forgotten int
+
+	for _, key := range i.order {
+		record, ok := i.records[key]
+		if ok && !record.Expired(now) {
+			break
+		}
+
+		delete(i.records, key)
+
+		forgotten++
+	}
+
+	i.order = i.order[forgotten:]
+}
@@ -0,0 +1,138 @@
+package memorydb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/memorydb"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	record := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	expectedRecord := record
+	expectedRecord.FruitID = "1234"
+	expectedRecord.ExpiresAt = time.Now().Add(time.Hour)
+	keys := newIdempotencyKeys()
+	ctx := context.TODO()
+
+	reserved, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+
+	reserved, err = keys.Reserve(ctx, repository.IdempotencyRecord{Key: record.Key, Fingerprint: "def"})
+	assert.NoError(t, err)
+	assert.Equal(t, &record, reserved)
+
+	err = keys.Complete(ctx, record, "1234", expectedRecord.ExpiresAt)
+	assert.NoError(t, err)
+
+	reserved, err = keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Equal(t, &expectedRecord, reserved)
+}
+
+func TestReleasedIdempotencyKeyCanBeReservedAgain(t *testing.T) {
+	t.Parallel()
+
+	record := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	keys := newIdempotencyKeys()
+	ctx := context.TODO()
+
+	_, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+
+	err = keys.Release(ctx, record)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, record)
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+}
+
+func TestExpiredIdempotencyKeyIsForgotten(t *testing.T) {
+	t.Parallel()
+
+	expiredRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		FruitID:     "1234",
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+	newRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "def",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	keys := newIdempotencyKeys()
+	ctx := context.TODO()
+
+	_, err := keys.Reserve(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, newRecord)
+	assert.NoError(t, err)
+	assert.Nil(t, reserved)
+
+	reserved, err = keys.Reserve(ctx, newRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, &newRecord, reserved)
+}
+
+func TestCompleteLostIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	expiredRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+	retryRecord := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "abc",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	keys := newIdempotencyKeys()
+	ctx := context.TODO()
+
+	err := keys.Complete(ctx, retryRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	_, err = keys.Reserve(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	err = keys.Complete(ctx, expiredRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	_, err = keys.Reserve(ctx, retryRecord)
+	assert.NoError(t, err)
+
+	err = keys.Complete(ctx, expiredRecord, "1234", time.Now().Add(time.Hour))
+	assert.Equal(t, repository.ErrIdempotencyKeyLost, err)
+
+	err = keys.Release(ctx, expiredRecord)
+	assert.NoError(t, err)
+
+	reserved, err := keys.Reserve(ctx, retryRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, &retryRecord, reserved)
+}
+
+func newIdempotencyKeys() *memorydb.IdempotencyKeys {
+	return memorydb.NewIdempotencyKeys(memorydb.Setup{
+		Logger: loggers.NewLoggerWithStdout("", loggers.Error),
+	})
+}
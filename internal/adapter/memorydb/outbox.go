@@ -0,0 +1,79 @@
+package memorydb
+
+import (
+	"context"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// PendingEvents returns up to limit events of the outbox that are due at the
+// given moment, the oldest first.
+func (m *MemoryDB) PendingEvents(_ context.Context, now time.Time, limit int) ([]repository.OutboxEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pending := make([]repository.OutboxEvent, 0)
+
+	for _, eventID := range m.eventOrder {
+		if len(pending) == limit {
+			break
+		}
+
+		event := m.events[eventID]
+		if event.Due(now) {
+			pending = append(pending, event)
+		}
+	}
+
+	return pending, nil
+}
+
+// AcknowledgeEvent removes a published event from the outbox.
+func (m *MemoryDB) AcknowledgeEvent(_ context.Context, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.events[eventID]; !ok {
+		return nil
+	}
+
+	delete(m.events, eventID)
+
+	for i, id := range m.eventOrder {
+		if id == eventID {
+			m.eventOrder = append(m.eventOrder[:i], m.eventOrder[i+1:]...)
+
+			break
+		}
+	}
+
+	m.logger.Debug(
+		"outbox event acknowledged",
+		loggers.Fields{
+			"id": eventID,
+		},
+	)
+
+	return nil
+}
+
+// RetryEvent stores the attempts of an event that could not be published,
+// it does nothing if the event was already acknowledged.
+func (m *MemoryDB) RetryEvent(_ context.Context, event repository.OutboxEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	storedEvent, ok := m.events[event.ID()]
+	if !ok {
+		return nil
+	}
+
+	storedEvent.Attempts = event.Attempts
+	storedEvent.NextAttemptAt = event.NextAttemptAt
+	storedEvent.LastError = event.LastError
+	m.events[event.ID()] = storedEvent
+
+	return nil
+}
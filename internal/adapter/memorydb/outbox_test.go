@@ -0,0 +1,123 @@
+package memorydb_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveRecordsEventsInOrder(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryDB()
+	ctx := context.TODO()
+
+	mangoID, err := repo.Save(ctx, repository.NewFruit{Name: "Mango", Variety: "Tommy Atkins", Price: repository.FruitPrice(2.5)})
+	assert.NoError(t, err)
+
+	results := repo.SaveAll(ctx, []repository.NewFruit{{Name: "Pear"}, {Name: "Apple"}})
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.Equal(t, repository.EventFruitCreated, pending[0].Event.Type)
+	assert.NotEmpty(t, pending[0].Event.ID)
+	assert.WithinDuration(t, time.Now(), pending[0].Event.OccurredAt, time.Minute)
+	assert.Equal(t, &repository.NewFruitEvent{SourceID: string(mangoID), Version: 1, Name: "Mango", Variety: "Tommy Atkins", Price: 2.5}, pending[0].Event.FruitCreated)
+	assert.Equal(t, string(results[0].ID), pending[1].Event.FruitID())
+	assert.Equal(t, string(results[1].ID), pending[2].Event.FruitID())
+
+	pending, err = repo.PendingEvents(ctx, time.Now(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, "Mango", pending[0].Event.FruitCreated.Name)
+}
+
+func TestRetryAndAcknowledgeEvent(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryDB()
+	ctx := context.TODO()
+
+	_, err := repo.Save(ctx, repository.NewFruit{Name: "Mango"})
+	assert.NoError(t, err)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	retriedEvent := pending[0]
+	retriedEvent.Attempts = 1
+	retriedEvent.NextAttemptAt = time.Now().Add(time.Minute)
+	retriedEvent.LastError = "topic not found"
+
+	err = repo.RetryEvent(ctx, retriedEvent)
+	assert.NoError(t, err)
+
+	pending, err = repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	pending, err = repo.PendingEvents(ctx, time.Now().Add(2*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.OutboxEvent{retriedEvent}, pending)
+
+	err = repo.AcknowledgeEvent(ctx, retriedEvent.ID())
+	assert.NoError(t, err)
+
+	err = repo.RetryEvent(ctx, retriedEvent)
+	assert.NoError(t, err)
+
+	pending, err = repo.PendingEvents(ctx, time.Now().Add(2*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestUpdateAndDeleteRecordEvents(t *testing.T) {
+	t.Parallel()
+
+	repo := newMemoryDB()
+	ctx := context.TODO()
+
+	mangoID, err := repo.Save(ctx, repository.NewFruit{Name: "Mango", Variety: "Tommy Atkins", Price: repository.FruitPrice(2.5)})
+	assert.NoError(t, err)
+
+	mango, err := repo.FindByID(ctx, mangoID)
+	assert.NoError(t, err)
+
+	mango.Variety = "Kent"
+	mango.Price = nil
+	mango.LastModified = time.Now().UTC()
+
+	err = repo.Update(ctx, *mango)
+	assert.NoError(t, err)
+
+	err = repo.Delete(ctx, mangoID, 1)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	err = repo.Delete(ctx, mangoID, 2)
+	assert.NoError(t, err)
+
+	pending, err := repo.PendingEvents(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	updated := pending[1].Event
+	assert.Equal(t, repository.EventFruitUpdated, updated.Type)
+	assert.Equal(t, mango.LastModified, updated.OccurredAt)
+	assert.Equal(t, &repository.FruitUpdatedEvent{
+		SourceID:      string(mangoID),
+		Version:       2,
+		ChangedFields: []string{"price", "variety"},
+		Before:        map[string]json.RawMessage{"price": json.RawMessage(`2.5`), "variety": json.RawMessage(`"Tommy Atkins"`)},
+		After:         map[string]json.RawMessage{"price": json.RawMessage(`null`), "variety": json.RawMessage(`"Kent"`)},
+	}, updated.FruitUpdated)
+
+	deleted := pending[2].Event
+	assert.Equal(t, repository.EventFruitDeleted, deleted.Type)
+	assert.Equal(t, &repository.FruitDeletedEvent{SourceID: string(mangoID), Version: 3}, deleted.FruitDeleted)
+}
@@ -0,0 +1,79 @@
+package publishing
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops the attempts to publish after threshold consecutive
+// failures. Once openDuration passes it lets one attempt through, the circuit
+// closes again if it succeeds and stays open another openDuration if not.
+type circuitBreaker struct {
+	mu           sync.Mutex
+	threshold    int
+	openDuration time.Duration
+	failures     int
+	// openUntil is the moment the circuit lets an attempt through, zero while it is closed.
+	openUntil time.Time
+	// probing is set while the attempt let through an open circuit runs.
+	probing bool
+}
+
+func newCircuitBreaker(threshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:    threshold,
+		openDuration: openDuration,
+	}
+}
+
+// attempt is an attempt let through the circuit.
+type attempt struct {
+	// probe is set if the attempt was let through an open circuit.
+	probe bool
+}
+
+// allow checks if an attempt can be made at the given moment, the returned
+// attempt must be recorded once it is made.
+func (c *circuitBreaker) allow(now time.Time) (attempt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.openUntil.IsZero() {
+		return attempt{}, true
+	}
+
+	if c.probing || now.Before(c.openUntil) {
+		return attempt{}, false
+	}
+
+	c.probing = true
+
+	return attempt{probe: true}, true
+}
+
+// record keeps the outcome of an attempt made at the given moment. While
+// the circuit is open only the probe decides whether it closes or stays
+// open, attempts that were let through before it opened are ignored.
+func (c *circuitBreaker) record(now time.Time, made attempt, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if made.probe {
+		c.probing = false
+	} else if !c.openUntil.IsZero() {
+		return
+	}
+
+	if err == nil {
+		c.failures = 0
+		c.openUntil = time.Time{}
+
+		return
+	}
+
+	c.failures++
+
+	if made.probe || (c.threshold > 0 && c.failures >= c.threshold) {
+		c.openUntil = now.Add(c.openDuration)
+	}
+}
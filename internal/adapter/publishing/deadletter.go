@@ -0,0 +1,201 @@
+package publishing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+var (
+	errAddingDeadLetter   = errors.New("unable to add dead-lettered event")
+	errReadingDeadLetters = errors.New("unable to read dead-lettered events")
+	errRemovingDeadLetter = errors.New("unable to remove dead-lettered event")
+)
+
+// FileDeadLetters keeps the dead-lettered events in a local file, one json
+// event per line. It is safe for concurrent use within a process, replicas
+// must not share the file.
+type FileDeadLetters struct {
+	mu     sync.Mutex
+	path   string
+	logger *loggers.Logger
+}
+
+// NewFileDeadLetters creates a dead-letter sink that keeps its events in the
+// file with the given path, the file is created with the first event.
+func NewFileDeadLetters(path string, logger *loggers.Logger) *FileDeadLetters {
+	return &FileDeadLetters{
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Add appends the event to the file.
+func (f *FileDeadLetters) Add(_ context.Context, deadLetter repository.DeadLetter) error {
+	line, err := json.Marshal(deadLetter)
+	if err != nil {
+		f.logger.Error("unable to marshal dead-lettered event", loggers.Fields{"error": err})
+
+		return errAddingDeadLetter
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		f.logger.Error("unable to open dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return errAddingDeadLetter
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		file.Close()
+
+		f.logger.Error("unable to write dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return errAddingDeadLetter
+	}
+
+	err = file.Close()
+	if err != nil {
+		f.logger.Error("unable to close dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return errAddingDeadLetter
+	}
+
+	return nil
+}
+
+// List returns the events in the order they were dead-lettered.
+func (f *FileDeadLetters) List(_ context.Context) ([]repository.DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deadLetters, err := f.read()
+	if err != nil {
+		f.logger.Error("unable to read dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return nil, errReadingDeadLetters
+	}
+
+	return deadLetters, nil
+}
+
+// Remove removes the event with the given id from the file, it returns
+// repository.ErrDeadLetterNotFound if there is none.
+func (f *FileDeadLetters) Remove(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deadLetters, err := f.read()
+	if err != nil {
+		f.logger.Error("unable to read dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return errRemovingDeadLetter
+	}
+
+	kept := make([]repository.DeadLetter, 0, len(deadLetters))
+
+	for _, deadLetter := range deadLetters {
+		if deadLetter.ID != id {
+			kept = append(kept, deadLetter)
+		}
+	}
+
+	if len(kept) == len(deadLetters) {
+		return repository.ErrDeadLetterNotFound
+	}
+
+	err = f.write(kept)
+	if err != nil {
+		f.logger.Error("unable to write dead-letter file", loggers.Fields{"path": f.path, "error": err})
+
+		return errRemovingDeadLetter
+	}
+
+	return nil
+}
+
+// read returns the events of the file, none if it doesn't exist.
+func (f *FileDeadLetters) read() ([]repository.DeadLetter, error) {
+	deadLetters := make([]repository.DeadLetter, 0)
+
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return deadLetters, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var deadLetter repository.DeadLetter
+
+		err = json.Unmarshal(scanner.Bytes(), &deadLetter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dead-lettered event: %w", err)
+		}
+
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, scanner.Err()
+}
+
+// write replaces the file with the given events, the new content is written
+// to a temporary file first so the file is never left half written.
+func (f *FileDeadLetters) write(deadLetters []repository.DeadLetter) error {
+	file, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	for _, deadLetter := range deadLetters {
+		err = encoder.Encode(deadLetter)
+		if err != nil {
+			file.Close()
+
+			return err
+		}
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), f.path)
+}
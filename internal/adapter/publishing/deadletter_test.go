@@ -0,0 +1,73 @@
+package publishing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+	deadLetters := publishing.NewFileDeadLetters(path, loggers.NewLoggerWithStdout("", loggers.Error))
+	ctx := context.TODO()
+	mango := repository.DeadLetter{
+		ID:             "a",
+		Event:          newFruitCreated("1", "Mango"),
+		Attempts:       3,
+		LastError:      "topic not available",
+		DeadLetteredAt: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+	}
+	pear := repository.DeadLetter{
+		ID:             "b",
+		Event:          newFruitCreated("2", "Pear"),
+		Attempts:       3,
+		DeadLetteredAt: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+	}
+
+	got, err := deadLetters.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, deadLetters.Add(ctx, mango))
+	assert.NoError(t, deadLetters.Add(ctx, pear))
+
+	got, err = deadLetters.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.DeadLetter{mango, pear}, got)
+
+	err = deadLetters.Remove(ctx, "a")
+	assert.NoError(t, err)
+
+	err = deadLetters.Remove(ctx, "a")
+	assert.ErrorIs(t, err, repository.ErrDeadLetterNotFound)
+
+	got, err = publishing.NewFileDeadLetters(path, loggers.NewLoggerWithStdout("", loggers.Error)).List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.DeadLetter{pear}, got)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileDeadLettersRejectsInvalidFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+
+	err := os.WriteFile(path, []byte("not json\n"), 0o600)
+	assert.NoError(t, err)
+
+	_, err = publishing.NewFileDeadLetters(path, loggers.NewLoggerWithStdout("", loggers.Error)).List(context.TODO())
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package publishing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// ErrSinkFailed is returned when an event could not be published to some sink.
+var ErrSinkFailed = errors.New("event was not published to every sink")
+
+// Sink is a publisher of a fan-out, the name tells it apart in errors and logs.
+type Sink struct {
+	Name      string
+	Publisher Publisher
+}
+
+// FanOut publishes every event to several sinks at once.
+type FanOut struct {
+	sinks  []Sink
+	logger *loggers.Logger
+}
+
+// NewFanOut creates a publisher that publishes the events to all the given sinks.
+func NewFanOut(logger *loggers.Logger, sinks ...Sink) *FanOut {
+	return &FanOut{
+		sinks:  sinks,
+		logger: logger,
+	}
+}
+
+// Publish publishes the event to every sink concurrently. If a sink fails
+// ErrSinkFailed is returned after the others finished, so a retry publishes
+// the event again to the sinks that already had it and consumers must
+// discard the event ids they already handled.
+func (f *FanOut) Publish(ctx context.Context, event repository.Event) error {
+	errs := make([]error, len(f.sinks))
+
+	var wg sync.WaitGroup
+
+	for index, sink := range f.sinks {
+		wg.Add(1)
+
+		go func(index int, sink Sink) {
+			defer wg.Done()
+
+			errs[index] = sink.Publisher.Publish(ctx, event)
+		}(index, sink)
+	}
+
+	wg.Wait()
+
+	failures := make([]string, 0)
+
+	for index, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		f.logger.Error(
+			"unable to publish event to sink",
+			loggers.Fields{
+				"sink":  f.sinks[index].Name,
+				"event": event.ID,
+				"error": err,
+			},
+		)
+
+		failures = append(failures, fmt.Sprintf("%s: %s", f.sinks[index].Name, err))
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: %s", ErrSinkFailed, strings.Join(failures, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package publishing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOutPublishesToEverySink(t *testing.T) {
+	t.Parallel()
+
+	topic, queue := newPublisherMock(0), newPublisherMock(0)
+	fanOut := publishing.NewFanOut(
+		loggers.NewLoggerWithStdout("", loggers.Error),
+		publishing.Sink{Name: "sns", Publisher: topic},
+		publishing.Sink{Name: "sqs", Publisher: queue},
+		publishing.Sink{Name: "none", Publisher: publishing.Noop{}},
+	)
+
+	err := fanOut.Publish(context.TODO(), newFruitCreated("1", "Mango"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mango"}, topic.names())
+	assert.Equal(t, []string{"Mango"}, queue.names())
+}
+
+func TestFanOutFailsIfAnySinkFails(t *testing.T) {
+	t.Parallel()
+
+	topic, queue := newPublisherMock(1), newPublisherMock(0)
+	fanOut := publishing.NewFanOut(
+		loggers.NewLoggerWithStdout("", loggers.Error),
+		publishing.Sink{Name: "sns", Publisher: topic},
+		publishing.Sink{Name: "sqs", Publisher: queue},
+	)
+	ctx := context.TODO()
+
+	err := fanOut.Publish(ctx, newFruitCreated("1", "Mango"))
+	assert.ErrorIs(t, err, publishing.ErrSinkFailed)
+	assert.Contains(t, err.Error(), "sns: topic not available")
+	assert.Empty(t, topic.names())
+	assert.Equal(t, []string{"Mango"}, queue.names())
+
+	err = fanOut.Publish(ctx, newFruitCreated("1", "Mango"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mango"}, topic.names())
+	assert.Equal(t, []string{"Mango", "Mango"}, queue.names())
+}
+
+func TestResilientFanOutDeadLettersOnlyAfterEverySinkTried(t *testing.T) {
+	t.Parallel()
+
+	topic, queue := newPublisherMock(5), newPublisherMock(0)
+	fanOut := publishing.NewFanOut(
+		loggers.NewLoggerWithStdout("", loggers.Error),
+		publishing.Sink{Name: "sns", Publisher: topic},
+		publishing.Sink{Name: "sqs", Publisher: queue},
+	)
+	deadLetters := newFileDeadLetters(t)
+	resilient := publishing.NewResilient(fanOut, deadLetters, newSetup(2, 0))
+	ctx := context.TODO()
+
+	err := resilient.Publish(ctx, newFruitCreated("1", "Mango"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, topic.calls())
+	assert.Equal(t, 2, queue.calls())
+
+	got, err := deadLetters.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Contains(t, got[0].LastError, "sns: topic not available")
+}
@@ -0,0 +1,81 @@
+package publishing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/cloudevents"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+var errWritingEvent = errors.New("unable to write fruit event")
+
+// FileSink publishes the events to a local file, one structured JSON
+// CloudEvent per line. It is safe for concurrent use within a process,
+// replicas must not share the file.
+type FileSink struct {
+	mu     sync.Mutex
+	path   string
+	source string
+	logger *loggers.Logger
+}
+
+// NewFileSink creates a publisher that appends the events to the file with
+// the given path, source is the CloudEvents source of the events.
+func NewFileSink(path, source string, logger *loggers.Logger) *FileSink {
+	return &FileSink{
+		path:   path,
+		source: source,
+		logger: logger,
+	}
+}
+
+// Publish appends the event to the file.
+func (f *FileSink) Publish(_ context.Context, event repository.Event) error {
+	message, err := cloudevents.Marshal(event, f.source)
+	if err != nil {
+		f.logger.Error("unable to marshal fruit message", loggers.Fields{"event": event.ID, "error": err})
+
+		return errWritingEvent
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		f.logger.Error("unable to open events file", loggers.Fields{"path": f.path, "error": err})
+
+		return errWritingEvent
+	}
+
+	_, err = file.Write(append(message, '\n'))
+	if err != nil {
+		file.Close()
+
+		f.logger.Error("unable to write events file", loggers.Fields{"path": f.path, "error": err})
+
+		return errWritingEvent
+	}
+
+	err = file.Close()
+	if err != nil {
+		f.logger.Error("unable to close events file", loggers.Fields{"path": f.path, "error": err})
+
+		return errWritingEvent
+	}
+
+	return nil
+}
+
+// Noop discards the events, it is the publisher of a service whose events
+// nobody consumes.
+type Noop struct{}
+
+// Publish discards the event.
+func (Noop) Publish(_ context.Context, _ repository.Event) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package publishing_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/cloudevents"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink := publishing.NewFileSink(path, "https://github.com/fernandoocampo/fruits", loggers.NewLoggerWithStdout("", loggers.Error))
+	ctx := context.TODO()
+	mango := newFruitCreated("1", "Mango")
+	pear := newFruitCreated("2", "Pear")
+
+	assert.NoError(t, sink.Publish(ctx, mango))
+	assert.NoError(t, sink.Publish(ctx, pear))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	for index, want := range []repository.Event{mango, pear} {
+		got, err := cloudevents.Unmarshal(lines[index])
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestFileSinkRejectsInvalidEvent(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink := publishing.NewFileSink(path, "https://github.com/fernandoocampo/fruits", loggers.NewLoggerWithStdout("", loggers.Error))
+
+	err := sink.Publish(context.TODO(), repository.Event{ID: "1", Type: "fruit.peeled"})
+
+	assert.Error(t, err)
+	assert.NoFileExists(t, path)
+}
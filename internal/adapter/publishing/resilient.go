@@ -0,0 +1,221 @@
+package publishing
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/google/uuid"
+)
+
+// ErrCircuitOpen is returned without trying to publish while the publisher
+// keeps failing, the event is not dead-lettered.
+var ErrCircuitOpen = errors.New("publishing is paused after repeated failures")
+
+// Publisher defines the behavior of the publisher the events are sent to.
+type Publisher interface {
+	Publish(ctx context.Context, event repository.Event) error
+}
+
+// DeadLetterSink defines the behavior of the storage of the events that
+// could not be published.
+type DeadLetterSink interface {
+	Add(ctx context.Context, deadLetter repository.DeadLetter) error
+	// List returns the events in the order they were dead-lettered.
+	List(ctx context.Context) ([]repository.DeadLetter, error)
+	// Remove returns repository.ErrDeadLetterNotFound if there is no event with the id.
+	Remove(ctx context.Context, id string) error
+}
+
+// Setup contains the retry and circuit breaker settings.
+type Setup struct {
+	Logger *loggers.Logger
+	// MaxAttempts is the number of times an event is tried to be published.
+	MaxAttempts int
+	// RetryDelay is the delay after the first failed attempt, it doubles on
+	// every attempt up to MaxRetryDelay. Delays are jittered between half
+	// and all of their value.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// FailureThreshold is the number of consecutive failed attempts that
+	// opens the circuit, 0 never opens it.
+	FailureThreshold int
+	// OpenDuration is the time the circuit stays open before an attempt is
+	// let through to check if the publisher recovered.
+	OpenDuration time.Duration
+}
+
+// Resilient is a publisher decorator that retries failed attempts with a
+// jittered exponential backoff, stops trying while the publisher keeps
+// failing and dead-letters the events that exhaust their attempts.
+type Resilient struct {
+	publisher   Publisher
+	deadLetters DeadLetterSink
+	breaker     *circuitBreaker
+	setup       Setup
+	randMu      sync.Mutex
+	random      *rand.Rand
+}
+
+// NewResilient decorates the publisher, events that exhaust their attempts are added to deadLetters.
+func NewResilient(publisher Publisher, deadLetters DeadLetterSink, setup Setup) *Resilient {
+	if setup.MaxAttempts < 1 {
+		setup.MaxAttempts = 1
+	}
+
+	if setup.MaxRetryDelay < setup.RetryDelay {
+		setup.MaxRetryDelay = setup.RetryDelay
+	}
+
+	return &Resilient{
+		publisher:   publisher,
+		deadLetters: deadLetters,
+		breaker:     newCircuitBreaker(setup.FailureThreshold, setup.OpenDuration),
+		setup:       setup,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Publish publishes the event trying up to MaxAttempts times. An event that
+// exhausts its attempts is dead-lettered and nil is returned, because it is
+// kept there until it is re-driven. If the circuit is open or ctx is done
+// the event is not dead-lettered and the error is returned.
+func (r *Resilient) Publish(ctx context.Context, event repository.Event) error {
+	err := r.publishWithRetries(ctx, event)
+	if err == nil || errors.Is(err, ErrCircuitOpen) || ctx.Err() != nil {
+		return err
+	}
+
+	deadLetter := repository.DeadLetter{
+		ID:             uuid.New().String(),
+		Event:          event,
+		Attempts:       r.setup.MaxAttempts,
+		LastError:      err.Error(),
+		DeadLetteredAt: time.Now().UTC(),
+	}
+
+	addErr := r.deadLetters.Add(ctx, deadLetter)
+	if addErr != nil {
+		return addErr
+	}
+
+	r.setup.Logger.Error(
+		"event was dead-lettered after every attempt to publish it failed",
+		loggers.Fields{
+			"id":       deadLetter.ID,
+			"event":    event.ID,
+			"attempts": deadLetter.Attempts,
+			"error":    err,
+		},
+	)
+
+	return nil
+}
+
+// DeadLetters returns the dead-lettered events, the oldest first.
+func (r *Resilient) DeadLetters(ctx context.Context) ([]repository.DeadLetter, error) {
+	return r.deadLetters.List(ctx)
+}
+
+// Redrive publishes the dead-lettered event with the given id again and
+// removes it once it is published. If it cannot be published it is kept.
+func (r *Resilient) Redrive(ctx context.Context, id string) error {
+	deadLetters, err := r.deadLetters.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, deadLetter := range deadLetters {
+		if deadLetter.ID != id {
+			continue
+		}
+
+		err = r.publishWithRetries(ctx, deadLetter.Event)
+		if err != nil {
+			return err
+		}
+
+		return r.deadLetters.Remove(ctx, id)
+	}
+
+	return repository.ErrDeadLetterNotFound
+}
+
+// publishWithRetries tries to publish the event until it succeeds, it runs
+// out of attempts or the circuit opens.
+func (r *Resilient) publishWithRetries(ctx context.Context, event repository.Event) error {
+	var err error
+
+	for attempt := 1; attempt <= r.setup.MaxAttempts; attempt++ {
+		made, allowed := r.breaker.allow(time.Now())
+		if !allowed {
+			return ErrCircuitOpen
+		}
+
+		err = r.publisher.Publish(ctx, event)
+		r.breaker.record(time.Now(), made, err)
+
+		if err == nil {
+			return nil
+		}
+
+		r.setup.Logger.Debug(
+			"unable to publish event",
+			loggers.Fields{
+				"event":   event.ID,
+				"attempt": attempt,
+				"error":   err,
+			},
+		)
+
+		if attempt < r.setup.MaxAttempts {
+			sleepErr := sleep(ctx, r.retryDelay(attempt))
+			if sleepErr != nil {
+				return sleepErr
+			}
+		}
+	}
+
+	return err
+}
+
+// retryDelay returns the jittered delay after the given failed attempt.
+func (r *Resilient) retryDelay(attempt int) time.Duration {
+	delay := r.setup.RetryDelay
+
+	for i := 1; i < attempt && delay < r.setup.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > r.setup.MaxRetryDelay {
+		delay = r.setup.MaxRetryDelay
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	r.randMu.Lock()
+	defer r.randMu.Unlock()
+
+	half := delay / 2
+
+	return half + time.Duration(r.random.Int63n(int64(delay-half)+1))
+}
+
+// sleep waits for the given duration or until ctx is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
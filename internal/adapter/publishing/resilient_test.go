@@ -0,0 +1,285 @@
+package publishing_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishRetriesFailedAttempts(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		failures            int
+		expectedCalls       int
+		expectedDeadLetters int
+	}{
+		"first_attempt": {
+			failures:            0,
+			expectedCalls:       1,
+			expectedDeadLetters: 0,
+		},
+		"last_attempt": {
+			failures:            2,
+			expectedCalls:       3,
+			expectedDeadLetters: 0,
+		},
+		"exhausted": {
+			failures:            3,
+			expectedCalls:       3,
+			expectedDeadLetters: 1,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			publisher := newPublisherMock(test.failures)
+			deadLetters := newFileDeadLetters(st)
+			resilient := publishing.NewResilient(publisher, deadLetters, newSetup(3, 0))
+
+			err := resilient.Publish(context.TODO(), newFruitCreated("1", "Mango"))
+			assert.NoError(st, err)
+			assert.Equal(st, test.expectedCalls, publisher.calls())
+
+			got, err := resilient.DeadLetters(context.TODO())
+			assert.NoError(st, err)
+			assert.Len(st, got, test.expectedDeadLetters)
+		})
+	}
+}
+
+func TestPublishDeadLettersExhaustedEvents(t *testing.T) {
+	t.Parallel()
+
+	event := newFruitCreated("1", "Mango")
+	resilient := publishing.NewResilient(newPublisherMock(5), newFileDeadLetters(t), newSetup(2, 0))
+
+	err := resilient.Publish(context.TODO(), event)
+	assert.NoError(t, err)
+
+	got, err := resilient.DeadLetters(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.NotEmpty(t, got[0].ID)
+	assert.Equal(t, event, got[0].Event)
+	assert.Equal(t, 2, got[0].Attempts)
+	assert.Equal(t, "topic not available", got[0].LastError)
+	assert.WithinDuration(t, time.Now(), got[0].DeadLetteredAt, time.Minute)
+}
+
+func TestCircuitOpensAfterRepeatedFailures(t *testing.T) {
+	t.Parallel()
+
+	publisher := newPublisherMock(2)
+	setup := newSetup(1, 2)
+	setup.OpenDuration = 50 * time.Millisecond
+	resilient := publishing.NewResilient(publisher, newFileDeadLetters(t), setup)
+	ctx := context.TODO()
+
+	assert.NoError(t, resilient.Publish(ctx, newFruitCreated("1", "Mango")))
+	assert.NoError(t, resilient.Publish(ctx, newFruitCreated("2", "Pear")))
+
+	err := resilient.Publish(ctx, newFruitCreated("3", "Apple"))
+	assert.ErrorIs(t, err, publishing.ErrCircuitOpen)
+	assert.Equal(t, 2, publisher.calls())
+
+	time.Sleep(60 * time.Millisecond)
+
+	err = resilient.Publish(ctx, newFruitCreated("3", "Apple"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, publisher.calls())
+
+	got, err := resilient.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+}
+
+func TestLateAttemptDoesNotCloseOpenCircuit(t *testing.T) {
+	t.Parallel()
+
+	publisher := newGatedPublisherMock("Mango", 1)
+	resilient := publishing.NewResilient(publisher, newFileDeadLetters(t), newSetup(1, 1))
+	ctx := context.TODO()
+	lateResult := make(chan error)
+
+	// this attempt is let through while the circuit is closed and finishes after it opened.
+	go func() {
+		lateResult <- resilient.Publish(ctx, newFruitCreated("1", "Mango"))
+	}()
+
+	<-publisher.started
+
+	assert.NoError(t, resilient.Publish(ctx, newFruitCreated("2", "Pear")))
+
+	close(publisher.release)
+	assert.NoError(t, <-lateResult)
+
+	err := resilient.Publish(ctx, newFruitCreated("3", "Apple"))
+	assert.ErrorIs(t, err, publishing.ErrCircuitOpen)
+}
+
+func TestPublishDoesNotDeadLetterCancelledEvents(t *testing.T) {
+	t.Parallel()
+
+	setup := newSetup(3, 0)
+	setup.RetryDelay = time.Hour
+	setup.MaxRetryDelay = time.Hour
+	resilient := publishing.NewResilient(newPublisherMock(1), newFileDeadLetters(t), setup)
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+
+	defer cancel()
+
+	err := resilient.Publish(ctx, newFruitCreated("1", "Mango"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	got, err := resilient.DeadLetters(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRedriveDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	publisher := newPublisherMock(2)
+	resilient := publishing.NewResilient(publisher, newFileDeadLetters(t), newSetup(1, 0))
+	ctx := context.TODO()
+
+	err := resilient.Publish(ctx, newFruitCreated("1", "Mango"))
+	assert.NoError(t, err)
+
+	deadLetters, err := resilient.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 1)
+
+	err = resilient.Redrive(ctx, deadLetters[0].ID)
+	assert.EqualError(t, err, "topic not available")
+
+	got, err := resilient.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, deadLetters, got)
+
+	err = resilient.Redrive(ctx, deadLetters[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Mango"}, publisher.names())
+
+	got, err = resilient.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	err = resilient.Redrive(ctx, deadLetters[0].ID)
+	assert.ErrorIs(t, err, repository.ErrDeadLetterNotFound)
+}
+
+func newSetup(maxAttempts, failureThreshold int) publishing.Setup {
+	return publishing.Setup{
+		Logger:           loggers.NewLoggerWithStdout("", loggers.Error),
+		MaxAttempts:      maxAttempts,
+		RetryDelay:       time.Millisecond,
+		MaxRetryDelay:    2 * time.Millisecond,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     time.Minute,
+	}
+}
+
+func newFruitCreated(sourceID, name string) repository.Event {
+	return repository.NewFruitCreated(
+		repository.Fruit{ID: repository.FruitID(sourceID), Name: name},
+		time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+	)
+}
+
+func newFileDeadLetters(t *testing.T) *publishing.FileDeadLetters {
+	t.Helper()
+
+	return publishing.NewFileDeadLetters(filepath.Join(t.TempDir(), "dead-letters.ndjson"), loggers.NewLoggerWithStdout("", loggers.Error))
+}
+
+// publisherMock fails the given number of times before it publishes.
+type publisherMock struct {
+	mu        sync.Mutex
+	failures  int
+	attempts  int
+	published []repository.Event
+}
+
+func newPublisherMock(failures int) *publisherMock {
+	return &publisherMock{failures: failures}
+}
+
+func (p *publisherMock) Publish(_ context.Context, event repository.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+
+	if p.failures > 0 {
+		p.failures--
+
+		return errors.New("topic not available")
+	}
+
+	p.published = append(p.published, event)
+
+	return nil
+}
+
+func (p *publisherMock) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.attempts
+}
+
+func (p *publisherMock) names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.published))
+
+	for _, event := range p.published {
+		names = append(names, event.FruitCreated.Name)
+	}
+
+	return names
+}
+
+// gatedPublisherMock holds the attempts to publish the fruit with the given
+// name until release is closed, the rest fail the given number of times.
+type gatedPublisherMock struct {
+	publisherMock
+	gated   string
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGatedPublisherMock(gated string, failures int) *gatedPublisherMock {
+	return &gatedPublisherMock{
+		publisherMock: publisherMock{failures: failures},
+		gated:         gated,
+		started:       make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+}
+
+func (g *gatedPublisherMock) Publish(ctx context.Context, event repository.Event) error {
+	if event.FruitCreated.Name == g.gated {
+		close(g.started)
+		<-g.release
+
+		return nil
+	}
+
+	return g.publisherMock.Publish(ctx, event)
+}
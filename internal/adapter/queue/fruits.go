@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/fernandoocampo/fruits/internal/adapter/cloudevents"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// fifoSuffix ends the names of the fifo queues.
+const fifoSuffix = ".fifo"
+
+var (
+	errLoadingAWSConfig = errors.New("unable to load aws config")
+	errCreatingSQS      = errors.New("unable to connect to SQS")
+	errPublishingFruit  = errors.New("unable to send fruit event")
+)
+
+// Setup contains sqs settings.
+type Setup struct {
+	Logger   *loggers.Logger
+	Region   string
+	Endpoint string
+	// QueueURL is the url of the queue the events are sent to.
+	QueueURL string
+	// Source is the CloudEvents source of the sent events.
+	Source string
+}
+
+// SQS sends the fruit events straight to a queue.
+type SQS struct {
+	client   *sqs.Client
+	logger   *loggers.Logger
+	queueURL string
+	source   string
+}
+
+// NewSQSClient creates a client that sends the events to the queue of the setup.
+func NewSQSClient(ctx context.Context, setup Setup) (*SQS, error) {
+	newsqs := new(SQS)
+	newsqs.logger = setup.Logger
+	newsqs.queueURL = setup.QueueURL
+	newsqs.source = setup.Source
+
+	awsconfig, err := newsqs.getConfig(ctx, setup.Region, setup.Endpoint)
+	if err != nil {
+		return nil, errCreatingSQS
+	}
+
+	newsqs.client = sqs.NewFromConfig(awsconfig)
+
+	return newsqs, nil
+}
+
+func (s *SQS) getConfig(ctx context.Context, region, endpoint string) (aws.Config, error) {
+	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		if endpoint != "" {
+			return aws.Endpoint{
+				URL:           endpoint,
+				SigningRegion: region,
+			}, nil
+		}
+
+		return aws.Endpoint{}, nil
+	})
+
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion(region),
+		config.WithEndpointResolverWithOptions(customResolver),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("d", "d", "")),
+	)
+	if err != nil {
+		s.logger.Error("unable to load aws config",
+			loggers.Fields{
+				"error": err,
+			},
+		)
+
+		return cfg, errLoadingAWSConfig
+	}
+
+	return cfg, nil
+}
+
+// Publish sends the event as a structured JSON CloudEvent. On a fifo queue
+// the events of a fruit keep their order and the event id discards the
+// copies sent again within the deduplication interval of the queue.
+func (s *SQS) Publish(ctx context.Context, event repository.Event) error {
+	message, err := cloudevents.Marshal(event, s.source)
+	if err != nil {
+		s.logger.Error("unable to marshal fruit message", loggers.Fields{"event": event.ID, "error": err})
+
+		return errPublishingFruit
+	}
+
+	input := &sqs.SendMessageInput{
+		MessageBody: aws.String(string(message)),
+		QueueUrl:    aws.String(s.queueURL),
+	}
+
+	if strings.HasSuffix(s.queueURL, fifoSuffix) {
+		input.MessageGroupId = aws.String(event.FruitID())
+		input.MessageDeduplicationId = aws.String(event.ID)
+	}
+
+	result, err := s.client.SendMessage(ctx, input)
+	if err != nil {
+		s.logger.Error("unable to send fruit message", loggers.Fields{"error": err})
+
+		return errPublishingFruit
+	}
+
+	if result != nil {
+		s.logger.Info("sending fruit event", loggers.Fields{"event": event.ID, "result": result.MessageId})
+	}
+
+	return nil
+}
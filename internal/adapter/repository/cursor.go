@@ -0,0 +1,97 @@
+package repository
+
+import "sort"
+
+// Cursor is the position after the last fruit of a search page, backends
+// decide which of its fields they use.
+type Cursor struct {
+	// Key is the backend position of the last fruit, e.g. its dynamodb key.
+	Key string `json:"key,omitempty"`
+	// Last holds the id and the sort fields of the last fruit, it is used to
+	// continue sorted searches.
+	Last *Fruit `json:"last,omitempty"`
+	// Total is the number of fruits the first page found, for backends that
+	// don't count every fruit again on the next pages.
+	Total int `json:"total,omitempty"`
+}
+
+// SortedPage returns the window of the given sorted fruits described by the
+// filter, it starts right after the filter cursor if there is one. The
+// returned cursor is nil if there are no more fruits after the window.
+func (f FruitFilter) SortedPage(fruits []Fruit) ([]Fruit, *Cursor) {
+	first := f.Start - 1
+
+	if f.After != nil && f.After.Last != nil {
+		first = sort.Search(len(fruits), func(index int) bool {
+			return compareFruits(&fruits[index], f.After.Last, f.Sort) > 0
+		})
+	}
+
+	page, more := window(fruits, first, f.Count)
+	if !more {
+		return page, nil
+	}
+
+	return page, &Cursor{Last: sortKey(page[len(page)-1], f.Sort)}
+}
+
+// Page returns the window of the given fruits described by the filter and
+// whether there are more fruits after it. If the filter has a cursor the
+// given fruits must start right after it.
+func (f FruitFilter) Page(fruits []Fruit) ([]Fruit, bool) {
+	if f.After != nil {
+		return window(fruits, 0, f.Count)
+	}
+
+	return window(fruits, f.Start-1, f.Count)
+}
+
+// window returns count fruits from the first one and whether there are more fruits after them.
+func window(fruits []Fruit, first, count int) ([]Fruit, bool) {
+	if first < 0 {
+		first = 0
+	}
+
+	if first > len(fruits) {
+		first = len(fruits)
+	}
+
+	last := first + count
+	if last > len(fruits) {
+		last = len(fruits)
+	}
+
+	return fruits[first:last], last > first && last < len(fruits)
+}
+
+// sortKey returns a fruit with only the id and the given sort fields of the given fruit.
+func sortKey(fruit Fruit, fields []SortField) *Fruit {
+	key := Fruit{
+		ID: fruit.ID,
+	}
+
+	for _, field := range fields {
+		switch field.Field {
+		case SortByName:
+			key.Name = fruit.Name
+		case SortByVariety:
+			key.Variety = fruit.Variety
+		case SortByYear:
+			key.Year = fruit.Year
+		case SortByPrice:
+			key.Price = fruit.Price
+		case SortByVault:
+			key.Vault = fruit.Vault
+		case SortByCountry:
+			key.Country = fruit.Country
+		case SortByProvince:
+			key.Province = fruit.Province
+		case SortByRegion:
+			key.Region = fruit.Region
+		case SortByClassification:
+			key.Classification = fruit.Classification
+		}
+	}
+
+	return &key
+}
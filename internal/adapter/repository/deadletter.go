@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDeadLetterNotFound is returned when there is no dead-lettered event with the given id.
+var ErrDeadLetterNotFound = errors.New("dead-lettered event not found")
+
+// DeadLetter is an event that could not be published after every attempt.
+type DeadLetter struct {
+	ID    string `json:"id"`
+	Event Event  `json:"event"`
+	// Attempts is the number of times the event was tried to be published.
+	Attempts int `json:"attempts"`
+	// LastError is the reason of the last failed attempt.
+	LastError      string    `json:"last_error"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies the fruit change an event announces.
+type EventType string
+
+// Fruit changes announced with events.
+const (
+	// EventFruitCreated announces a new fruit, its data is in FruitCreated.
+	EventFruitCreated EventType = "fruit.created"
+	// EventFruitUpdated announces a fruit change, its data is in FruitUpdated.
+	EventFruitUpdated EventType = "fruit.updated"
+	// EventFruitDeleted announces a fruit deletion, its data is in FruitDeleted.
+	EventFruitDeleted EventType = "fruit.deleted"
+)
+
+// Event is a fruit change announced to other systems.
+type Event struct {
+	// ID identifies the event, it is the same on every attempt to publish it
+	// so consumers can discard duplicates.
+	ID   string    `json:"id"`
+	Type EventType `json:"type"`
+	// OccurredAt is the moment the fruit change was stored.
+	OccurredAt   time.Time          `json:"occurred_at"`
+	FruitCreated *NewFruitEvent     `json:"fruit_created,omitempty"`
+	FruitUpdated *FruitUpdatedEvent `json:"fruit_updated,omitempty"`
+	FruitDeleted *FruitDeletedEvent `json:"fruit_deleted,omitempty"`
+}
+
+// FruitUpdatedEvent contains data for updated fruit events.
+type FruitUpdatedEvent struct {
+	SourceID string `json:"source_id"`
+	// Version is the version of the fruit after the update, consumers can
+	// discard the events of a fruit older than the last one they handled.
+	Version int64 `json:"version"`
+	// ChangedFields are the json names of the fields whose value changed, sorted.
+	ChangedFields []string `json:"changed_fields"`
+	// Before and After have the json values of the changed fields.
+	Before map[string]json.RawMessage `json:"before"`
+	After  map[string]json.RawMessage `json:"after"`
+}
+
+// FruitDeletedEvent contains data for deleted fruit events.
+type FruitDeletedEvent struct {
+	SourceID string `json:"source_id"`
+	// Version is the version the deletion gives the fruit, one more than the
+	// version deleted, so it is newer than every event of the fruit.
+	Version int64 `json:"version"`
+}
+
+// untrackedFields are left out of FruitUpdated events, every update changes them.
+var untrackedFields = []string{"id", "version", "last_modified"}
+
+// NewFruitCreated returns the event that announces the given new fruit.
+func NewFruitCreated(fruit Fruit, occurredAt time.Time) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       EventFruitCreated,
+		OccurredAt: occurredAt,
+		FruitCreated: &NewFruitEvent{
+			SourceID: FruitIDValue(fruit.ID),
+			Version:  fruit.Version,
+			Name:     fruit.Name,
+			Variety:  fruit.Variety,
+			Price:    FruitPriceValue(fruit.Price),
+		},
+	}
+}
+
+// NewFruitUpdated returns the event that announces the update of the fruit
+// before into after, after has the version the update gives the fruit.
+func NewFruitUpdated(before, after Fruit, occurredAt time.Time) (Event, error) {
+	beforeFields, err := fruitFields(before)
+	if err != nil {
+		return Event{}, err
+	}
+
+	afterFields, err := fruitFields(after)
+	if err != nil {
+		return Event{}, err
+	}
+
+	updated := FruitUpdatedEvent{
+		SourceID:      FruitIDValue(after.ID),
+		Version:       after.Version,
+		ChangedFields: make([]string, 0),
+		Before:        make(map[string]json.RawMessage),
+		After:         make(map[string]json.RawMessage),
+	}
+
+	for field := range fieldNames(beforeFields, afterFields) {
+		beforeValue, afterValue := fieldValue(beforeFields, field), fieldValue(afterFields, field)
+		if string(beforeValue) == string(afterValue) {
+			continue
+		}
+
+		updated.ChangedFields = append(updated.ChangedFields, field)
+		updated.Before[field] = beforeValue
+		updated.After[field] = afterValue
+	}
+
+	sort.Strings(updated.ChangedFields)
+
+	return Event{
+		ID:           uuid.New().String(),
+		Type:         EventFruitUpdated,
+		OccurredAt:   occurredAt,
+		FruitUpdated: &updated,
+	}, nil
+}
+
+// NewFruitDeleted returns the event that announces the deletion of the given
+// fruit, the deleted fruit has the version that was stored.
+func NewFruitDeleted(fruit Fruit, occurredAt time.Time) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       EventFruitDeleted,
+		OccurredAt: occurredAt,
+		FruitDeleted: &FruitDeletedEvent{
+			SourceID: FruitIDValue(fruit.ID),
+			Version:  fruit.Version + 1,
+		},
+	}
+}
+
+// FruitID returns the id of the fruit the event is about.
+func (e Event) FruitID() string {
+	switch {
+	case e.FruitCreated != nil:
+		return e.FruitCreated.SourceID
+	case e.FruitUpdated != nil:
+		return e.FruitUpdated.SourceID
+	case e.FruitDeleted != nil:
+		return e.FruitDeleted.SourceID
+	}
+
+	return ""
+}
+
+// fruitFields returns the json value of every field of the fruit that an
+// update can change, the empty fields the json of a fruit omits are missing.
+func fruitFields(fruit Fruit) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(fruit)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range untrackedFields {
+		delete(fields, field)
+	}
+
+	return fields, nil
+}
+
+// fieldNames returns the names of the fields of both fruits.
+func fieldNames(before, after map[string]json.RawMessage) map[string]struct{} {
+	names := make(map[string]struct{}, len(after))
+
+	for name := range before {
+		names[name] = struct{}{}
+	}
+
+	for name := range after {
+		names[name] = struct{}{}
+	}
+
+	return names
+}
+
+// fieldValue returns the json value of the field, null if the fruit omits it.
+func fieldValue(fields map[string]json.RawMessage, field string) json.RawMessage {
+	value, ok := fields[field]
+	if !ok {
+		return json.RawMessage("null")
+	}
+
+	return value
+}
@@ -0,0 +1,98 @@
+package repository
+
+import "sort"
+
+// Fields fruits can be counted by.
+const (
+	FacetByCountry        = "country"
+	FacetByProvince       = "province"
+	FacetByRegion         = "region"
+	FacetByVariety        = "variety"
+	FacetByClassification = "classification"
+	FacetByVault          = "vault"
+)
+
+// FacetBucket contains the number of fruits that share the same value on a field.
+type FacetBucket struct {
+	Value string
+	Count int
+}
+
+// Facets contains the buckets of every counted field, by field name.
+type Facets map[string][]FacetBucket
+
+// facetValues return the value of a fruit field.
+var facetValues = map[string]func(fruit *Fruit) string{
+	FacetByCountry:        func(fruit *Fruit) string { return fruit.Country },
+	FacetByProvince:       func(fruit *Fruit) string { return fruit.Province },
+	FacetByRegion:         func(fruit *Fruit) string { return fruit.Region },
+	FacetByVariety:        func(fruit *Fruit) string { return fruit.Variety },
+	FacetByClassification: func(fruit *Fruit) string { return fruit.Classification },
+	FacetByVault:          func(fruit *Fruit) string { return fruit.Vault },
+}
+
+// IsFacet checks if fruits can be counted by the given field.
+func IsFacet(field string) bool {
+	_, ok := facetValues[field]
+
+	return ok
+}
+
+// FacetCounter counts fruits by the values of some fields.
+type FacetCounter struct {
+	counts map[string]map[string]int
+}
+
+// NewFacetCounter creates a counter of the given fields, unknown fields are ignored.
+func NewFacetCounter(fields []string) *FacetCounter {
+	counter := FacetCounter{
+		counts: make(map[string]map[string]int, len(fields)),
+	}
+
+	for _, field := range fields {
+		if IsFacet(field) {
+			counter.counts[field] = make(map[string]int)
+		}
+	}
+
+	return &counter
+}
+
+// Add counts the given fruit, empty values are not counted.
+func (f *FacetCounter) Add(fruit Fruit) {
+	for field, counts := range f.counts {
+		value := facetValues[field](&fruit)
+		if value != "" {
+			counts[value]++
+		}
+	}
+}
+
+// Facets returns the buckets of every field sorted by count, most common
+// values first, and then by value. It returns nil if no field was counted.
+func (f *FacetCounter) Facets() Facets {
+	if len(f.counts) == 0 {
+		return nil
+	}
+
+	facets := make(Facets, len(f.counts))
+
+	for field, counts := range f.counts {
+		buckets := make([]FacetBucket, 0, len(counts))
+		for value, count := range counts {
+			buckets = append(buckets, FacetBucket{Value: value, Count: count})
+		}
+
+		sort.Slice(buckets, func(i, j int) bool {
+			if buckets[i].Count != buckets[j].Count {
+				return buckets[i].Count > buckets[j].Count
+			}
+
+			return buckets[i].Value < buckets[j].Value
+		})
+
+		facets[field] = buckets
+	}
+
+	return facets
+}
@@ -0,0 +1,58 @@
+package repository
+
+// Match checks if the given fruit meets every condition of the filter,
+// fruits without price never match a price range.
+func (f FruitFilter) Match(fruit Fruit) bool {
+	return matchText(f.Country, fruit.Country) &&
+		matchText(f.Province, fruit.Province) &&
+		matchText(f.Region, fruit.Region) &&
+		matchText(f.Variety, fruit.Variety) &&
+		matchText(f.Classification, fruit.Classification) &&
+		matchText(f.Vault, fruit.Vault) &&
+		f.matchYear(fruit.Year) &&
+		f.matchPrice(fruit.Price)
+}
+
+// HasConditions checks if the filter has conditions besides its ids, without
+// them every fruit matches it.
+func (f FruitFilter) HasConditions() bool {
+	return f.Country != "" || f.Province != "" || f.Region != "" ||
+		f.Variety != "" || f.Classification != "" || f.Vault != "" ||
+		f.MinYear != nil || f.MaxYear != nil || f.MinPrice != nil || f.MaxPrice != nil
+}
+
+func (f FruitFilter) matchYear(year int) bool {
+	if f.MinYear != nil && year < *f.MinYear {
+		return false
+	}
+
+	if f.MaxYear != nil && year > *f.MaxYear {
+		return false
+	}
+
+	return true
+}
+
+func (f FruitFilter) matchPrice(price *float32) bool {
+	if f.MinPrice == nil && f.MaxPrice == nil {
+		return true
+	}
+
+	if price == nil {
+		return false
+	}
+
+	if f.MinPrice != nil && *price < *f.MinPrice {
+		return false
+	}
+
+	if f.MaxPrice != nil && *price > *f.MaxPrice {
+		return false
+	}
+
+	return true
+}
+
+func matchText(filter, value string) bool {
+	return filter == "" || filter == value
+}
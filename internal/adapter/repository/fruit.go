@@ -1,5 +1,26 @@
 package repository
 
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrFruitNotFound is returned when the fruit to change doesn't exist.
+	ErrFruitNotFound = errors.New("fruit not found")
+	// ErrVersionConflict is returned when the fruit to change has a different version than expected.
+	ErrVersionConflict = errors.New("fruit version conflict")
+	// ErrInvalidCursor is returned when a search cursor wasn't created by the backend.
+	ErrInvalidCursor = errors.New("invalid search cursor")
+	// ErrFruitExists is returned when a new fruit has the id of a stored one.
+	ErrFruitExists = errors.New("fruit already exists")
+)
+
+// FirstVersion is the version of a fruit when it is created.
+const FirstVersion int64 = 1
+
 // FruitID is the fruit identification type.
 type FruitID string
 
@@ -19,10 +40,17 @@ type Fruit struct {
 	Classification string   `json:"classification"`
 	LocalName      string   `json:"local_name"`
 	WikiPage       string   `json:"wiki_page,omitempty"`
+	// Version is increased every time the fruit changes.
+	Version int64 `json:"version"`
+	// LastModified is when the fruit was created or last changed, repositories
+	// set it when a fruit is saved and keep the given one on updates.
+	LastModified time.Time `json:"last_modified"`
 }
 
 // NewFruit contains data to create a new fruit.
 type NewFruit struct {
+	// ID is the id the fruit is stored with, a new one is generated if it is empty.
+	ID             FruitID  `json:"-"`
 	Name           string   `json:"name"`
 	Variety        string   `json:"variety"`
 	Year           int      `json:"year"`
@@ -38,20 +66,70 @@ type NewFruit struct {
 	WikiPage       string   `json:"wiki_page,omitempty"`
 }
 
+// SaveResult contains the outcome of saving one fruit of a batch, Err is set
+// if the fruit was not saved.
+type SaveResult struct {
+	ID  FruitID
+	Err error
+}
+
 // FindFruitsResult contains the list of fruits found plus some metadata.
 type FindFruitsResult struct {
 	Fruits []Fruit
 	Total  int
 	Start  int
 	Count  int
+	// Next is the cursor to get the next page, nil if this is the last one.
+	Next *Cursor
+	// Facets are the fruits that match the filter counted by the filter facets.
+	Facets Facets
 }
 
-// FruitFilter contains filters to search fruits.
+// FruitPage is a page of every stored fruit.
+type FruitPage struct {
+	Fruits []Fruit
+	// Next is the cursor to get the next page, nil if this is the last one.
+	Next *Cursor
+}
+
+// FruitFilter contains filters to search fruits. Empty text filters and
+// nil ranges match every fruit.
 type FruitFilter struct {
 	// Start record to query
 	Start int
 	// rows to return
 	Count int
+	// exact match filters
+	Country        string
+	Province       string
+	Region         string
+	Variety        string
+	Classification string
+	Vault          string
+	// inclusive ranges
+	MinYear  *int
+	MaxYear  *int
+	MinPrice *float32
+	MaxPrice *float32
+	// Sort fields to sort fruits by, in order of precedence.
+	Sort []SortField
+	// After is the cursor of the previous page, if it is present Start is ignored.
+	After *Cursor
+	// IDs restricts the search to the fruits with these ids, nil means every
+	// fruit. Without sort fields the fruits are returned in the IDs order.
+	// These searches are paged by Start only, they cannot be combined with
+	// After and their result has no Next cursor.
+	IDs []FruitID
+	// Facets are the fields to count the fruits that match the filter by,
+	// every matching fruit is counted, not only the ones of the page.
+	Facets []string
+}
+
+// TextMatch is a fruit found by a full-text search.
+type TextMatch struct {
+	ID FruitID
+	// Score is the relevance of the fruit for the search, higher is more relevant.
+	Score float64
 }
 
 // FruitDatasetStatus contains data for dataset status.
@@ -62,10 +140,12 @@ type FruitDatasetStatus struct {
 
 // NewFruitEvent contains data for new fruit events.
 type NewFruitEvent struct {
-	SourceID string  `json:"source_id"`
-	Name     string  `json:"name"`
-	Variety  string  `json:"variety"`
-	Price    float32 `json:"price"`
+	SourceID string `json:"source_id"`
+	// Version is the version of the new fruit.
+	Version int64   `json:"version"`
+	Name    string  `json:"name"`
+	Variety string  `json:"variety"`
+	Price   float32 `json:"price"`
 }
 
 // FruitPrice returns a pointer to the int value passed in.
@@ -87,6 +167,15 @@ func FruitPriceValue(v *float32) float32 {
 	return *v
 }
 
+// NewFruitID returns the id of the given new fruit or a new one if it has none.
+func NewFruitID(fruit NewFruit) FruitID {
+	if fruit.ID != "" {
+		return fruit.ID
+	}
+
+	return FruitID(uuid.New().String())
+}
+
 // FruitIDValue returns the value of the fruit id value as a int64.
 func FruitIDValue(v FruitID) string {
 	return string(v)
@@ -109,5 +198,6 @@ func (u NewFruit) ToFruit(fruitID FruitID) Fruit {
 		Classification: u.Classification,
 		LocalName:      u.LocalName,
 		WikiPage:       u.WikiPage,
+		Version:        FirstVersion,
 	}
 }
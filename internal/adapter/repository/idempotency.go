@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrIdempotencyKeyLost is returned when a key is no longer reserved by the
+// request that reserved it, because it expired or another request took it.
+var ErrIdempotencyKeyLost = errors.New("idempotency key is no longer reserved")
+
+// IdempotencyRecord contains what was done for the request sent with an idempotency key.
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies the content of the request sent with the key.
+	Fingerprint string
+	// FruitID is the fruit created by the request, empty while it is running.
+	FruitID FruitID
+	// ExpiresAt is the moment the key is forgotten.
+	ExpiresAt time.Time
+}
+
+// Expired checks if the record was already forgotten at the given moment.
+func (i IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+// Holds checks if the record is still the given reservation at the given
+// moment: same request, same lease, not completed and not expired.
+func (i IdempotencyRecord) Holds(reservation IdempotencyRecord, now time.Time) bool {
+	return i.Fingerprint == reservation.Fingerprint &&
+		i.ExpiresAt.Equal(reservation.ExpiresAt) &&
+		i.FruitID == "" &&
+		!i.Expired(now)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"time"
+)
+
+// OutboxEvent is an event stored in the same write as the fruit change it
+// announces, it is kept until it is published.
+type OutboxEvent struct {
+	Event Event
+	// Attempts is the number of failed attempts to publish the event.
+	Attempts int
+	// NextAttemptAt is the earliest moment the event is published again.
+	NextAttemptAt time.Time
+	// LastError is the reason of the last failed attempt.
+	LastError string
+}
+
+// NewOutboxEvent returns the outbox entry of the event, it is due as soon as
+// the change it announces is stored.
+func NewOutboxEvent(event Event) OutboxEvent {
+	return OutboxEvent{
+		Event:         event,
+		NextAttemptAt: event.OccurredAt,
+	}
+}
+
+// ID returns the id of the event.
+func (o OutboxEvent) ID() string {
+	return o.Event.ID
+}
+
+// Due checks if the event must be published at the given moment.
+func (o OutboxEvent) Due(now time.Time) bool {
+	return !now.Before(o.NextAttemptAt)
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+)
+
+// Fields fruits can be sorted by.
+const (
+	SortByName           = "name"
+	SortByVariety        = "variety"
+	SortByYear           = "year"
+	SortByPrice          = "price"
+	SortByVault          = "vault"
+	SortByCountry        = "country"
+	SortByProvince       = "province"
+	SortByRegion         = "region"
+	SortByClassification = "classification"
+)
+
+// SortField defines a field to sort fruits by.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// fruitComparators compare two fruits by a field, they return a negative
+// number if a goes before b, zero if they are equal and a positive number otherwise.
+var fruitComparators = map[string]func(a, b *Fruit) int{
+	SortByName:           func(a, b *Fruit) int { return strings.Compare(a.Name, b.Name) },
+	SortByVariety:        func(a, b *Fruit) int { return strings.Compare(a.Variety, b.Variety) },
+	SortByYear:           func(a, b *Fruit) int { return a.Year - b.Year },
+	SortByPrice:          comparePrice,
+	SortByVault:          func(a, b *Fruit) int { return strings.Compare(a.Vault, b.Vault) },
+	SortByCountry:        func(a, b *Fruit) int { return strings.Compare(a.Country, b.Country) },
+	SortByProvince:       func(a, b *Fruit) int { return strings.Compare(a.Province, b.Province) },
+	SortByRegion:         func(a, b *Fruit) int { return strings.Compare(a.Region, b.Region) },
+	SortByClassification: func(a, b *Fruit) int { return strings.Compare(a.Classification, b.Classification) },
+}
+
+// IsSortable checks if fruits can be sorted by the given field.
+func IsSortable(field string) bool {
+	_, ok := fruitComparators[field]
+
+	return ok
+}
+
+// SortFruits sorts the given fruits by the given fields. Fruits that are
+// equal on every field are sorted by id, so the order is the same on every
+// call and paging doesn't skip or repeat fruits. Unknown fields are ignored.
+func SortFruits(fruits []Fruit, fields []SortField) {
+	sort.SliceStable(fruits, func(i, j int) bool {
+		return compareFruits(&fruits[i], &fruits[j], fields) < 0
+	})
+}
+
+func compareFruits(a, b *Fruit, fields []SortField) int {
+	for _, field := range fields {
+		compare, ok := fruitComparators[field.Field]
+		if !ok {
+			continue
+		}
+
+		if field.Field == SortByPrice && (a.Price == nil) != (b.Price == nil) {
+			// fruits without price go last in both directions.
+			return compare(a, b)
+		}
+
+		result := compare(a, b)
+		if result == 0 {
+			continue
+		}
+
+		if field.Descending {
+			return -result
+		}
+
+		return result
+	}
+
+	return strings.Compare(string(a.ID), string(b.ID))
+}
+
+// comparePrice compares fruit prices, fruits without price go last.
+func comparePrice(a, b *Fruit) int {
+	switch {
+	case a.Price == nil && b.Price == nil:
+		return 0
+	case a.Price == nil:
+		return 1
+	case b.Price == nil:
+		return -1
+	case *a.Price < *b.Price:
+		return -1
+	case *a.Price > *b.Price:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,36 @@
+package textindex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common english words that are not indexed.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// analyze splits the given text in lower case words, drops stop words and
+// returns the stem of the rest.
+func analyze(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if stopWords[word] {
+			continue
+		}
+
+		terms = append(terms, stem(word))
+	}
+
+	return terms
+}
@@ -0,0 +1,319 @@
+package textindex
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// Field weights, a word in the name is more relevant than in the description.
+const (
+	nameWeight        = 3
+	localNameWeight   = 2
+	descriptionWeight = 1
+)
+
+// BM25 scoring parameters.
+const (
+	termSaturation   = 1.2
+	lengthNormalizer = 0.75
+)
+
+// document contains the indexed data of a fruit.
+type document struct {
+	version int64
+	// frequencies weighted frequency of every term of the fruit.
+	frequencies map[string]float64
+	length      float64
+}
+
+// errRebuildRunning is returned when the index is rebuilt while another rebuild runs.
+var errRebuildRunning = errors.New("text index is already being rebuilt")
+
+// Index is an in-memory inverted index of fruit names, local names and
+// descriptions. It is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	current *contents
+	// next is the index being rebuilt, nil if no rebuild runs.
+	next *contents
+	// removed are the fruits removed while the index is rebuilt, a rebuild
+	// that read them before they were removed doesn't add them again.
+	removed map[repository.FruitID]struct{}
+}
+
+// contents are the indexed fruits.
+type contents struct {
+	documents map[repository.FruitID]document
+	// postings has the fruits that contain each term.
+	postings    map[string]map[repository.FruitID]struct{}
+	totalLength float64
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{
+		current: newContents(),
+	}
+}
+
+func newContents() *contents {
+	return &contents{
+		documents: make(map[repository.FruitID]document),
+		postings:  make(map[string]map[repository.FruitID]struct{}),
+	}
+}
+
+// Index adds the given fruit to the index or replaces it if it was already
+// indexed. Fruits older than the indexed version are ignored, so a rebuild
+// running along with updates doesn't bring stale data back.
+func (i *Index) Index(fruit repository.Fruit) {
+	newDocument := newFruitDocument(fruit)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.current.add(fruit.ID, newDocument)
+
+	if i.next != nil {
+		i.next.add(fruit.ID, newDocument)
+	}
+}
+
+// Remove removes the fruit with the given id from the index.
+func (i *Index) Remove(fruitID repository.FruitID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.current.remove(fruitID)
+
+	if i.next != nil {
+		i.next.remove(fruitID)
+		i.removed[fruitID] = struct{}{}
+	}
+}
+
+// Rebuild fills a fresh index with the fruits load indexes and replaces
+// this one with it once load succeeds, the index keeps answering searches
+// meanwhile. Fruits changed or removed while load runs are changed or
+// removed in the fresh index too, so a fruit load read before it was
+// removed is not found again.
+func (i *Index) Rebuild(load func(index func(fruit repository.Fruit)) error) error {
+	i.mu.Lock()
+
+	if i.next != nil {
+		i.mu.Unlock()
+
+		return errRebuildRunning
+	}
+
+	i.next = newContents()
+	i.removed = make(map[repository.FruitID]struct{})
+	i.mu.Unlock()
+
+	err := load(i.indexRebuilt)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err == nil {
+		i.current = i.next
+	}
+
+	i.next = nil
+	i.removed = nil
+
+	return err
+}
+
+// indexRebuilt adds a fruit read by the rebuild to the fresh index.
+func (i *Index) indexRebuilt(fruit repository.Fruit) {
+	newDocument := newFruitDocument(fruit)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, removed := i.removed[fruit.ID]; removed || i.next == nil {
+		return
+	}
+
+	i.next.add(fruit.ID, newDocument)
+}
+
+// Search returns the fruits that contain every word of the query, the most
+// relevant first. Fruits with the same score are sorted by id.
+func (i *Index) Search(query string) []repository.TextMatch {
+	terms := uniqueTerms(analyze(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.current.search(terms)
+}
+
+// Size returns the number of fruits indexed.
+func (i *Index) Size() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.current.documents)
+}
+
+// newFruitDocument returns the indexed data of the given fruit.
+func newFruitDocument(fruit repository.Fruit) document {
+	newDocument := document{
+		version:     fruit.Version,
+		frequencies: make(map[string]float64),
+	}
+
+	addTerms(&newDocument, fruit.Name, nameWeight)
+	addTerms(&newDocument, fruit.LocalName, localNameWeight)
+	addTerms(&newDocument, fruit.Description, descriptionWeight)
+
+	return newDocument
+}
+
+// add adds or replaces the document of the fruit unless the indexed one is newer.
+func (c *contents) add(fruitID repository.FruitID, newDocument document) {
+	if indexed, ok := c.documents[fruitID]; ok {
+		if indexed.version > newDocument.version {
+			return
+		}
+
+		c.remove(fruitID)
+	}
+
+	c.documents[fruitID] = newDocument
+	c.totalLength += newDocument.length
+
+	for term := range newDocument.frequencies {
+		fruits, ok := c.postings[term]
+		if !ok {
+			fruits = make(map[repository.FruitID]struct{})
+			c.postings[term] = fruits
+		}
+
+		fruits[fruitID] = struct{}{}
+	}
+}
+
+// remove removes the document of the fruit if it is indexed.
+func (c *contents) remove(fruitID repository.FruitID) {
+	indexed, ok := c.documents[fruitID]
+	if !ok {
+		return
+	}
+
+	for term := range indexed.frequencies {
+		delete(c.postings[term], fruitID)
+
+		if len(c.postings[term]) == 0 {
+			delete(c.postings, term)
+		}
+	}
+
+	c.totalLength -= indexed.length
+	delete(c.documents, fruitID)
+}
+
+// search returns the fruits that contain every term sorted by relevance.
+func (c *contents) search(terms []string) []repository.TextMatch {
+	candidates := c.candidates(terms)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	averageLength := c.totalLength / float64(len(c.documents))
+	matches := make([]repository.TextMatch, 0, len(candidates))
+
+	for _, fruitID := range candidates {
+		matches = append(matches, repository.TextMatch{
+			ID:    fruitID,
+			Score: c.score(c.documents[fruitID], terms, averageLength),
+		})
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+
+		return matches[a].ID < matches[b].ID
+	})
+
+	return matches
+}
+
+// candidates returns the fruits that contain every term.
+func (c *contents) candidates(terms []string) []repository.FruitID {
+	// start with the rarest term to check as few fruits as possible.
+	sort.Slice(terms, func(a, b int) bool {
+		return len(c.postings[terms[a]]) < len(c.postings[terms[b]])
+	})
+
+	candidates := make([]repository.FruitID, 0, len(c.postings[terms[0]]))
+
+	for fruitID := range c.postings[terms[0]] {
+		if c.containsAll(fruitID, terms[1:]) {
+			candidates = append(candidates, fruitID)
+		}
+	}
+
+	return candidates
+}
+
+func (c *contents) containsAll(fruitID repository.FruitID, terms []string) bool {
+	for _, term := range terms {
+		if _, ok := c.postings[term][fruitID]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// score computes the BM25 relevance of the given document for the given terms.
+func (c *contents) score(indexed document, terms []string, averageLength float64) float64 {
+	var score float64
+
+	documentCount := float64(len(c.documents))
+
+	for _, term := range terms {
+		frequency := indexed.frequencies[term]
+		matchingDocuments := float64(len(c.postings[term]))
+		inverseFrequency := math.Log(1 + (documentCount-matchingDocuments+0.5)/(matchingDocuments+0.5))
+		normalizedLength := 1 - lengthNormalizer + lengthNormalizer*indexed.length/averageLength
+
+		score += inverseFrequency * frequency * (termSaturation + 1) / (frequency + termSaturation*normalizedLength)
+	}
+
+	return score
+}
+
+// addTerms adds the terms of the given text to the document with the given weight.
+func addTerms(indexed *document, text string, weight float64) {
+	for _, term := range analyze(text) {
+		indexed.frequencies[term] += weight
+		indexed.length += weight
+	}
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,202 @@
+package textindex_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/adapter/textindex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query   string
+		wantIDs []repository.FruitID
+	}{
+		"single_word": {
+			query:   "Etna",
+			wantIDs: []repository.FruitID{"3", "1"},
+		},
+		"every_word_must_match": {
+			query:   "brisk acidity",
+			wantIDs: []repository.FruitID{"1"},
+		},
+		"case_and_punctuation_are_ignored": {
+			query:   "RIPE, fruity!",
+			wantIDs: []repository.FruitID{"2"},
+		},
+		"plurals_and_inflections_match": {
+			query:   "tannins aged",
+			wantIDs: []repository.FruitID{"2"},
+		},
+		"local_name": {
+			query:   "voss",
+			wantIDs: []repository.FruitID{"2"},
+		},
+		"stop_words_are_ignored": {
+			query:   "the acidity of",
+			wantIDs: []repository.FruitID{"1"},
+		},
+		"only_stop_words": {
+			query:   "the of",
+			wantIDs: nil,
+		},
+		"no_match": {
+			query:   "sparkling",
+			wantIDs: nil,
+		},
+	}
+
+	index := newIndex()
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			got := index.Search(test.query)
+
+			assert.Equal(st, test.wantIDs, matchIDs(got))
+		})
+	}
+}
+
+func TestSearchRanksNameMatchesFirst(t *testing.T) {
+	t.Parallel()
+
+	index := textindex.New()
+	index.Index(repository.Fruit{ID: "1", Name: "Rainstorm", Description: "notes of Etna ash"})
+	index.Index(repository.Fruit{ID: "2", Name: "Nicosia Etna"})
+	index.Index(repository.Fruit{ID: "3", Name: "Tasca", Description: "etna"})
+
+	got := index.Search("etna")
+
+	assert.Equal(t, []repository.FruitID{"2", "3", "1"}, matchIDs(got))
+	assert.Greater(t, got[0].Score, got[1].Score)
+	assert.Greater(t, got[1].Score, got[2].Score)
+}
+
+func TestIndexReplacesAndRemoves(t *testing.T) {
+	t.Parallel()
+
+	index := textindex.New()
+	index.Index(repository.Fruit{ID: "1", Name: "Nicosia Etna", Version: 1})
+
+	index.Index(repository.Fruit{ID: "1", Name: "Nicosia Vulka", Version: 2})
+	assert.Empty(t, index.Search("etna"))
+	assert.Len(t, index.Search("vulka"), 1)
+
+	// an older version read by a rebuild must not replace the newer one.
+	index.Index(repository.Fruit{ID: "1", Name: "Nicosia Etna", Version: 1})
+	assert.Empty(t, index.Search("etna"))
+
+	index.Remove("1")
+	assert.Empty(t, index.Search("vulka"))
+	assert.Equal(t, 0, index.Size())
+}
+
+func TestRebuildKeepsChangesMadeMeanwhile(t *testing.T) {
+	t.Parallel()
+
+	index := textindex.New()
+	index.Index(repository.Fruit{ID: "1", Name: "Nicosia Etna", Version: 1})
+	index.Index(repository.Fruit{ID: "2", Name: "Tasca Etna", Version: 1})
+	index.Index(repository.Fruit{ID: "3", Name: "Rainstorm Etna", Version: 1})
+
+	err := index.Rebuild(func(add func(fruit repository.Fruit)) error {
+		// the rebuild read a page before these changes happened.
+		page := []repository.Fruit{
+			{ID: "1", Name: "Nicosia Etna", Version: 1},
+			{ID: "2", Name: "Tasca Etna", Version: 1},
+		}
+
+		index.Remove("1")
+		index.Index(repository.Fruit{ID: "2", Name: "Tasca Vulka", Version: 2})
+		index.Index(repository.Fruit{ID: "4", Name: "Quinta Etna", Version: 1})
+
+		// the old index answers while the rebuild runs.
+		assert.Equal(t, []repository.FruitID{"3", "4"}, matchIDs(index.Search("etna")))
+
+		for _, fruit := range page {
+			add(fruit)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	// fruit 3 was not read by the rebuild, so it is no longer stored.
+	assert.Equal(t, []repository.FruitID{"4"}, matchIDs(index.Search("etna")))
+	assert.Equal(t, []repository.FruitID{"2"}, matchIDs(index.Search("vulka")))
+	assert.Equal(t, 2, index.Size())
+}
+
+func TestFailedRebuildKeepsIndex(t *testing.T) {
+	t.Parallel()
+
+	index := textindex.New()
+	index.Index(repository.Fruit{ID: "1", Name: "Nicosia Etna", Version: 1})
+
+	err := index.Rebuild(func(add func(fruit repository.Fruit)) error {
+		add(repository.Fruit{ID: "2", Name: "Tasca Etna", Version: 1})
+
+		return errors.New("any error")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, []repository.FruitID{"1"}, matchIDs(index.Search("etna")))
+}
+
+func TestRebuildWhileAnotherRebuildRuns(t *testing.T) {
+	t.Parallel()
+
+	index := textindex.New()
+
+	err := index.Rebuild(func(add func(fruit repository.Fruit)) error {
+		return index.Rebuild(func(add func(fruit repository.Fruit)) error {
+			return nil
+		})
+	})
+
+	assert.Error(t, err)
+}
+
+func newIndex() *textindex.Index {
+	index := textindex.New()
+	index.Index(repository.Fruit{
+		ID:          "1",
+		Name:        "Nicosia 2013 Vulka Bianco (Etna)",
+		LocalName:   "Kerin OKeefe",
+		Description: "Aromas include tropical fruit, broom, brimstone and dried herb. The palate isn't overly expressive, offering unripened apple, citrus and dried sage alongside brisk acidity.",
+	})
+	index.Index(repository.Fruit{
+		ID:          "2",
+		Name:        "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+		LocalName:   "Roger Voss",
+		Description: "This is ripe and fruity, a wine that is smooth while still structured. Firm tannin is filled out with juicy red berry fruits, ageing well.",
+	})
+	index.Index(repository.Fruit{
+		ID:          "3",
+		Name:        "Tasca 2014 Etna Rosso",
+		LocalName:   "Kerin OKeefe",
+		Description: "Volcanic notes and a soft acid.",
+	})
+
+	return index
+}
+
+func matchIDs(matches []repository.TextMatch) []repository.FruitID {
+	if matches == nil {
+		return nil
+	}
+
+	ids := make([]repository.FruitID, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+
+	return ids
+}
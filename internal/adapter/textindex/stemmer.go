@@ -0,0 +1,160 @@
+package textindex
+
+import "strings"
+
+// stem returns the stem of the given lower case word. It implements the
+// steps of the Porter stemming algorithm that remove inflections like plurals,
+// -ed and -ing, so "wines", "aged" and "ageing" match "wine" and "age".
+// Words that are not plain ascii are returned as they are.
+func stem(word string) string {
+	if len(word) <= 2 || !isASCIIWord(word) {
+		return word
+	}
+
+	word = stemPlural(word)
+	word = stemPastAndGerund(word)
+
+	if strings.HasSuffix(word, "y") && hasVowel(word[:len(word)-1]) {
+		word = word[:len(word)-1] + "i"
+	}
+
+	return stemFinalE(word)
+}
+
+// stemFinalE is the step 5 of the Porter algorithm.
+func stemFinalE(word string) string {
+	if strings.HasSuffix(word, "e") {
+		base := word[:len(word)-1]
+		if measure(base) > 1 || (measure(base) == 1 && !endsWithCVC(base)) {
+			return base
+		}
+	}
+
+	if strings.HasSuffix(word, "ll") && measure(word) > 1 {
+		return word[:len(word)-1]
+	}
+
+	return word
+}
+
+// stemPlural is the step 1a of the Porter algorithm.
+func stemPlural(word string) string {
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ies"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
+
+// stemPastAndGerund is the step 1b of the Porter algorithm.
+func stemPastAndGerund(word string) string {
+	if strings.HasSuffix(word, "eed") {
+		if measure(word[:len(word)-3]) > 0 {
+			return word[:len(word)-1]
+		}
+
+		return word
+	}
+
+	var base string
+
+	switch {
+	case strings.HasSuffix(word, "ed") && hasVowel(word[:len(word)-2]):
+		base = word[:len(word)-2]
+	case strings.HasSuffix(word, "ing") && hasVowel(word[:len(word)-3]):
+		base = word[:len(word)-3]
+	default:
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(base, "at"), strings.HasSuffix(base, "bl"), strings.HasSuffix(base, "iz"):
+		return base + "e"
+	case endsWithDoubleConsonant(base) && !strings.HasSuffix(base, "l") &&
+		!strings.HasSuffix(base, "s") && !strings.HasSuffix(base, "z"):
+		return base[:len(base)-1]
+	case measure(base) == 1 && endsWithCVC(base):
+		return base + "e"
+	default:
+		return base
+	}
+}
+
+// isConsonant checks if the letter in the given position is a consonant,
+// y is a consonant at the start or after a vowel.
+func isConsonant(word string, index int) bool {
+	switch word[index] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return index == 0 || !isConsonant(word, index-1)
+	default:
+		return true
+	}
+}
+
+// measure counts the vowel-consonant sequences of the given word.
+func measure(word string) int {
+	count := 0
+	previousIsVowel := false
+
+	for index := range word {
+		consonant := isConsonant(word, index)
+		if consonant && previousIsVowel {
+			count++
+		}
+
+		previousIsVowel = !consonant
+	}
+
+	return count
+}
+
+func hasVowel(word string) bool {
+	for index := range word {
+		if !isConsonant(word, index) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func endsWithDoubleConsonant(word string) bool {
+	last := len(word) - 1
+
+	return last > 0 && word[last] == word[last-1] && isConsonant(word, last)
+}
+
+// endsWithCVC checks if the word ends with consonant, vowel and a consonant
+// that is not w, x or y, e.g. hop.
+func endsWithCVC(word string) bool {
+	last := len(word) - 1
+	if last < 2 {
+		return false
+	}
+
+	switch word[last] {
+	case 'w', 'x', 'y':
+		return false
+	}
+
+	return isConsonant(word, last) && !isConsonant(word, last-1) && isConsonant(word, last-2)
+}
+
+func isASCIIWord(word string) bool {
+	for index := 0; index < len(word); index++ {
+		if word[index] < 'a' || word[index] > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,41 +2,47 @@ package topic
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/fernandoocampo/fruits/internal/adapter/cloudevents"
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
 	"github.com/fernandoocampo/fruits/internal/adapter/repository"
 )
 
-const fruitsTopic = "arn:aws:sns:us-east-1:000000000000:fruits"
-
 var (
 	errLoadingAWSConfig = errors.New("unable to load aws config")
 	errCreatingDynamodb = errors.New("unable to connect to DynamoDB")
 	errPublishingFruit  = errors.New("unable to publish new fruit")
 )
 
-// Setup contains dynamodb settings.
+// Setup contains sns settings.
 type Setup struct {
 	Logger   *loggers.Logger
 	Region   string
 	Endpoint string
+	// TopicARN is the arn of the topic the events are published to.
+	TopicARN string
+	// Source is the CloudEvents source of the published events.
+	Source string
 }
 
 // SNS defines logic for sns.
 type SNS struct {
-	client *sns.Client
-	logger *loggers.Logger
+	client   *sns.Client
+	logger   *loggers.Logger
+	topicARN string
+	source   string
 }
 
 func NewSNSClient(ctx context.Context, setup Setup) (*SNS, error) {
 	newsns := new(SNS)
 	newsns.logger = setup.Logger
+	newsns.topicARN = setup.TopicARN
+	newsns.source = setup.Source
 
 	awsconfig, err := newsns.getConfig(ctx, setup.Region, setup.Endpoint)
 	if err != nil {
@@ -78,17 +84,18 @@ func (s *SNS) getConfig(ctx context.Context, region, endpoint string) (aws.Confi
 	return cfg, nil
 }
 
-func (s *SNS) Publish(ctx context.Context, fruit repository.NewFruitEvent) error {
-	message, err := json.Marshal(fruit)
+// Publish publishes the event as a structured JSON CloudEvent.
+func (s *SNS) Publish(ctx context.Context, event repository.Event) error {
+	message, err := cloudevents.Marshal(event, s.source)
 	if err != nil {
-		s.logger.Error("unable to marshal fruit message", loggers.Fields{"error": err})
+		s.logger.Error("unable to marshal fruit message", loggers.Fields{"event": event.ID, "error": err})
 
 		return errPublishingFruit
 	}
 
 	input := &sns.PublishInput{
 		Message:  aws.String(string(message)),
-		TopicArn: aws.String(fruitsTopic),
+		TopicArn: aws.String(s.topicARN),
 	}
 
 	result, err := s.client.Publish(ctx, input)
@@ -99,7 +106,7 @@ func (s *SNS) Publish(ctx context.Context, fruit repository.NewFruitEvent) error
 	}
 
 	if result != nil {
-		s.logger.Info("publishing new fruit", loggers.Fields{"result": result.MessageId})
+		s.logger.Info("publishing fruit event", loggers.Fields{"event": event.ID, "result": result.MessageId})
 	}
 
 	return nil
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/fruits"
+)
+
+// defaultCacheControl lets clients store fruit reads but makes them
+// revalidate every time, so changes are seen as soon as they happen.
+const defaultCacheControl = "no-cache"
+
+// contextKeyConditions is the context key of the conditional headers of a request.
+type contextKeyConditions struct{}
+
+// conditions contains the conditional headers of a GET request.
+type conditions struct {
+	ifNoneMatch     string
+	ifModifiedSince string
+}
+
+// validators describe the version of a response, see RFC 7232.
+type validators struct {
+	// etag of the response, if it is empty a weak one is derived from the body.
+	etag string
+	// lastModified is when the response last changed, zero if it is unknown.
+	lastModified time.Time
+	// ignoreModifiedSince answers If-Modified-Since with the full response,
+	// for responses whose lastModified doesn't change on every change.
+	ignoreModifiedSince bool
+}
+
+// bufferedResponse keeps the body written to it, its headers are the ones of the response.
+type bufferedResponse struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+// putConditions puts the conditional headers of the request in the context.
+func putConditions(ctx context.Context, req *http.Request) context.Context {
+	return context.WithValue(ctx, contextKeyConditions{}, conditions{
+		ifNoneMatch:     req.Header.Get("If-None-Match"),
+		ifModifiedSince: req.Header.Get("If-Modified-Since"),
+	})
+}
+
+// writeCacheable writes the response of a GET written by write with its
+// validators and the given Cache-Control. If the client already has this version of the
+// response it is answered with 304 Not Modified and no body.
+func writeCacheable(ctx context.Context, res http.ResponseWriter, cacheControl string, responseValidators validators, write func(res http.ResponseWriter) error) error {
+	buffered := bufferedResponse{ResponseWriter: res}
+
+	err := write(&buffered)
+	if err != nil {
+		return err
+	}
+
+	etag := responseValidators.etag
+	if etag == "" {
+		etag = bodyETag(buffered.body.Bytes())
+	}
+
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Cache-Control", cacheControl)
+
+	if !responseValidators.lastModified.IsZero() {
+		res.Header().Set("Last-Modified", responseValidators.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(ctx, etag, responseValidators) {
+		res.Header().Del("Content-Type")
+		res.WriteHeader(http.StatusNotModified)
+
+		return nil
+	}
+
+	_, err = res.Write(buffered.body.Bytes())
+
+	return err
+}
+
+// isNotModified checks the conditional headers of the request, If-None-Match
+// takes precedence over If-Modified-Since.
+func isNotModified(ctx context.Context, etag string, responseValidators validators) bool {
+	requestConditions, _ := ctx.Value(contextKeyConditions{}).(conditions)
+
+	if requestConditions.ifNoneMatch != "" {
+		return matchesAnyETag(requestConditions.ifNoneMatch, etag)
+	}
+
+	if requestConditions.ifModifiedSince == "" || responseValidators.ignoreModifiedSince || responseValidators.lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(requestConditions.ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	return !responseValidators.lastModified.Truncate(time.Second).After(since)
+}
+
+// matchesAnyETag compares the entity tags of an If-None-Match header with
+// the given one, weak and strong tags with the same value match.
+func matchesAnyETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == anyETag || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bodyETag returns a weak entity tag of the given body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// fruitValidators returns the validators of the given fruit in the
+// representation of the api negotiated for the request, see fruitETag.
+func fruitValidators(ctx context.Context, fruit *fruits.Fruit, api string) validators {
+	if fruit == nil || fruit.Version == 0 {
+		return validators{}
+	}
+
+	return validators{
+		etag:         fruitETag(fruit.Version, api, representationFromContext(ctx).format),
+		lastModified: fruit.LastModified,
+	}
+}
+
+// searchValidators returns the validators of a search page, it was last
+// modified when its newest fruit was. A deleted fruit doesn't change that
+// time, so searches are only revalidated with their etag.
+func searchValidators(result *fruits.SearchFruitsResult) validators {
+	searchValidators := validators{
+		ignoreModifiedSince: true,
+	}
+
+	if result == nil {
+		return searchValidators
+	}
+
+	for _, fruit := range result.Fruits {
+		if fruit.LastModified.After(searchValidators.lastModified) {
+			searchValidators.lastModified = fruit.LastModified
+		}
+	}
+
+	return searchValidators
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errBuildingDeadLettersResponse = errors.New("cannot build dead-lettered events response")
+	errBuildingRedriveResponse     = errors.New("cannot build re-drive response")
+)
+
+// DeadLetterResponse contains data about an event that could not be published.
+type DeadLetterResponse struct {
+	ID             string    `json:"id"`
+	SourceID       string    `json:"source_id"`
+	Name           string    `json:"name"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// addAdminRoutes adds the routes to operate the service, they are not versioned.
+func addAdminRoutes(router *mux.Router, fruitEndpoints fruits.Endpoints, encodeError httptransport.ErrorEncoder, options []httptransport.ServerOption, logger *loggers.Logger) {
+	router.Methods(http.MethodGet).Path("/admin/dead-letters").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.ListDeadLettersEndpoint,
+			makeEmptyDecoder(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeDeadLettersResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPost).Path("/admin/dead-letters/{id}/redrive").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.RedriveDeadLetterEndpoint,
+			makeDecodeRedriveDeadLetterRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeRedriveDeadLetterResponse(logger)),
+			options...),
+	)
+}
+
+// makeDecodeRedriveDeadLetterRequest reads the dead-lettered event id from the request path.
+func makeDecodeRedriveDeadLetterRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		id := mux.Vars(req)["id"]
+
+		logger.Debug(
+			"re-drive dead-lettered event request",
+			loggers.Fields{
+				"method": "decodeRedriveDeadLetterRequest",
+				"id":     id,
+			},
+		)
+
+		return id, nil
+	}
+}
+
+func makeEncodeDeadLettersResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.DeadLettersResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.DeadLettersResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeDeadLettersResponse",
+				},
+			)
+
+			return errBuildingDeadLettersResponse
+		}
+
+		deadLetters := make([]DeadLetterResponse, 0, len(result.DeadLetters))
+
+		for _, deadLetter := range result.DeadLetters {
+			deadLetters = append(deadLetters, DeadLetterResponse(deadLetter))
+		}
+
+		return encodeAdminResult(res, Result{Success: true, Data: deadLetters}, logger)
+	}
+}
+
+func makeEncodeRedriveDeadLetterResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.RedriveDeadLetterResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.RedriveDeadLetterResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeRedriveDeadLetterResponse",
+				},
+			)
+
+			return errBuildingRedriveResponse
+		}
+
+		return encodeAdminResult(res, Result{Success: true, Data: result.ID}, logger)
+	}
+}
+
+func encodeAdminResult(res http.ResponseWriter, message Result, logger *loggers.Logger) error {
+	res.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(res).Encode(message)
+	if err != nil {
+		logger.Error(
+			"cannot encode Result",
+			loggers.Fields{
+				"result": fmt.Sprintf("%+v", message),
+				"method": "encodeAdminResult",
+			},
+		)
+
+		return errEncodingResultResponse
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/memorydb"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAndRedriveDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	topic := &switchablePublisher{}
+	resilient := publishing.NewResilient(
+		topic,
+		publishing.NewFileDeadLetters(filepath.Join(t.TempDir(), "dead-letters.ndjson"), logger),
+		publishing.Setup{Logger: logger, MaxAttempts: 1},
+	)
+	fruitService := fruits.NewService(memorydb.New(memorydb.Setup{Logger: logger}), resilient, logger, fruits.WithDeadLetters(resilient))
+	fruitEndpoints := fruits.NewEndpoints(fruitService, logger)
+
+	err := resilient.Publish(context.TODO(), repository.NewFruitCreated(repository.Fruit{ID: "1", Name: "Mango"}, time.Now()))
+	assert.NoError(t, err)
+
+	response, body := doRequest(t, fruitEndpoints, http.MethodGet, "/admin/dead-letters", nil, "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var listed deadLettersResult
+
+	assert.NoError(t, json.Unmarshal(body, &listed))
+	assert.True(t, listed.Success)
+	assert.Len(t, listed.Data, 1)
+	assert.Equal(t, "1", listed.Data[0].SourceID)
+	assert.Equal(t, "Mango", listed.Data[0].Name)
+	assert.Equal(t, 1, listed.Data[0].Attempts)
+	assert.Equal(t, "topic not available", listed.Data[0].LastError)
+
+	redrivePath := "/admin/dead-letters/" + listed.Data[0].ID + "/redrive"
+
+	response, body = doRequest(t, fruitEndpoints, http.MethodPost, redrivePath, nil, "")
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, "application/problem+json", response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), fruits.ErrRedrivingEvent.Error())
+
+	topic.recover()
+
+	response, body = doRequest(t, fruitEndpoints, http.MethodPost, redrivePath, nil, "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"success":true,"data":"`+listed.Data[0].ID+`","errors":null}`, string(body))
+
+	response, body = doRequest(t, fruitEndpoints, http.MethodGet, "/admin/dead-letters", nil, "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"success":true,"data":[],"errors":null}`, string(body))
+
+	response, _ = doRequest(t, fruitEndpoints, http.MethodPost, redrivePath, nil, "")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestAdminRoutesAreNotVersioned(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{"/v1/admin/dead-letters", "/v2/admin/dead-letters"} {
+		response, _ := doRequest(t, makeMemoryEndpoints(), http.MethodGet, path, nil, "")
+
+		assert.Equal(t, http.StatusNotFound, response.StatusCode, path)
+	}
+}
+
+type deadLettersResult struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		ID             string    `json:"id"`
+		SourceID       string    `json:"source_id"`
+		Name           string    `json:"name"`
+		Attempts       int       `json:"attempts"`
+		LastError      string    `json:"last_error"`
+		DeadLetteredAt time.Time `json:"dead_lettered_at"`
+	} `json:"data"`
+}
+
+// switchablePublisher fails until it recovers.
+type switchablePublisher struct {
+	mu        sync.Mutex
+	available bool
+}
+
+func (s *switchablePublisher) Publish(_ context.Context, _ repository.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.available {
+		return errors.New("topic not available")
+	}
+
+	return nil
+}
+
+func (s *switchablePublisher) recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.available = true
+}
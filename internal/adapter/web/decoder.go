@@ -6,10 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 )
@@ -22,11 +27,42 @@ const (
 var (
 	errFruitIDNoInt         = errors.New("fruit ID must be a valid integer")
 	errDecodingRequest      = errors.New("something went wrong decoding request")
+	errReadingRequest       = errors.New("something went wrong reading request body")
 	errNoFruitIDWasProvided = errors.New("fruit ID was not provided")
+	errIfMatchRequired      = errors.New("If-Match header with the fruit ETag is required")
+	errInvalidIfMatch       = errors.New("If-Match header must be a fruit ETag or *")
+	errWeakIfMatch          = errors.New("If-Match header has a weak ETag, it never matches the fruit")
+	errInvalidIdempotency   = errors.New("Idempotency-Key header must have between 1 and 255 visible ascii characters")
 )
 
+// anyETag If-Match value that matches any version.
+const anyETag = "*"
+
+// ndjsonContentType is the media type of batches sent as one json fruit per line.
+const ndjsonContentType = "application/x-ndjson"
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key header allowed.
+const maxIdempotencyKeyLength = 255
+
+// maxBatchBodySize is the largest batch body read, in bytes.
+const maxBatchBodySize = 8 << 20
+
+// errBatchBodyTooLarge is returned when a batch body has more than maxBatchBodySize bytes.
+var errBatchBodyTooLarge = fmt.Errorf("%w: the body cannot have more than %d bytes", fruits.ErrBatchTooLarge, maxBatchBodySize)
+
+// maxFruitBodySize is the largest body of a request with one fruit, in bytes.
+const maxFruitBodySize = 64 << 10
+
+// errFruitBodyTooLarge is returned when a fruit body has more than maxFruitBodySize bytes.
+var errFruitBodyTooLarge = fmt.Errorf("fruit body cannot have more than %d bytes", maxFruitBodySize)
+
 func makeDecodeGetFruitWithIDRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
 	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		err := checkAcceptable(req.Header.Get("Accept"))
+		if err != nil {
+			return nil, err
+		}
+
 		v := mux.Vars(req)
 
 		fruitID, ok := v["id"]
@@ -51,51 +87,177 @@ func makeDecodeGetFruitWithIDRequest(logger *loggers.Logger) httptransport.Decod
 
 func makeDecodeSearchFruitsRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
 	return func(ctx context.Context, req *http.Request) (interface{}, error) {
-		filterRequest := SearchFruitFilter{
-			Start: startRecordPosition,
-			Count: rowPerPage,
+		err := checkAcceptable(req.Header.Get("Accept"))
+		if err != nil {
+			return nil, err
+		}
+
+		filterRequest, err := readSearchFruitFilter(req.URL.Query())
+		if err != nil {
+			logger.Error(
+				"invalid search parameter",
+				loggers.Fields{
+					"method": "decodeSearchFruitsRequest",
+					"error":  err,
+				},
+			)
+
+			return nil, err
 		}
 
-		filters := req.URL.Query()
+		filter := filterRequest.toSearchFruitFilter()
+
+		return filter, nil
+	}
+}
+
+// readSearchFruitFilter reads the search filters from the query parameters,
+// a parameter that cannot be parsed is reported as a fruits.InvalidFilterError.
+func readSearchFruitFilter(filters url.Values) (SearchFruitFilter, error) {
+	filterRequest := SearchFruitFilter{
+		Start:          startRecordPosition,
+		Count:          rowPerPage,
+		Country:        filters.Get("country"),
+		Province:       filters.Get("province"),
+		Region:         filters.Get("region"),
+		Variety:        filters.Get("variety"),
+		Classification: filters.Get("classification"),
+		Vault:          filters.Get("vault"),
+		Cursor:         filters.Get("cursor"),
+		Query:          strings.TrimSpace(filters.Get("q")),
+	}
+
+	if filters.Has("cursor") && filters.Has("start") {
+		return SearchFruitFilter{}, fruits.InvalidFilterError{Filter: "cursor", Reason: "cannot be combined with start"}
+	}
+
+	start, err := readIntParameter(filters, "start")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	if start != nil {
+		filterRequest.Start = *start
+	}
+
+	count, err := readIntParameter(filters, "count")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	if count != nil {
+		filterRequest.Count = *count
+	}
+
+	filterRequest.MinYear, err = readIntParameter(filters, "min_year")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	filterRequest.MaxYear, err = readIntParameter(filters, "max_year")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	filterRequest.MinPrice, err = readPriceParameter(filters, "min_price")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	filterRequest.MaxPrice, err = readPriceParameter(filters, "max_price")
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	filterRequest.Sort, err = readSortParameter(filters)
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	filterRequest.Facets, err = readFacetsParameter(filters)
+	if err != nil {
+		return SearchFruitFilter{}, err
+	}
+
+	return filterRequest, nil
+}
 
-		if v, ok := filters["start"]; ok {
-			start, err := strconv.Atoi(v[0])
-			if err != nil {
-				logger.Error(
-					"invalid page parameter, must be an integer",
-					loggers.Fields{
-						"method": "decodeSearchFruitsRequest",
-						"error":  err,
-					},
-				)
+// readSortParameter reads the comma separated sort fields, fields with a
+// leading - are sorted in descending order, e.g. sort=-price,name.
+func readSortParameter(filters url.Values) ([]fruits.SortField, error) {
+	if !filters.Has("sort") {
+		return nil, nil
+	}
 
-				start = 1
-			}
+	names := strings.Split(filters.Get("sort"), ",")
+	sortFields := make([]fruits.SortField, 0, len(names))
 
-			filterRequest.Start = start
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		sortField := fruits.SortField{
+			Name:       strings.TrimPrefix(name, "-"),
+			Descending: strings.HasPrefix(name, "-"),
 		}
 
-		if v, ok := filters["count"]; ok {
-			count, err := strconv.Atoi(v[0])
-			if err != nil {
-				logger.Error(
-					"invalid page size parameter, must be an integer",
-					loggers.Fields{
-						"method": "decodeSearchFruitsRequest",
-						"error":  err,
-					},
-				)
+		if sortField.Name == "" {
+			return nil, fruits.InvalidFilterError{Filter: "sort", Reason: "field names must not be empty"}
+		}
+
+		sortFields = append(sortFields, sortField)
+	}
+
+	return sortFields, nil
+}
+
+// readFacetsParameter reads the comma separated facet fields, e.g. facets=country,variety.
+func readFacetsParameter(filters url.Values) ([]string, error) {
+	if !filters.Has("facets") {
+		return nil, nil
+	}
 
-				count = 10
-			}
+	names := strings.Split(filters.Get("facets"), ",")
+	facets := make([]string, 0, len(names))
 
-			filterRequest.Count = count
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fruits.InvalidFilterError{Filter: "facets", Reason: "field names must not be empty"}
 		}
 
-		filter := filterRequest.toSearchFruitFilter()
+		facets = append(facets, name)
+	}
 
-		return filter, nil
+	return facets, nil
+}
+
+// readIntParameter reads the given integer query parameter, it returns nil if it is not present.
+func readIntParameter(filters url.Values, name string) (*int, error) {
+	if !filters.Has(name) {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(filters.Get(name))
+	if err != nil {
+		return nil, fruits.InvalidFilterError{Filter: name, Reason: "must be an integer"}
+	}
+
+	return &value, nil
+}
+
+// readPriceParameter reads the given price query parameter, it returns nil if it is not present.
+func readPriceParameter(filters url.Values, name string) (*float32, error) {
+	if !filters.Has(name) {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(filters.Get(name), 32)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fruits.InvalidFilterError{Filter: name, Reason: "must be a number"}
 	}
+
+	price := float32(value)
+
+	return &price, nil
 }
 
 func makeDecodeCreateFruitRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
@@ -109,38 +271,354 @@ func makeDecodeCreateFruitRequest(logger *loggers.Logger) httptransport.DecodeRe
 
 		defer req.Body.Close()
 
+		if !isValidIdempotencyKey(req.Header.Values("Idempotency-Key")) {
+			return nil, errInvalidIdempotency
+		}
+
 		var newFruitRequest NewFruit
 
-		body, err := io.ReadAll(req.Body)
+		err := decodeFruitBody(req, &newFruitRequest, logger, "decodeCreateFruitRequest")
 		if err != nil {
-			return nil, fmt.Errorf("something went wrong decoding create fruit request: %w", err)
+			return nil, err
+		}
+
+		logger.Debug(
+			"fruit request was decoded",
+			loggers.Fields{
+				"method":  "decodeCreateFruitRequest",
+				"request": newFruitRequest,
+			},
+		)
+
+		domainFruit := newFruitRequest.toFruit()
+
+		return domainFruit, nil
+	}
+}
+
+// makeDecodeCreateBatchRequest decodes a json array of new fruits, or one
+// json fruit per line if the content type is application/x-ndjson.
+func makeDecodeCreateBatchRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		var newFruits []NewFruit
+
+		var err error
+
+		body := newMaxBytesBody(req.Body, maxBatchBodySize)
+		req.Body = body
+
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mediaType == ndjsonContentType {
+			newFruits, err = decodeNDJSONFruits(req, logger)
+		} else {
+			err = decodeJSONBody(req, &newFruits, logger, "decodeCreateBatchRequest")
+		}
+
+		if body.tooLarge {
+			return nil, errBatchBodyTooLarge
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		batchRequest := fruits.CreateBatchRequest{
+			Fruits: make([]fruits.NewFruit, 0, len(newFruits)),
+		}
+
+		for index := range newFruits {
+			batchRequest.Fruits = append(batchRequest.Fruits, *newFruits[index].toFruit())
+		}
+
+		return &batchRequest, nil
+	}
+}
+
+// maxBytesBody is a request body read with http.MaxBytesReader that tells
+// whether the body was larger than the limit.
+type maxBytesBody struct {
+	io.ReadCloser
+	read     int64
+	limit    int64
+	tooLarge bool
+}
+
+func newMaxBytesBody(body io.ReadCloser, limit int64) *maxBytesBody {
+	return &maxBytesBody{
+		ReadCloser: http.MaxBytesReader(nil, body, limit),
+		limit:      limit,
+	}
+}
+
+func (m *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := m.ReadCloser.Read(p)
+	m.read += int64(n)
+
+	if err != nil && !errors.Is(err, io.EOF) && m.read >= m.limit {
+		m.tooLarge = true
+	}
+
+	return n, err
+}
+
+// decodeNDJSONFruits decodes one json fruit per line of the request body, it
+// stops reading as soon as the batch is too large.
+func decodeNDJSONFruits(req *http.Request, logger *loggers.Logger) ([]NewFruit, error) {
+	defer req.Body.Close()
+
+	var newFruits []NewFruit
+
+	decoder := json.NewDecoder(req.Body)
+
+	for line := 1; ; line++ {
+		var newFruit NewFruit
+
+		err := decoder.Decode(&newFruit)
+		if errors.Is(err, io.EOF) {
+			return newFruits, nil
 		}
 
-		err = json.Unmarshal(body, &newFruitRequest)
 		if err != nil {
 			logger.Error(
-				"new fruit request could not be decoded",
+				"batch fruit could not be decoded",
 				loggers.Fields{
-					"method":  "decodeCreateFruitRequest",
-					"request": string(body),
-					"error":   err,
+					"method": "decodeNDJSONFruits",
+					"line":   line,
+					"error":  err,
 				},
 			)
 
-			return nil, errDecodingRequest
+			return nil, fmt.Errorf("%w: fruit %d: %s", errDecodingRequest, line, err)
 		}
 
-		logger.Debug(
-			"fruit request was decoded",
+		if len(newFruits) == fruits.MaxBatchSize {
+			return nil, fruits.ErrBatchTooLarge
+		}
+
+		newFruits = append(newFruits, newFruit)
+	}
+}
+
+func makeDecodeUpdateFruitRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		fruitID, err := readFruitID(req, logger, "decodeUpdateFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		var fruitRequest NewFruit
+
+		err = decodeFruitBody(req, &fruitRequest, logger, "decodeUpdateFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := readIfMatch(req, logger, "decodeUpdateFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		updateRequest := fruits.UpdateFruitRequest{
+			ID:      fruitID,
+			Version: version,
+			Fruit:   *fruitRequest.toFruit(),
+		}
+
+		return &updateRequest, nil
+	}
+}
+
+func makeDecodePatchFruitRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		fruitID, err := readFruitID(req, logger, "decodePatchFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		var patchRequest FruitPatch
+
+		err = decodeFruitBody(req, &patchRequest, logger, "decodePatchFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := readIfMatch(req, logger, "decodePatchFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		request := fruits.PatchFruitRequest{
+			ID:      fruitID,
+			Version: version,
+			Patch:   patchRequest.toFruitPatch(),
+		}
+
+		return &request, nil
+	}
+}
+
+func makeDecodeDeleteFruitRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		fruitID, err := readFruitID(req, logger, "decodeDeleteFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := readIfMatch(req, logger, "decodeDeleteFruitRequest")
+		if err != nil {
+			return nil, err
+		}
+
+		request := fruits.DeleteFruitRequest{
+			ID:      fruitID,
+			Version: version,
+		}
+
+		return &request, nil
+	}
+}
+
+// readIfMatch reads the fruit version expected by the If-Match header,
+// * is read as fruits.AnyVersion. A weak ETag fails the precondition.
+func readIfMatch(req *http.Request, logger *loggers.Logger, method string) (int64, error) {
+	ifMatch := strings.TrimSpace(req.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, errIfMatchRequired
+	}
+
+	if ifMatch == anyETag {
+		return fruits.AnyVersion, nil
+	}
+
+	version, err := parseETag(ifMatch)
+	if errors.Is(err, errWeakETag) {
+		return 0, errWeakIfMatch
+	}
+
+	if err != nil {
+		logger.Error(
+			"invalid If-Match header",
 			loggers.Fields{
-				"method":  "decodeCreateFruitRequest",
-				"request": newFruitRequest,
+				"method":   method,
+				"if-match": ifMatch,
 			},
 		)
 
-		domainFruit := newFruitRequest.toFruit()
+		return 0, errInvalidIfMatch
+	}
 
-		return domainFruit, nil
+	return version, nil
+}
+
+// putIdempotencyKey puts the Idempotency-Key header in the context so the
+// service can detect a repeated request.
+func putIdempotencyKey(ctx context.Context, req *http.Request) context.Context {
+	key := req.Header.Get("Idempotency-Key")
+	if key == "" {
+		return ctx
+	}
+
+	return fruits.ContextWithIdempotencyKey(ctx, key)
+}
+
+// isValidIdempotencyKey checks that the request has at most one
+// Idempotency-Key header made of visible ascii characters.
+func isValidIdempotencyKey(values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	if len(values) > 1 || values[0] == "" || len(values[0]) > maxIdempotencyKeyLength {
+		return false
+	}
+
+	for _, char := range values[0] {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readFruitID reads the fruit id from the request path.
+func readFruitID(req *http.Request, logger *loggers.Logger, method string) (string, error) {
+	fruitID, ok := mux.Vars(req)["id"]
+	if !ok {
+		return "", errNoFruitIDWasProvided
+	}
+
+	if fruitID == "" {
+		logger.Error(
+			"fruit id cannot be empty",
+			loggers.Fields{
+				"method": method,
+			},
+		)
+
+		return "", errFruitIDNoInt
+	}
+
+	return fruitID, nil
+}
+
+// decodeFruitBody decodes the json body of a one fruit request in the given
+// target, bodies larger than maxFruitBodySize are rejected.
+func decodeFruitBody(req *http.Request, target interface{}, logger *loggers.Logger, method string) error {
+	body := newMaxBytesBody(req.Body, maxFruitBodySize)
+	req.Body = body
+
+	err := decodeJSONBody(req, target, logger, method)
+	if body.tooLarge {
+		return errFruitBodyTooLarge
+	}
+
+	return err
+}
+
+// decodeJSONBody decodes the json request body in the given target.
+func decodeJSONBody(req *http.Request, target interface{}, logger *loggers.Logger, method string) error {
+	defer req.Body.Close()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errReadingRequest, err)
+	}
+
+	err = json.Unmarshal(body, target)
+	if err != nil {
+		logger.Error(
+			"request could not be decoded",
+			loggers.Fields{
+				"method":  method,
+				"request": string(body),
+				"error":   err,
+			},
+		)
+
+		return errDecodingRequest
+	}
+
+	return nil
+}
+
+// makeDecodeGetStatusRequest checks that the dataset status can be written as the request accepts.
+func makeDecodeGetStatusRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		err := checkAcceptable(req.Header.Get("Accept"))
+		if err != nil {
+			logger.Debug(
+				"status representation is not acceptable",
+				loggers.Fields{
+					"method": "decodeGetStatusRequest",
+					"accept": req.Header.Get("Accept"),
+				},
+			)
+
+			return nil, err
+		}
+
+		return nil, nil
 	}
 }
 
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
 	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
@@ -17,8 +21,13 @@ var (
 	errBuildingGetFruitResponse    = errors.New("cannot build get fruit response")
 	errBuildingFruitDatasetStatus  = errors.New("cannot build fruit dataset status response")
 	errBuildingCreateFruitResponse = errors.New("cannot build create fruit response")
+	errBuildingCreateBatchResponse = errors.New("cannot build create batch response")
 	errEncodingResultResponse      = errors.New("cannot encode result")
 	errBuildingSearchFruitResponse = errors.New("cannot build search fruits response")
+	errBuildingUpdateFruitResponse = errors.New("cannot build update fruit response")
+	errBuildingDeleteFruitResponse = errors.New("cannot build delete fruit response")
+	errWeakETag                    = errors.New("invalid entity tag: weak tags never match")
+	errUnknownETag                 = errors.New("invalid entity tag: it is not the one of a fruit representation")
 )
 
 func makeEncodeCreateFruitRequest(logger *loggers.Logger) httptransport.EncodeResponseFunc {
@@ -57,7 +66,43 @@ func makeEncodeCreateFruitRequest(logger *loggers.Logger) httptransport.EncodeRe
 	}
 }
 
-func makeEncodeGetFruitWithIDResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+func makeEncodeCreateBatchResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.CreateBatchResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.CreateBatchResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeCreateBatchResponse",
+				},
+			)
+
+			return errBuildingCreateBatchResponse
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+
+		message := toCreateBatchResponse(result)
+
+		err := json.NewEncoder(res).Encode(message)
+		if err != nil {
+			logger.Error(
+				"cannot encode Result",
+				loggers.Fields{
+					"result": fmt.Sprintf("%+v", message),
+					"method": "encodeCreateBatchResponse",
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+func makeEncodeGetFruitWithIDResponse(cacheControl string, logger *loggers.Logger) httptransport.EncodeResponseFunc {
 	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
 		result, ok := response.(fruits.GetFruitWithIDResult)
 		if !ok {
@@ -72,9 +117,48 @@ func makeEncodeGetFruitWithIDResponse(logger *loggers.Logger) httptransport.Enco
 			return errBuildingGetFruitResponse
 		}
 
+		message := toGetFruitWithIDResponse(result)
+
+		err := writeCacheable(ctx, res, cacheControl, fruitValidators(ctx, result.Fruit, apiV1), func(res http.ResponseWriter) error {
+			return writeRepresentation(ctx, res, message, resultXMLRoot, func(w io.Writer) error {
+				return writeFruitCSV(w, result.Fruit)
+			})
+		})
+		if err != nil {
+			logger.Error(
+				"cannot encode Result",
+				loggers.Fields{
+					"result": fmt.Sprintf("%+v", message),
+					"method": "encodeGetFruitWithIDResponse",
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+func makeEncodeUpdateFruitResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.UpdateFruitResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.UpdateFruitResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeUpdateFruitResponse",
+				},
+			)
+
+			return errBuildingUpdateFruitResponse
+		}
+
 		res.Header().Set("Content-Type", "application/json")
+		setFruitETag(res, result.Fruit, apiV1, formatJSON)
 
-		message := toGetFruitWithIDResponse(result)
+		message := toUpdateFruitResponse(result)
 
 		err := json.NewEncoder(res).Encode(message)
 		if err != nil {
@@ -82,7 +166,43 @@ func makeEncodeGetFruitWithIDResponse(logger *loggers.Logger) httptransport.Enco
 				"cannot encode Result",
 				loggers.Fields{
 					"result": fmt.Sprintf("%+v", message),
-					"method": "encodeGetFruitWithIDResponse",
+					"method": "encodeUpdateFruitResponse",
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+func makeEncodeDeleteFruitResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.DeleteFruitResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.DeleteFruitResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeDeleteFruitResponse",
+				},
+			)
+
+			return errBuildingDeleteFruitResponse
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+
+		message := toDeleteFruitResponse(result)
+
+		err := json.NewEncoder(res).Encode(message)
+		if err != nil {
+			logger.Error(
+				"cannot encode Result",
+				loggers.Fields{
+					"result": fmt.Sprintf("%+v", message),
+					"method": "encodeDeleteFruitResponse",
 				},
 			)
 
@@ -93,7 +213,7 @@ func makeEncodeGetFruitWithIDResponse(logger *loggers.Logger) httptransport.Enco
 	}
 }
 
-func makeEncodeSearchFruitsResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+func makeEncodeSearchFruitsResponse(cacheControl string, logger *loggers.Logger) httptransport.EncodeResponseFunc {
 	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
 		result, ok := response.(fruits.SearchFruitsDataResult)
 		if !ok {
@@ -108,11 +228,17 @@ func makeEncodeSearchFruitsResponse(logger *loggers.Logger) httptransport.Encode
 			return errBuildingSearchFruitResponse
 		}
 
-		res.Header().Set("Content-Type", "application/json")
+		if result.SearchResult != nil {
+			setNextLink(ctx, res, result.SearchResult.NextCursor)
+		}
 
 		message := toSearchFruitsResponse(result)
 
-		err := json.NewEncoder(res).Encode(message)
+		err := writeCacheable(ctx, res, cacheControl, searchValidators(result.SearchResult), func(res http.ResponseWriter) error {
+			return writeRepresentation(ctx, res, message, resultXMLRoot, func(w io.Writer) error {
+				return writeSearchCSV(w, result.SearchResult)
+			})
+		})
 		if err != nil {
 			logger.Error(
 				"cannot encode Result",
@@ -144,11 +270,11 @@ func makeEncodeGetStatusResponse(logger *loggers.Logger) httptransport.EncodeRes
 			return errBuildingFruitDatasetStatus
 		}
 
-		res.Header().Set("Content-Type", "application/json")
-
 		message := toFruitDatasetStatusResponse(result)
 
-		err := json.NewEncoder(res).Encode(message)
+		err := writeRepresentation(ctx, res, message, datasetStatusXMLRoot, func(w io.Writer) error {
+			return writeDatasetStatusCSV(w, message)
+		})
 		if err != nil {
 			logger.Error(
 				"cannot encode Result",
@@ -165,6 +291,92 @@ func makeEncodeGetStatusResponse(logger *loggers.Logger) httptransport.EncodeRes
 	}
 }
 
+// makeEncodeFailedResponse encodes responses whose request failed, see endpoint.Failer,
+// with the given error encoder and any other response with the given encoder.
+func makeEncodeFailedResponse(encodeError httptransport.ErrorEncoder, encode httptransport.EncodeResponseFunc) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		if failer, ok := response.(endpoint.Failer); ok && failer.Failed() != nil {
+			encodeError(ctx, failer.Failed(), res)
+
+			return nil
+		}
+
+		return encode(ctx, res, response)
+	}
+}
+
+// APIs whose fruit representations have their own entity tags.
+const (
+	apiV1 = "v1"
+	apiV2 = "v2"
+)
+
+// setFruitETag sets the ETag header of the given fruit in the representation of the api.
+func setFruitETag(res http.ResponseWriter, fruit *fruits.Fruit, api, format string) {
+	if fruit == nil || fruit.Version == 0 {
+		return
+	}
+
+	res.Header().Set("ETag", fruitETag(fruit.Version, api, format))
+}
+
+// fruitETag returns the strong entity tag of a fruit version in the given
+// api and format. Every representation has its own tag because their bytes
+// differ, the version goes first so If-Match reads it from any of them. The
+// default representation, v1 json, is tagged with the version alone.
+func fruitETag(version int64, api, format string) string {
+	value := strconv.FormatInt(version, 10)
+
+	if api != apiV1 {
+		value += "-" + api
+	}
+
+	if format != formatJSON {
+		value += "-" + format
+	}
+
+	return strconv.Quote(value)
+}
+
+// parseETag returns the fruit version of the given entity tag. Weak tags are
+// rejected because If-Match uses the strong comparison, see RFC 7232.
+func parseETag(etag string) (int64, error) {
+	if strings.HasPrefix(etag, "W/") {
+		return 0, errWeakETag
+	}
+
+	value, err := strconv.Unquote(etag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entity tag: %w", err)
+	}
+
+	versionValue, _, _ := strings.Cut(value, "-")
+
+	version, err := strconv.ParseInt(versionValue, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entity tag: %w", err)
+	}
+
+	if !isFruitETag(etag, version) {
+		return 0, errUnknownETag
+	}
+
+	return version, nil
+}
+
+// isFruitETag checks if the entity tag is the one of a fruit representation.
+func isFruitETag(etag string, version int64) bool {
+	for _, api := range []string{apiV1, apiV2} {
+		for _, format := range []string{formatJSON, formatXML, formatCSV} {
+			if etag == fruitETag(version, api, format) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func makeEncodeHeartbeatResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
 	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
 		result, ok := response.(Result)
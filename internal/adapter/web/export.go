@@ -0,0 +1,276 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/dataset"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// exportFormatNDJSON is the export format of one json fruit per line.
+const exportFormatNDJSON = "ndjson"
+
+// exportMediaTypes media type of every export format, in order of preference.
+var exportMediaTypes = []representation{
+	{format: formatJSON, mediaType: "application/json"},
+	{format: exportFormatNDJSON, mediaType: ndjsonContentType},
+	{format: formatCSV, mediaType: "text/csv"},
+}
+
+var (
+	errInvalidExportFormat = fruits.InvalidFilterError{Filter: "format", Reason: "must be csv, json or ndjson"}
+	errBuildingExport      = errors.New("cannot build export response")
+)
+
+// fruitWriter writes fruits in an export format.
+type fruitWriter interface {
+	Write(fruit fruits.Fruit) error
+	// Flush writes any buffered data and closes the format.
+	Flush() error
+}
+
+// makeDecodeExportFruitsRequest checks that the requested export format is supported.
+func makeDecodeExportFruitsRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		format, err := negotiateExportFormat(req.URL.Query().Get("format"), req.Header.Get("Accept"))
+		if err != nil {
+			logger.Debug(
+				"export format is not supported",
+				loggers.Fields{
+					"method": "decodeExportFruitsRequest",
+					"format": req.URL.Query().Get("format"),
+					"accept": req.Header.Get("Accept"),
+				},
+			)
+
+			return nil, err
+		}
+
+		return format, nil
+	}
+}
+
+// makeEncodeExportFruitsResponse streams every fruit in the requested format.
+// If the fruits cannot be read once the response started the connection is
+// aborted, so clients don't take a truncated export as a complete one.
+func makeEncodeExportFruitsResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.ExportFruitsResult)
+		if !ok || result.Fruits == nil {
+			logger.Error(
+				"cannot transform to fruits.ExportFruitsResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeExportFruitsResponse",
+				},
+			)
+
+			return errBuildingExport
+		}
+
+		format := exportFormatFromContext(ctx)
+
+		res.Header().Set("Content-Type", exportMediaType(format))
+		res.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "fruits."+format))
+
+		exported, err := exportFruits(ctx, result.Fruits, newFruitWriter(format, res))
+		if err != nil {
+			logger.Error(
+				"export was aborted",
+				loggers.Fields{
+					"method":   "encodeExportFruitsResponse",
+					"exported": exported,
+					"error":    err,
+				},
+			)
+
+			panic(http.ErrAbortHandler)
+		}
+
+		logger.Debug(
+			"fruits were exported",
+			loggers.Fields{
+				"method":   "encodeExportFruitsResponse",
+				"format":   format,
+				"exported": exported,
+			},
+		)
+
+		return nil
+	}
+}
+
+// exportFruits writes every fruit of the iterator, it returns the number of fruits written.
+func exportFruits(ctx context.Context, iterator *fruits.FruitIterator, writer fruitWriter) (int, error) {
+	var exported int
+
+	for {
+		fruit, err := iterator.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return exported, err
+		}
+
+		err = writer.Write(*fruit)
+		if err != nil {
+			return exported, err
+		}
+
+		exported++
+	}
+
+	return exported, writer.Flush()
+}
+
+// negotiateExportFormat returns the export format of the format parameter or,
+// if it is empty, the one the Accept header prefers. JSON is the default.
+func negotiateExportFormat(format, accept string) (string, error) {
+	if format != "" {
+		for _, exportMediaType := range exportMediaTypes {
+			if format == exportMediaType.format {
+				return format, nil
+			}
+		}
+
+		return "", errInvalidExportFormat
+	}
+
+	negotiated, err := negotiate(accept, exportMediaTypes)
+	if err != nil {
+		return "", err
+	}
+
+	return negotiated.format, nil
+}
+
+// exportFormatFromContext negotiates the export format again with the
+// request data httptransport.PopulateRequestContext put in the context.
+func exportFormatFromContext(ctx context.Context) string {
+	requestURI, _ := ctx.Value(httptransport.ContextKeyRequestURI).(string)
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+
+	var format string
+
+	if requestURL, err := url.ParseRequestURI(requestURI); err == nil {
+		format = requestURL.Query().Get("format")
+	}
+
+	negotiated, err := negotiateExportFormat(format, accept)
+	if err != nil {
+		return formatJSON
+	}
+
+	return negotiated
+}
+
+func exportMediaType(format string) string {
+	for _, exportMediaType := range exportMediaTypes {
+		if format == exportMediaType.format {
+			return exportMediaType.mediaType
+		}
+	}
+
+	return ""
+}
+
+func newFruitWriter(format string, w io.Writer) fruitWriter {
+	switch format {
+	case formatCSV:
+		return dataset.NewCSVWriter(w)
+	case exportFormatNDJSON:
+		return newNDJSONWriter(w)
+	default:
+		return newJSONArrayWriter(w)
+	}
+}
+
+// jsonArrayWriter writes fruits as a json array.
+type jsonArrayWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	written int
+}
+
+func newJSONArrayWriter(w io.Writer) *jsonArrayWriter {
+	writer := bufio.NewWriter(w)
+
+	return &jsonArrayWriter{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (j *jsonArrayWriter) Write(fruit fruits.Fruit) error {
+	separator := byte(',')
+	if j.written == 0 {
+		separator = '['
+	}
+
+	err := j.writer.WriteByte(separator)
+	if err != nil {
+		return fmt.Errorf("unable to write fruit: %w", err)
+	}
+
+	err = j.encoder.Encode(toFruit(&fruit))
+	if err != nil {
+		return fmt.Errorf("unable to write fruit: %w", err)
+	}
+
+	j.written++
+
+	return nil
+}
+
+func (j *jsonArrayWriter) Flush() error {
+	closing := "]\n"
+	if j.written == 0 {
+		closing = "[]\n"
+	}
+
+	_, err := j.writer.WriteString(closing)
+	if err != nil {
+		return fmt.Errorf("unable to write fruits: %w", err)
+	}
+
+	return j.writer.Flush()
+}
+
+// ndjsonWriter writes fruits as one json object per line.
+type ndjsonWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	writer := bufio.NewWriter(w)
+
+	return &ndjsonWriter{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (n *ndjsonWriter) Write(fruit fruits.Fruit) error {
+	err := n.encoder.Encode(toFruit(&fruit))
+	if err != nil {
+		return fmt.Errorf("unable to write fruit: %w", err)
+	}
+
+	return nil
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return n.writer.Flush()
+}
@@ -0,0 +1,222 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/dataset"
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// importFormField is the multipart form field of an uploaded dataset.
+const importFormField = "file"
+
+var (
+	errMissingDataset               = errors.New("dataset must be sent in the file field of the form")
+	errBuildingImportJobResponse    = errors.New("cannot build import job response")
+	errBuildingImportReportResponse = errors.New("cannot build import report response")
+)
+
+// importReportHeader are the columns of the rejected rows report.
+var importReportHeader = []string{"line", "reason"}
+
+// makeDecodeStartImportRequest keeps the uploaded csv dataset in a temporary
+// file, so it can be loaded after the request ends. The dataset is the file
+// field of a multipart form or else the whole request body.
+func makeDecodeStartImportRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		defer req.Body.Close()
+
+		upload, err := readUploadedDataset(req)
+		if err != nil {
+			logger.Error(
+				"uploaded dataset could not be read",
+				loggers.Fields{
+					"method": "decodeStartImportRequest",
+					"error":  err,
+				},
+			)
+
+			return nil, err
+		}
+
+		source, err := dataset.NewTemporaryCSVFile(upload)
+		if err != nil {
+			logger.Error(
+				"uploaded dataset could not be kept",
+				loggers.Fields{
+					"method": "decodeStartImportRequest",
+					"error":  err,
+				},
+			)
+
+			return nil, fmt.Errorf("%w: %s", errReadingRequest, err)
+		}
+
+		return &fruits.StartImportRequest{Source: source}, nil
+	}
+}
+
+// readUploadedDataset returns the file field of a multipart form or else the request body.
+func readUploadedDataset(req *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return req.Body, nil
+	}
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errReadingRequest, err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errMissingDataset
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errReadingRequest, err)
+		}
+
+		if part.FormName() == importFormField {
+			return part, nil
+		}
+	}
+}
+
+// makeDecodeImportJobRequest reads the import job id from the request path.
+func makeDecodeImportJobRequest(logger *loggers.Logger) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, req *http.Request) (interface{}, error) {
+		jobID := mux.Vars(req)["id"]
+
+		logger.Debug(
+			"import job request",
+			loggers.Fields{
+				"method": "decodeImportJobRequest",
+				"jobID":  jobID,
+			},
+		)
+
+		return jobID, nil
+	}
+}
+
+// makeEncodeStartImportResponse answers 202 Accepted with the new job, its
+// location is the request path followed by the job id.
+func makeEncodeStartImportResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.ImportJobResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.ImportJobResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeStartImportResponse",
+				},
+			)
+
+			return errBuildingImportJobResponse
+		}
+
+		requestPath, _ := ctx.Value(httptransport.ContextKeyRequestPath).(string)
+
+		res.Header().Set("Location", path.Join(requestPath, result.Job.JobID))
+
+		return encodeImportJob(res, http.StatusAccepted, result, logger)
+	}
+}
+
+func makeEncodeImportJobResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.ImportJobResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.ImportJobResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeImportJobResponse",
+				},
+			)
+
+			return errBuildingImportJobResponse
+		}
+
+		return encodeImportJob(res, http.StatusOK, result, logger)
+	}
+}
+
+// makeEncodeImportReportResponse writes the rejected rows of an import job as csv.
+func makeEncodeImportReportResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.ImportReportResult)
+		if !ok {
+			logger.Error(
+				"cannot transform to fruits.ImportReportResult",
+				loggers.Fields{
+					"received": fmt.Sprintf("%T", response),
+					"method":   "encodeImportReportResponse",
+				},
+			)
+
+			return errBuildingImportReportResponse
+		}
+
+		res.Header().Set("Content-Type", "text/csv")
+		res.Header().Set("Content-Disposition", `attachment; filename="rejected-rows.csv"`)
+
+		writer := csv.NewWriter(res)
+
+		err := writer.Write(importReportHeader)
+		if err != nil {
+			return fmt.Errorf("unable to write import report: %w", err)
+		}
+
+		for _, rejection := range result.Rejections {
+			err = writer.Write([]string{strconv.Itoa(rejection.Line), rejection.Reason})
+			if err != nil {
+				return fmt.Errorf("unable to write import report: %w", err)
+			}
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+	}
+}
+
+func encodeImportJob(res http.ResponseWriter, status int, result fruits.ImportJobResult, logger *loggers.Logger) error {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+
+	message := Result{
+		Success: true,
+		Data:    toFruitDatasetStatusResponse(result.Job),
+	}
+
+	err := json.NewEncoder(res).Encode(message)
+	if err != nil {
+		logger.Error(
+			"cannot encode Result",
+			loggers.Fields{
+				"result": fmt.Sprintf("%+v", message),
+				"method": "encodeImportJob",
+			},
+		)
+
+		return errEncodingResultResponse
+	}
+
+	return nil
+}
@@ -4,34 +4,60 @@ import "github.com/fernandoocampo/fruits/internal/fruits"
 
 // Result standard result for the service.
 type Result struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data"`
-	Errors  []string    `json:"errors"`
+	Success bool        `json:"success" xml:"success"`
+	Data    interface{} `json:"data" xml:"data"`
+	Errors  []string    `json:"errors" xml:"errors>error"`
+}
+
+// Envelope is the v2 response document. Failed requests are answered with
+// problem details instead, so it has no success flag nor errors.
+type Envelope struct {
+	Data interface{} `json:"data" xml:"data"`
+	// Meta describes the data, e.g. the page of a search.
+	Meta interface{} `json:"meta,omitempty" xml:"meta,omitempty"`
+}
+
+// SearchMeta describes the page of fruits found by a v2 search.
+type SearchMeta struct {
+	Total      int    `json:"total" xml:"total"`
+	Start      int    `json:"start" xml:"start"`
+	Count      int    `json:"count" xml:"count"`
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	Facets     Facets `json:"facets,omitempty" xml:"facets,omitempty"`
+}
+
+// FruitItems are the fruits found by a v2 search.
+type FruitItems []FruitItemResult
+
+// CreatedFruitResponse contains the id of a fruit created with v2.
+type CreatedFruitResponse struct {
+	ID string `json:"id" xml:"id"`
 }
 
 // Fruit contains fruit data.
 type Fruit struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Variety        string  `json:"variety"`
-	Vault          string  `json:"vault"`
-	Year           int     `json:"year"`
-	Price          float32 `json:"price,omitempty"`
-	Country        string  `json:"country"`
-	Province       string  `json:"province"`
-	Region         string  `json:"region,omitempty"`
-	Finca          string  `json:"finca,omitempty"`
-	Description    string  `json:"description"`
-	Classification string  `json:"classification"`
-	LocalName      string  `json:"local_name"`
-	WikiPage       string  `json:"wiki_page"`
+	ID             string  `json:"id" xml:"id"`
+	Name           string  `json:"name" xml:"name"`
+	Variety        string  `json:"variety" xml:"variety"`
+	Vault          string  `json:"vault" xml:"vault"`
+	Year           int     `json:"year" xml:"year"`
+	Price          float32 `json:"price,omitempty" xml:"price,omitempty"`
+	Country        string  `json:"country" xml:"country"`
+	Province       string  `json:"province" xml:"province"`
+	Region         string  `json:"region,omitempty" xml:"region,omitempty"`
+	Finca          string  `json:"finca,omitempty" xml:"finca,omitempty"`
+	Description    string  `json:"description" xml:"description"`
+	Classification string  `json:"classification" xml:"classification"`
+	LocalName      string  `json:"local_name" xml:"local_name"`
+	WikiPage       string  `json:"wiki_page" xml:"wiki_page"`
+	Version        int64   `json:"version" xml:"version"`
 }
 
 // FruitItemResult contains data related to a fruit found during a search.
 type FruitItemResult struct {
-	ID string `json:"id"`
+	ID string `json:"id" xml:"id"`
 	// Name or name of the fruit.
-	Name string `json:"name"`
+	Name string `json:"name" xml:"name"`
 }
 
 // NewFruit contains the expected data for a new fruit.
@@ -51,12 +77,44 @@ type NewFruit struct {
 	WikiPage       string  `json:"wiki_page"`
 }
 
+// FruitPatch contains the fruit fields to change, missing fields are left as they are.
+type FruitPatch struct {
+	Name           *string  `json:"name,omitempty"`
+	Variety        *string  `json:"variety,omitempty"`
+	Vault          *string  `json:"vault,omitempty"`
+	Year           *int     `json:"year,omitempty"`
+	Price          *float32 `json:"price,omitempty"`
+	Country        *string  `json:"country,omitempty"`
+	Province       *string  `json:"province,omitempty"`
+	Region         *string  `json:"region,omitempty"`
+	Finca          *string  `json:"finca,omitempty"`
+	Description    *string  `json:"description,omitempty"`
+	Classification *string  `json:"classification,omitempty"`
+	LocalName      *string  `json:"local_name,omitempty"`
+	WikiPage       *string  `json:"wiki_page,omitempty"`
+}
+
 // CreateFruitResponse standard response for create Fruit.
 type CreateFruitResponse struct {
 	ID  string `json:"id"`
 	Err string `json:"err,omitempty"`
 }
 
+// CreateBatchResponse contains the outcome of creating fruits at once.
+type CreateBatchResponse struct {
+	Created  int                 `json:"created"`
+	Rejected int                 `json:"rejected"`
+	Items    []BatchItemResponse `json:"items"`
+}
+
+// BatchItemResponse contains the outcome of creating one fruit of a batch,
+// Problem describes why it was not created.
+type BatchItemResponse struct {
+	Index   int      `json:"index"`
+	ID      string   `json:"id,omitempty"`
+	Problem *Problem `json:"problem,omitempty"`
+}
+
 // GetFruitWithIDResponse standard response for get a Fruit with an ID.
 type GetFruitWithIDResponse struct {
 	Fruit *Fruit `json:"fruit"`
@@ -75,21 +133,65 @@ type SearchFruitFilter struct {
 	Start int
 	// rows per page
 	Count int
+	// exact match filters
+	Country        string
+	Province       string
+	Region         string
+	Variety        string
+	Classification string
+	Vault          string
+	// inclusive ranges
+	MinYear  *int
+	MaxYear  *int
+	MinPrice *float32
+	MaxPrice *float32
+	// Sort fields to sort the results by, in order of precedence.
+	Sort []fruits.SortField
+	// Cursor is the next cursor of the previous page.
+	Cursor string
+	// Query are the words to search for.
+	Query string
+	// Facets are the fields to count the results by.
+	Facets []string
 }
 
 // SearchFruitsResult contains search fruits result data.
 type SearchFruitsResult struct {
-	Fruits []FruitItemResult `json:"fruits"`
-	Total  int               `json:"total"`
-	Start  int               `json:"start"`
-	Count  int               `json:"count"`
+	Fruits     []FruitItemResult `json:"fruits" xml:"fruits>fruit"`
+	Total      int               `json:"total" xml:"total"`
+	Start      int               `json:"start" xml:"start"`
+	Count      int               `json:"count" xml:"count"`
+	NextCursor string            `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	// Facets are the buckets of every requested facet, by field name.
+	Facets Facets `json:"facets,omitempty" xml:"facets,omitempty"`
+}
+
+// Facets are the buckets of every facet, by field name.
+type Facets map[string][]FacetBucketResult
+
+// FacetBucketResult contains the number of fruits found with a field value.
+type FacetBucketResult struct {
+	Value string `json:"value" xml:"value,attr"`
+	Count int    `json:"count" xml:"count,attr"`
 }
 
 // FruitDatasetStatusResponse contains fruit dataset status result data.
 type FruitDatasetStatusResponse struct {
-	Status    string `json:"status"`
-	Message   string `json:"message"`
-	Timestamp int64  `json:"timestamp"`
+	JobID     string `json:"job_id,omitempty" xml:"job_id,omitempty"`
+	Status    string `json:"status" xml:"status"`
+	Message   string `json:"message" xml:"message"`
+	Timestamp int64  `json:"timestamp" xml:"timestamp"`
+	// Processed number of dataset rows read so far, the loaded and the rejected ones.
+	Processed  int                        `json:"processed" xml:"processed"`
+	Loaded     int                        `json:"loaded" xml:"loaded"`
+	Rejected   int                        `json:"rejected" xml:"rejected"`
+	Rejections []DatasetRejectionResponse `json:"rejections,omitempty" xml:"rejections>rejection,omitempty"`
+}
+
+// DatasetRejectionResponse contains data about a dataset row that was not loaded.
+type DatasetRejectionResponse struct {
+	Line   int    `json:"line" xml:"line"`
+	Reason string `json:"reason" xml:"reason"`
 }
 
 // toFruit transforms new fruit to a fruit object.
@@ -113,6 +215,7 @@ func toFruit(fruit *fruits.Fruit) *Fruit {
 		Classification: fruit.Classification,
 		LocalName:      fruit.LocalName,
 		WikiPage:       fruit.WikiPage,
+		Version:        fruit.Version,
 	}
 
 	return &webFruit
@@ -146,15 +249,36 @@ func toSearchFruitResult(result *fruits.SearchFruitsResult) *SearchFruitsResult
 	}
 
 	webFruit := SearchFruitsResult{
-		Fruits: fruitsFound,
-		Total:  result.Total,
-		Start:  result.Start,
-		Count:  result.Count,
+		Fruits:     fruitsFound,
+		Total:      result.Total,
+		Start:      result.Start,
+		Count:      result.Count,
+		NextCursor: result.NextCursor,
+		Facets:     toFacetBucketResults(result.Facets),
 	}
 
 	return &webFruit
 }
 
+func toFacetBucketResults(facets map[string][]fruits.FacetBucket) Facets {
+	if facets == nil {
+		return nil
+	}
+
+	webFacets := make(Facets, len(facets))
+
+	for field, buckets := range facets {
+		webBuckets := make([]FacetBucketResult, len(buckets))
+		for index, bucket := range buckets {
+			webBuckets[index] = FacetBucketResult{Value: bucket.Value, Count: bucket.Count}
+		}
+
+		webFacets[field] = webBuckets
+	}
+
+	return webFacets
+}
+
 // toFruit transforms new fruit to a fruit object.
 func (n *NewFruit) toFruit() *fruits.NewFruit {
 	if n == nil {
@@ -180,6 +304,25 @@ func (n *NewFruit) toFruit() *fruits.NewFruit {
 	return &fruitDomain
 }
 
+// toFruitPatch transforms the patch request to a domain fruit patch.
+func (p FruitPatch) toFruitPatch() fruits.FruitPatch {
+	return fruits.FruitPatch{
+		Name:           p.Name,
+		Variety:        p.Variety,
+		Vault:          p.Vault,
+		Year:           p.Year,
+		Price:          p.Price,
+		Country:        p.Country,
+		Province:       p.Province,
+		Region:         p.Region,
+		Finca:          p.Finca,
+		Description:    p.Description,
+		Classification: p.Classification,
+		LocalName:      p.LocalName,
+		WikiPage:       p.WikiPage,
+	}
+}
+
 func toCreateFruitResponse(fruitResult fruits.CreateFruitResult) Result {
 	var message Result
 
@@ -195,6 +338,47 @@ func toCreateFruitResponse(fruitResult fruits.CreateFruitResult) Result {
 	return message
 }
 
+func toCreateBatchResponse(batchResult fruits.CreateBatchResult) Result {
+	var message Result
+
+	if batchResult.Err != "" {
+		message.Errors = []string{batchResult.Err}
+
+		return message
+	}
+
+	message.Success = true
+	message.Data = toCreateBatchData(batchResult)
+
+	return message
+}
+
+// toCreateBatchData returns the outcome of every fruit of a batch, a fruit
+// that was not created has the problem details of why.
+func toCreateBatchData(batchResult fruits.CreateBatchResult) CreateBatchResponse {
+	response := CreateBatchResponse{
+		Created:  batchResult.Created,
+		Rejected: batchResult.Rejected,
+		Items:    make([]BatchItemResponse, 0, len(batchResult.Items)),
+	}
+
+	for _, item := range batchResult.Items {
+		itemResponse := BatchItemResponse{
+			Index: item.Index,
+			ID:    item.ID,
+		}
+
+		if item.Err != nil {
+			problem := newProblem(item.Err)
+			itemResponse.Problem = &problem
+		}
+
+		response.Items = append(response.Items, itemResponse)
+	}
+
+	return response
+}
+
 func toGetFruitWithIDResponse(fruitResult fruits.GetFruitWithIDResult) Result {
 	var message Result
 
@@ -212,6 +396,36 @@ func toGetFruitWithIDResponse(fruitResult fruits.GetFruitWithIDResult) Result {
 	return message
 }
 
+func toUpdateFruitResponse(fruitResult fruits.UpdateFruitResult) Result {
+	var message Result
+
+	if fruitResult.Err == "" {
+		message.Success = true
+		message.Data = toFruit(fruitResult.Fruit)
+	}
+
+	if fruitResult.Err != "" {
+		message.Errors = []string{fruitResult.Err}
+	}
+
+	return message
+}
+
+func toDeleteFruitResponse(fruitResult fruits.DeleteFruitResult) Result {
+	var message Result
+
+	if fruitResult.Err == "" {
+		message.Success = true
+		message.Data = fruitResult.ID
+	}
+
+	if fruitResult.Err != "" {
+		message.Errors = []string{fruitResult.Err}
+	}
+
+	return message
+}
+
 func toSearchFruitsResponse(fruitResult fruits.SearchFruitsDataResult) Result {
 	var message Result
 
@@ -227,11 +441,41 @@ func toSearchFruitsResponse(fruitResult fruits.SearchFruitsDataResult) Result {
 	return message
 }
 
+// toSearchEnvelope puts the fruits found in the data of a v2 envelope and the page in its meta.
+func toSearchEnvelope(result *fruits.SearchFruitsResult) Envelope {
+	search := toSearchFruitResult(result)
+	if search == nil {
+		return Envelope{Data: FruitItems{}}
+	}
+
+	return Envelope{
+		Data: FruitItems(search.Fruits),
+		Meta: SearchMeta{
+			Total:      search.Total,
+			Start:      search.Start,
+			Count:      search.Count,
+			NextCursor: search.NextCursor,
+			Facets:     search.Facets,
+		},
+	}
+}
+
 func toFruitDatasetStatusResponse(status fruits.DatasetStatus) FruitDatasetStatusResponse {
 	response := FruitDatasetStatusResponse{
+		JobID:     status.JobID,
 		Status:    string(status.Status),
 		Message:   status.Message,
 		Timestamp: status.Timestamp,
+		Processed: status.Loaded + status.Rejected,
+		Loaded:    status.Loaded,
+		Rejected:  status.Rejected,
+	}
+
+	for _, rejection := range status.Rejections {
+		response.Rejections = append(response.Rejections, DatasetRejectionResponse{
+			Line:   rejection.Line,
+			Reason: rejection.Reason,
+		})
 	}
 
 	return response
@@ -239,7 +483,21 @@ func toFruitDatasetStatusResponse(status fruits.DatasetStatus) FruitDatasetStatu
 
 func (s SearchFruitFilter) toSearchFruitFilter() fruits.SearchFruitFilter {
 	return fruits.SearchFruitFilter{
-		Start: s.Start,
-		Count: s.Count,
+		Start:          s.Start,
+		Count:          s.Count,
+		Country:        s.Country,
+		Province:       s.Province,
+		Region:         s.Region,
+		Variety:        s.Variety,
+		Classification: s.Classification,
+		Vault:          s.Vault,
+		MinYear:        s.MinYear,
+		MaxYear:        s.MaxYear,
+		MinPrice:       s.MinPrice,
+		MaxPrice:       s.MaxPrice,
+		Sort:           s.Sort,
+		Cursor:         s.Cursor,
+		Query:          s.Query,
+		Facets:         s.Facets,
 	}
 }
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Response formats.
+const (
+	formatJSON = "json"
+	formatXML  = "xml"
+	formatCSV  = "csv"
+)
+
+// representation is a format a response can be written in and its media type.
+type representation struct {
+	format    string
+	mediaType string
+}
+
+// mediaRange is a media range of an Accept header.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// responseRepresentations are the representations of fruits, searches and
+// the dataset status, in order of preference.
+var responseRepresentations = []representation{
+	{format: formatJSON, mediaType: "application/json"},
+	{format: formatXML, mediaType: "application/xml"},
+	{format: formatXML, mediaType: "text/xml"},
+	{format: formatCSV, mediaType: "text/csv"},
+}
+
+var errNotAcceptable = errors.New("none of the media types of the Accept header can be produced")
+
+// negotiate returns the representation the Accept header prefers, if the
+// header prefers several the first one of offers. Without Accept header
+// the first offer is returned.
+func negotiate(accept string, offers []representation) (representation, error) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], nil
+	}
+
+	for _, accepted := range parseAccept(accept) {
+		for _, offer := range offers {
+			if matchesMediaRange(offer.mediaType, accepted.mediaType) {
+				return offer, nil
+			}
+		}
+	}
+
+	mediaTypes := make([]string, len(offers))
+	for index, offer := range offers {
+		mediaTypes[index] = offer.mediaType
+	}
+
+	return representation{}, fmt.Errorf("%w, available ones are %s", errNotAcceptable, strings.Join(mediaTypes, ", "))
+}
+
+// checkAcceptable rejects the request if no response representation satisfies its Accept header.
+func checkAcceptable(accept string) error {
+	_, err := negotiate(accept, responseRepresentations)
+
+	return err
+}
+
+// representationFromContext negotiates the response representation again
+// with the Accept header httptransport.PopulateRequestContext put in the context.
+func representationFromContext(ctx context.Context) representation {
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+
+	negotiated, err := negotiate(accept, responseRepresentations)
+	if err != nil {
+		return responseRepresentations[0]
+	}
+
+	return negotiated
+}
+
+// parseAccept returns the media ranges of an Accept header that are
+// acceptable, the preferred ones first.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	return ranges
+}
+
+// matchesMediaRange checks if the media type belongs to the media range, e.g. text/* or */*.
+func matchesMediaRange(mediaType, mediaRange string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	typeName, _, _ := strings.Cut(mediaType, "/")
+
+	return rangeSubtype == "*" && rangeType == typeName
+}
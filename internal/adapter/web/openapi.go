@@ -0,0 +1,52 @@
+package web
+
+import (
+	_ "embed"
+	"log"
+	"net/http"
+)
+
+// openAPIDocument is the OpenAPI 3 description of every route of NewHTTPServer.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+const docsContent = `<!DOCTYPE html>
+<html>
+   <head>
+	  <title>fruits api</title>
+	  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+   </head>
+   <body>
+	  <div id="swagger-ui"></div>
+	  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+	  <script>
+		 window.onload = () => {
+			window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+		 };
+	  </script>
+   </body>
+</html>
+`
+
+type openAPI struct{}
+
+func (o openAPI) ServeHTTP(res http.ResponseWriter, _ *http.Request) {
+	res.Header().Set("Content-Type", "application/json")
+
+	_, err := res.Write(openAPIDocument)
+	if err != nil {
+		log.Println("unable to write openapi document", err)
+	}
+}
+
+type docs struct{}
+
+func (d docs) ServeHTTP(res http.ResponseWriter, _ *http.Request) {
+	res.Header().Set("Content-Type", "text/html;charset=UTF-8")
+
+	_, err := res.Write([]byte(docsContent))
+	if err != nil {
+		log.Println("unable to write docs content", err)
+	}
+}
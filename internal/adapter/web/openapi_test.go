@@ -0,0 +1,133 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/web"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	t.Parallel()
+
+	routes := registeredRoutes(t)
+	documented := documentedRoutes(t)
+
+	for _, route := range routes {
+		assert.Contains(t, documented, route, "route is not in openapi.json")
+	}
+
+	for _, route := range documented {
+		assert.Contains(t, routes, route, "openapi.json documents a route that is not registered")
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	t.Parallel()
+
+	response, body := doGetRequest(t, fruits.Endpoints{}, "/docs", "")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/html;charset=UTF-8", response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `url: "/openapi.json"`)
+}
+
+// registeredRoutes returns the method and path template of every route of the http server.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+
+	handler := web.NewHTTPServer(fruits.Endpoints{}, loggers.NewLoggerWithStdout("", loggers.Error))
+
+	router, ok := handler.(*mux.Router)
+	if !ok {
+		t.Fatalf("unexpected handler type: %T", handler)
+	}
+
+	var routes []string
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		// path prefixes of the versions have no methods.
+		methods, _ := route.GetMethods()
+
+		for _, method := range methods {
+			routes = append(routes, method+" "+path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error walking routes: %s", err)
+	}
+
+	sort.Strings(routes)
+
+	return routes
+}
+
+// documentedRoutes returns the method and path of every operation of the openapi document.
+func documentedRoutes(t *testing.T) []string {
+	t.Helper()
+
+	_, body := doGetRequest(t, fruits.Endpoints{}, "/openapi.json", "")
+
+	var document struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+
+	err := json.Unmarshal(body, &document)
+	if err != nil {
+		t.Fatalf("unexpected error reading openapi document: %s", err)
+	}
+
+	assert.True(t, strings.HasPrefix(document.OpenAPI, "3."))
+
+	var routes []string
+
+	for path, item := range document.Paths {
+		if ref, ok := item["$ref"]; ok {
+			item = document.Paths[referencedPath(t, ref)]
+		}
+
+		for method := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+
+	return routes
+}
+
+// referencedPath returns the path of a reference like #/paths/~1fruit~1{id}.
+func referencedPath(t *testing.T, ref json.RawMessage) string {
+	t.Helper()
+
+	var pointer string
+
+	err := json.Unmarshal(ref, &pointer)
+	if err != nil {
+		t.Fatalf("unexpected error reading path reference: %s", err)
+	}
+
+	path := strings.TrimPrefix(pointer, "#/paths/")
+	path = strings.ReplaceAll(path, "~1", "/")
+
+	return strings.ReplaceAll(path, "~0", "~")
+}
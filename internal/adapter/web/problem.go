@@ -0,0 +1,169 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// problemContentType is the media type of problem details, see RFC 7807.
+const problemContentType = "application/problem+json"
+
+// problemTypeBlank problem type of errors that are fully described by their http status.
+const problemTypeBlank = "about:blank"
+
+// internalErrorDetail is the detail of unexpected errors, they are logged
+// instead of being sent because they may describe the internals of the service.
+const internalErrorDetail = "the request could not be processed because of an unexpected error"
+
+// Problem contains the details of a failed request, see RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// InvalidParams are the request fields that were rejected and why.
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam contains a request field that was rejected.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// decodeErrors are the errors of requests that cannot be read.
+var decodeErrors = []error{
+	errDecodingRequest,
+	errReadingRequest,
+	errNoFruitIDWasProvided,
+	errFruitIDNoInt,
+	errInvalidIfMatch,
+	errInvalidIdempotency,
+	errMissingDataset,
+}
+
+// makeEncodeError encodes errors that make a request fail as the problem details they represent.
+func makeEncodeError(logger *loggers.Logger) httptransport.ErrorEncoder {
+	return func(ctx context.Context, err error, res http.ResponseWriter) {
+		problem := newProblem(err)
+		problem.Instance, _ = ctx.Value(httptransport.ContextKeyRequestPath).(string)
+
+		fields := loggers.Fields{
+			"method": "encodeError",
+			"status": problem.Status,
+			"error":  err,
+		}
+
+		if problem.Status == http.StatusInternalServerError {
+			logger.Error("request failed unexpectedly", fields)
+		} else {
+			logger.Debug("request failed", fields)
+		}
+
+		writeProblem(res, problem, logger)
+	}
+}
+
+// makeProblemHandler returns a handler that replies every request with the given status problem.
+func makeProblemHandler(status int, logger *loggers.Logger) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		problem := Problem{
+			Type:     problemTypeBlank,
+			Title:    http.StatusText(status),
+			Status:   status,
+			Instance: req.URL.Path,
+		}
+
+		writeProblem(res, problem, logger)
+	})
+}
+
+func writeProblem(res http.ResponseWriter, problem Problem, logger *loggers.Logger) {
+	res.Header().Set("Content-Type", problemContentType)
+	res.WriteHeader(problem.Status)
+
+	err := json.NewEncoder(res).Encode(problem)
+	if err != nil {
+		logger.Error(
+			"cannot encode problem",
+			loggers.Fields{
+				"problem": problem,
+				"method":  "writeProblem",
+				"error":   err,
+			},
+		)
+	}
+}
+
+// newProblem returns the problem details that represent the given error,
+// the detail of unexpected errors is not the error.
+func newProblem(err error) Problem {
+	status := toStatusCode(err)
+	problem := Problem{
+		Type:   problemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}
+
+	if status == http.StatusInternalServerError {
+		problem.Detail = internalErrorDetail
+	}
+
+	var validationError fruits.ValidationError
+	if errors.As(err, &validationError) {
+		for _, violation := range validationError.Violations {
+			problem.InvalidParams = append(problem.InvalidParams, InvalidParam{Name: violation.Field, Reason: violation.Reason})
+		}
+	}
+
+	var invalidFilter fruits.InvalidFilterError
+	if errors.As(err, &invalidFilter) {
+		problem.InvalidParams = []InvalidParam{{Name: invalidFilter.Filter, Reason: invalidFilter.Reason}}
+	}
+
+	return problem
+}
+
+// toStatusCode returns the http status code that represents the given error.
+func toStatusCode(err error) int {
+	switch {
+	case errors.Is(err, fruits.ErrFruitNotFound), errors.Is(err, fruits.ErrImportJobNotFound), errors.Is(err, fruits.ErrDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, fruits.ErrVersionConflict), errors.Is(err, errWeakIfMatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errIfMatchRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, fruits.ErrIdempotencyKeyInUse), errors.Is(err, fruits.ErrImportJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, errNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, fruits.ErrBatchTooLarge), errors.Is(err, errFruitBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, new(fruits.ValidationError)), errors.Is(err, fruits.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, new(fruits.InvalidFilterError)), isDecodeError(err):
+		return http.StatusBadRequest
+	case errors.Is(err, fruits.ErrDataAccess), errors.Is(err, fruits.ErrRedrivingEvent):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func isDecodeError(err error) bool {
+	for _, decodeError := range decodeErrors {
+		if errors.Is(err, decodeError) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/dataset"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// XML root elements of the responses.
+const (
+	resultXMLRoot        = "result"
+	datasetStatusXMLRoot = "dataset_status"
+)
+
+// Columns of the csv representations.
+var (
+	searchCSVHeader        = []string{"id", "name"}
+	datasetStatusCSVHeader = []string{"job_id", "status", "message", "timestamp", "processed", "loaded", "rejected"}
+)
+
+// writeRepresentation writes the message in the representation negotiated
+// for the request, xml with the given root element. The csv representation
+// is written by writeCSV.
+func writeRepresentation(ctx context.Context, res http.ResponseWriter, message interface{}, xmlRoot string, writeCSV func(w io.Writer) error) error {
+	negotiated := representationFromContext(ctx)
+
+	res.Header().Set("Content-Type", negotiated.mediaType)
+	res.Header().Add("Vary", "Accept")
+
+	switch negotiated.format {
+	case formatXML:
+		_, err := io.WriteString(res, xml.Header)
+		if err != nil {
+			return fmt.Errorf("unable to write xml: %w", err)
+		}
+
+		err = xml.NewEncoder(res).EncodeElement(message, xml.StartElement{Name: xml.Name{Local: xmlRoot}})
+		if err != nil {
+			return fmt.Errorf("unable to write xml: %w", err)
+		}
+
+		return nil
+	case formatCSV:
+		return writeCSV(res)
+	default:
+		return json.NewEncoder(res).Encode(message)
+	}
+}
+
+// writeFruitCSV writes the fruit with the columns of the fruit datasets.
+func writeFruitCSV(w io.Writer, fruit *fruits.Fruit) error {
+	writer := dataset.NewCSVWriter(w)
+
+	if fruit != nil {
+		err := writer.Write(*fruit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// writeSearchCSV writes the id and name of every fruit found.
+func writeSearchCSV(w io.Writer, result *fruits.SearchFruitsResult) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(searchCSVHeader)
+	if err != nil {
+		return fmt.Errorf("unable to write csv: %w", err)
+	}
+
+	if result != nil {
+		for _, fruit := range result.Fruits {
+			err = writer.Write([]string{fruit.ID, fruit.Name})
+			if err != nil {
+				return fmt.Errorf("unable to write csv: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// writeDatasetStatusCSV writes the dataset status as one row, without the rejected rows.
+func writeDatasetStatusCSV(w io.Writer, status FruitDatasetStatusResponse) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.WriteAll([][]string{
+		datasetStatusCSVHeader,
+		{
+			status.JobID,
+			status.Status,
+			status.Message,
+			strconv.FormatInt(status.Timestamp, 10),
+			strconv.Itoa(status.Processed),
+			strconv.Itoa(status.Loaded),
+			strconv.Itoa(status.Rejected),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write csv: %w", err)
+	}
+
+	return nil
+}
+
+// setNextLink adds a Link header to the next page of a search, the request
+// uri with the next cursor, so representations without cursor can be paged.
+func setNextLink(ctx context.Context, res http.ResponseWriter, nextCursor string) {
+	requestURI, _ := ctx.Value(httptransport.ContextKeyRequestURI).(string)
+
+	nextURL, err := url.ParseRequestURI(requestURI)
+	if err != nil || nextCursor == "" {
+		return
+	}
+
+	query := nextURL.Query()
+	query.Del("start")
+	query.Set("cursor", nextCursor)
+	nextURL.RawQuery = query.Encode()
+
+	res.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.RequestURI()))
+}
+
+// MarshalXML writes every facet as a facet element with its field and
+// buckets, ordered by field, see xml.Marshaler.
+func (f Facets) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type facet struct {
+		Field   string              `xml:"field,attr"`
+		Buckets []FacetBucketResult `xml:"bucket"`
+	}
+
+	fields := make([]string, 0, len(f))
+	for field := range f {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	facets := struct {
+		Facets []facet `xml:"facet"`
+	}{
+		Facets: make([]facet, 0, len(fields)),
+	}
+
+	for _, field := range fields {
+		facets.Facets = append(facets.Facets, facet{Field: field, Buckets: f[field]})
+	}
+
+	return e.EncodeElement(facets, start)
+}
+
+// MarshalXML writes every fruit as a fruit element, see xml.Marshaler.
+func (f FruitItems) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	items := struct {
+		Fruits []FruitItemResult `xml:"fruit"`
+	}{
+		Fruits: f,
+	}
+
+	return e.EncodeElement(items, start)
+}
@@ -24,42 +24,164 @@ const homeContent = `<!DOCTYPE html>
 </html>
 `
 
-// NewHTTPServer is a factory to create http servers for this project.
-func NewHTTPServer(fruitEndpoints fruits.Endpoints, logger *loggers.Logger) http.Handler {
+// ServerOption sets optional settings of the http server.
+type ServerOption func(*serverSettings)
+
+// serverSettings contains the optional settings of the http server.
+type serverSettings struct {
+	// cacheControl is the Cache-Control of fruit reads.
+	cacheControl string
+}
+
+// WithCacheControl sets the Cache-Control header of fruit reads, no-cache by default.
+func WithCacheControl(cacheControl string) ServerOption {
+	return func(settings *serverSettings) {
+		settings.cacheControl = cacheControl
+	}
+}
+
+// NewHTTPServer is a factory to create http servers for this project. The
+// v1 routes are served with and without the /v1 prefix, so clients of the
+// unversioned routes keep working.
+func NewHTTPServer(fruitEndpoints fruits.Endpoints, logger *loggers.Logger, serverOptions ...ServerOption) http.Handler {
+	settings := serverSettings{
+		cacheControl: defaultCacheControl,
+	}
+
+	for _, serverOption := range serverOptions {
+		serverOption(&settings)
+	}
+
 	router := mux.NewRouter()
+	encodeError := makeEncodeError(logger)
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, putConditions),
+	}
+
+	addV1Routes(router, fruitEndpoints, encodeError, options, settings, logger)
+	addV1Routes(newSubrouter(router, "/v1", logger), fruitEndpoints, encodeError, options, settings, logger)
+	addV2Routes(newSubrouter(router, "/v2", logger), fruitEndpoints, encodeError, options, settings, logger)
+	addAdminRoutes(router, fruitEndpoints, encodeError, options, logger)
+	router.Methods(http.MethodGet).Path("/openapi.json").Handler(openAPI{})
+	router.Methods(http.MethodGet).Path("/docs").Handler(docs{})
+
+	router.NotFoundHandler = makeProblemHandler(http.StatusNotFound, logger)
+	router.MethodNotAllowedHandler = makeProblemHandler(http.StatusMethodNotAllowed, logger)
+
+	return router
+}
+
+// newSubrouter returns a router of the routes with the given path prefix.
+func newSubrouter(router *mux.Router, prefix string, logger *loggers.Logger) *mux.Router {
+	subrouter := router.PathPrefix(prefix).Subrouter()
+	subrouter.NotFoundHandler = makeProblemHandler(http.StatusNotFound, logger)
+	subrouter.MethodNotAllowedHandler = makeProblemHandler(http.StatusMethodNotAllowed, logger)
+
+	return subrouter
+}
+
+// addV1Routes adds the routes of the first version of the api, whose responses are a Result.
+func addV1Routes(router *mux.Router, fruitEndpoints fruits.Endpoints, encodeError httptransport.ErrorEncoder, options []httptransport.ServerOption, settings serverSettings, logger *loggers.Logger) {
 	router.Methods(http.MethodGet).Path("/home").Handler(home{})
+	router.Methods(http.MethodGet).Path("/fruit/export").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.ExportFruitsEndpoint,
+			makeDecodeExportFruitsRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeExportFruitsResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPost).Path("/fruit/import").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.StartImportEndpoint,
+			makeDecodeStartImportRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeStartImportResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/fruit/import/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetImportJobEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeImportJobResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/fruit/import/{id}/report").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetImportReportEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeImportReportResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodDelete).Path("/fruit/import/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.CancelImportEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeImportJobResponse(logger)),
+			options...),
+	)
 	router.Methods(http.MethodGet).Path("/fruit/{id}").Handler(
 		httptransport.NewServer(
 			fruitEndpoints.GetFruitWithIDEndpoint,
 			makeDecodeGetFruitWithIDRequest(logger),
-			makeEncodeGetFruitWithIDResponse(logger)),
+			makeEncodeFailedResponse(encodeError, makeEncodeGetFruitWithIDResponse(settings.cacheControl, logger)),
+			options...),
 	)
 	router.Methods(http.MethodPut).Path("/fruit").Handler(
 		httptransport.NewServer(
 			fruitEndpoints.CreateFruitEndpoint,
 			makeDecodeCreateFruitRequest(logger),
-			makeEncodeCreateFruitRequest(logger)),
+			makeEncodeFailedResponse(encodeError, makeEncodeCreateFruitRequest(logger)),
+			append(options, httptransport.ServerBefore(putIdempotencyKey))...),
+	)
+	router.Methods(http.MethodPost).Path("/fruit/batch").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.CreateBatchEndpoint,
+			makeDecodeCreateBatchRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeCreateBatchResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPut).Path("/fruit/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.UpdateFruitEndpoint,
+			makeDecodeUpdateFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeUpdateFruitResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPatch).Path("/fruit/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.PatchFruitEndpoint,
+			makeDecodePatchFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeUpdateFruitResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodDelete).Path("/fruit/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.DeleteFruitEndpoint,
+			makeDecodeDeleteFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeDeleteFruitResponse(logger)),
+			options...),
 	)
 	router.Methods(http.MethodGet).Path("/fruit").Handler(
 		httptransport.NewServer(
 			fruitEndpoints.SearchFruitsEndpoint,
 			makeDecodeSearchFruitsRequest(logger),
-			makeEncodeSearchFruitsResponse(logger)),
+			makeEncodeFailedResponse(encodeError, makeEncodeSearchFruitsResponse(settings.cacheControl, logger)),
+			options...),
 	)
 	router.Methods(http.MethodGet).Path("/status").Handler(
 		httptransport.NewServer(
 			fruitEndpoints.GetStatusEndpoint,
-			makeEmptyDecoder(logger),
-			makeEncodeGetStatusResponse(logger)),
+			makeDecodeGetStatusRequest(logger),
+			makeEncodeGetStatusResponse(logger),
+			options...),
 	)
 	router.Methods(http.MethodGet).Path("/heartbeat").Handler(
 		httptransport.NewServer(
 			MakeGetHeartbeatEndpoint(logger),
 			makeEmptyDecoder(logger),
-			makeEncodeHeartbeatResponse(logger)),
+			makeEncodeHeartbeatResponse(logger),
+			options...),
 	)
-
-	return router
 }
 
 // MakeGetHeartbeatEndpoint service endpoint is a heartbeat.
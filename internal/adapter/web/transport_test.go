@@ -5,11 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/memorydb"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
 	"github.com/fernandoocampo/fruits/internal/adapter/web"
 	"github.com/fernandoocampo/fruits/internal/fruits"
 	"github.com/go-kit/kit/endpoint"
@@ -22,6 +28,25 @@ type webResultGetFruit struct {
 	Errors  []string   `json:"errors"`
 }
 
+type webResultImportJob struct {
+	Success bool                           `json:"success"`
+	Data    web.FruitDatasetStatusResponse `json:"data"`
+	Errors  []string                       `json:"errors"`
+}
+
+type webEnvelopeFruit struct {
+	Data *web.Fruit `json:"data"`
+}
+
+type webEnvelopeSearchFruits struct {
+	Data []web.FruitItemResult `json:"data"`
+	Meta web.SearchMeta        `json:"meta"`
+}
+
+type webEnvelopeCreatedFruit struct {
+	Data web.CreatedFruitResponse `json:"data"`
+}
+
 type webResultSearchFruits struct {
 	Success bool                    `json:"success"`
 	Data    *web.SearchFruitsResult `json:"data"`
@@ -34,6 +59,12 @@ type webResultCreateFruit struct {
 	Errors  []string `json:"errors"`
 }
 
+type webResultDeleteFruit struct {
+	Success bool     `json:"success"`
+	Data    string   `json:"data"`
+	Errors  []string `json:"errors"`
+}
+
 type webResultGetStatus struct {
 	Status    string `json:"status"`
 	Message   string `json:"message"`
@@ -187,89 +218,246 @@ func TestSearchFruitsSuccessfully(t *testing.T) {
 	assert.Equal(t, expectedResponse, result)
 }
 
-func TestGetFruitNotFound(t *testing.T) {
+func TestSearchFruitsWithFilters(t *testing.T) {
 	t.Parallel()
 
-	fruitID := "ad1a4350-978b-4a08-83f7-20199dc8f21a"
-	expectedResponse := webResultGetFruit{
-		Success: true,
-		Data:    nil,
-		Errors:  nil,
+	queryParams := "?start=2&count=5&q=+brisk+acidity+&country=Italy&variety=White+Blend&min_year=2010&max_price=20&sort=-price,name&facets=country,+variety"
+	minYear := 2010
+	maxPrice := float32(20)
+	expectedFilter := fruits.SearchFruitFilter{
+		Start:    2,
+		Count:    5,
+		Query:    "brisk acidity",
+		Country:  "Italy",
+		Variety:  "White Blend",
+		MinYear:  &minYear,
+		MaxPrice: &maxPrice,
+		Sort:     []fruits.SortField{{Name: "price", Descending: true}, {Name: "name"}},
+		Facets:   []string{"country", "variety"},
 	}
 	fruitEndpoints := fruits.Endpoints{
-		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, nil, nil),
+		SearchFruitsEndpoint: makeDummySearchFruitsSuccessfullyEndpoint(t, expectedFilter, &fruits.SearchFruitsResult{}, nil),
 	}
-	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
-	httpHandler := web.NewHTTPServer(fruitEndpoints, logger)
-	dummyServer := httptest.NewServer(httpHandler)
-	defer dummyServer.Close()
 
-	ctx := context.TODO()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dummyServer.URL+"/fruit/"+fruitID, nil)
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+	var result webResultSearchFruits
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit"+queryParams, nil, nil, &result)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, result.Success)
+}
+
+func TestSearchFruitsWithInvalidFilters(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		queryParams string
+		wantError   string
+	}{
+		"start_is_not_a_number": {
+			queryParams: "?start=first",
+			wantError:   "invalid filter start: must be an integer",
+		},
+		"year_is_not_a_number": {
+			queryParams: "?min_year=old",
+			wantError:   "invalid filter min_year: must be an integer",
+		},
+		"price_is_not_a_number": {
+			queryParams: "?max_price=cheap",
+			wantError:   "invalid filter max_price: must be a number",
+		},
+		"price_is_not_finite": {
+			queryParams: "?min_price=NaN",
+			wantError:   "invalid filter min_price: must be a number",
+		},
+		"cursor_with_start": {
+			queryParams: "?cursor=abc.def&start=3",
+			wantError:   "invalid filter cursor: cannot be combined with start",
+		},
+		"empty_sort_field": {
+			queryParams: "?sort=price,,name",
+			wantError:   "invalid filter sort: field names must not be empty",
+		},
+		"empty_facet_field": {
+			queryParams: "?facets=country,",
+			wantError:   "invalid filter facets: field names must not be empty",
+		},
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				SearchFruitsEndpoint: makeUnexpectedEndpoint(st),
+			}
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruitEndpoints, http.MethodGet, "/fruit"+test.queryParams, nil, nil, &result)
+
+			assert.Equal(st, http.StatusBadRequest, response.StatusCode)
+			assert.Equal(st, test.wantError, result.Detail)
+			assert.Len(st, result.InvalidParams, 1)
+		})
 	}
-	defer response.Body.Close()
+}
 
-	var result webResultGetFruit
+func TestSearchFruitsWithCursor(t *testing.T) {
+	t.Parallel()
 
-	err = json.NewDecoder(response.Body).Decode(&result)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	expectedFilter := fruits.SearchFruitFilter{
+		Start:  1,
+		Count:  2,
+		Cursor: "abc.def",
+	}
+	serviceResult := fruits.SearchFruitsResult{
+		Fruits:     []fruits.FruitItem{{ID: "1234"}},
+		Total:      3,
+		Start:      1,
+		Count:      2,
+		NextCursor: "ghi.jkl",
+	}
+	fruitEndpoints := fruits.Endpoints{
+		SearchFruitsEndpoint: makeDummySearchFruitsSuccessfullyEndpoint(t, expectedFilter, &serviceResult, nil),
 	}
 
-	assert.Equal(t, expectedResponse, result)
+	var result webResultSearchFruits
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit?count=2&cursor=abc.def", nil, nil, &result)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "ghi.jkl", result.Data.NextCursor)
 }
 
-func TestGetFruitWithError(t *testing.T) {
+func TestSearchFruitsWithFacets(t *testing.T) {
 	t.Parallel()
 
-	fruitID := "1234"
-	expectedResponse := webResultGetFruit{
-		Success: false,
-		Data:    nil,
-		Errors:  []string{"any error"},
+	expectedFilter := fruits.SearchFruitFilter{
+		Start:  1,
+		Count:  10,
+		Facets: []string{"country"},
+	}
+	serviceResult := fruits.SearchFruitsResult{
+		Fruits: []fruits.FruitItem{{ID: "1234"}},
+		Total:  3,
+		Start:  1,
+		Count:  10,
+		Facets: map[string][]fruits.FacetBucket{
+			"country": {{Value: "Italy", Count: 2}, {Value: "Portugal", Count: 1}},
+		},
+	}
+	expectedFacets := web.Facets{
+		"country": {{Value: "Italy", Count: 2}, {Value: "Portugal", Count: 1}},
 	}
-	errorToReturn := errAnyError
 	fruitEndpoints := fruits.Endpoints{
-		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, nil, errorToReturn),
+		SearchFruitsEndpoint: makeDummySearchFruitsSuccessfullyEndpoint(t, expectedFilter, &serviceResult, nil),
 	}
-	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
-	httpHandler := web.NewHTTPServer(fruitEndpoints, logger)
-	dummyServer := httptest.NewServer(httpHandler)
-	defer dummyServer.Close()
 
-	ctx := context.TODO()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dummyServer.URL+"/fruit/"+fruitID, nil)
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+	var result webResultSearchFruits
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit?facets=country", nil, nil, &result)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, expectedFacets, result.Data.Facets)
+}
+
+func TestSearchFruitsRejectedByService(t *testing.T) {
+	t.Parallel()
+
+	expectedProblem := web.Problem{
+		Type:          "about:blank",
+		Title:         "Bad Request",
+		Status:        http.StatusBadRequest,
+		Detail:        "invalid filter min_year: must not be greater than max_year",
+		Instance:      "/fruit",
+		InvalidParams: []web.InvalidParam{{Name: "min_year", Reason: "must not be greater than max_year"}},
+	}
+	fruitService := fruits.NewService(&staleRepository{}, nil, loggers.NewLoggerWithStdout("", loggers.Error))
+	fruitEndpoints := fruits.Endpoints{
+		SearchFruitsEndpoint: fruits.MakeSearchFruitsEndpoint(fruitService, loggers.NewLoggerWithStdout("", loggers.Error)),
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	var result web.Problem
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit?min_year=2015&max_year=2010", nil, nil, &result)
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "application/problem+json", response.Header.Get("Content-Type"))
+	assert.Equal(t, expectedProblem, result)
+}
+
+func TestGetFruitNotFound(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "ad1a4350-978b-4a08-83f7-20199dc8f21a"
+	expectedProblem := web.Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "record not found",
+		Instance: "/fruit/" + fruitID,
+	}
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, nil, nil),
 	}
-	defer response.Body.Close()
 
-	var result webResultGetFruit
+	var result web.Problem
 
-	err = json.NewDecoder(response.Body).Decode(&result)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit/"+fruitID, nil, nil, &result)
+
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, "application/problem+json", response.Header.Get("Content-Type"))
+	assert.Equal(t, expectedProblem, result)
+}
+
+func TestGetFruitWithError(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err             error
+		expectedProblem web.Problem
+	}{
+		"unexpected_error": {
+			err: errAnyError,
+			expectedProblem: web.Problem{
+				Type:     "about:blank",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "the request could not be processed because of an unexpected error",
+				Instance: "/fruit/1234",
+			},
+		},
+		"data_access_error": {
+			err: fruits.ErrDataAccess,
+			expectedProblem: web.Problem{
+				Type:     "about:blank",
+				Title:    "Service Unavailable",
+				Status:   http.StatusServiceUnavailable,
+				Detail:   fruits.ErrDataAccess.Error(),
+				Instance: "/fruit/1234",
+			},
+		},
 	}
 
-	assert.Equal(t, expectedResponse, result)
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(st, nil, test.err),
+			}
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruitEndpoints, http.MethodGet, "/fruit/1234", nil, nil, &result)
+
+			assert.Equal(st, test.expectedProblem.Status, response.StatusCode)
+			assert.Equal(st, test.expectedProblem, result)
+		})
+	}
 }
 
 func TestPostFruitSuccessfully(t *testing.T) {
@@ -362,10 +550,12 @@ func TestPostFruitWithError(t *testing.T) {
 	dummyServer := httptest.NewServer(fruitHandler)
 	defer dummyServer.Close()
 
-	expectedResponse := webResultCreateFruit{
-		Success: false,
-		Data:    "",
-		Errors:  []string{"any error"},
+	expectedProblem := web.Problem{
+		Type:     "about:blank",
+		Title:    "Internal Server Error",
+		Status:   http.StatusInternalServerError,
+		Detail:   "the request could not be processed because of an unexpected error",
+		Instance: "/fruit",
 	}
 
 	ctx := context.TODO()
@@ -381,7 +571,7 @@ func TestPostFruitWithError(t *testing.T) {
 	}
 	defer response.Body.Close()
 
-	var result webResultCreateFruit
+	var result web.Problem
 
 	err = json.NewDecoder(response.Body).Decode(&result)
 	if err != nil {
@@ -389,51 +579,1659 @@ func TestPostFruitWithError(t *testing.T) {
 		t.FailNow()
 	}
 
-	assert.Equal(t, expectedResponse, result)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	assert.Equal(t, expectedProblem, result)
 }
 
-func TestStatusSuccessfully(t *testing.T) {
+func TestCreateFruitForwardsIdempotencyKey(t *testing.T) {
 	t.Parallel()
 
-	expectedResponse := webResultGetStatus{
-		Status:    "ok",
-		Message:   "",
-		Timestamp: 1234,
+	newFruit := web.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
 	}
-	status := fruits.DatasetStateOK
-	errorMessage := ""
-	timeStamp := int64(1234)
 	fruitEndpoints := fruits.Endpoints{
-		GetStatusEndpoint: makeDummyGetStatusEndpoint(status, errorMessage, timeStamp),
+		CreateFruitEndpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+			assert.Equal(t, "3a5f7c1e-2b4d", fruits.IdempotencyKeyFromContext(ctx))
+
+			return fruits.CreateFruitResult{ID: "1234"}, nil
+		},
 	}
-	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
-	httpHandler := web.NewHTTPServer(fruitEndpoints, logger)
-	dummyServer := httptest.NewServer(httpHandler)
-	defer dummyServer.Close()
+	header := http.Header{}
+	header.Set("Idempotency-Key", "3a5f7c1e-2b4d")
 
-	ctx := context.TODO()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dummyServer.URL+"/status", nil)
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+	var result webResultCreateFruit
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodPut, "/fruit", header, newFruit, &result)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "1234", result.Data)
+}
+
+func TestCreateFruitWithInvalidIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		key string
+	}{
+		"too_long": {
+			key: strings.Repeat("k", 256),
+		},
+		"with_spaces": {
+			key: "3a5f 7c1e",
+		},
+		"not_ascii": {
+			key: "3a5f7c1eñ",
+		},
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				CreateFruitEndpoint: makeUnexpectedEndpoint(st),
+			}
+			header := http.Header{}
+			header.Set("Idempotency-Key", test.key)
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruitEndpoints, http.MethodPut, "/fruit", header, web.NewFruit{Name: "Nicosia"}, &result)
+
+			assert.Equal(st, http.StatusBadRequest, response.StatusCode)
+			assert.Equal(st, "Idempotency-Key header must have between 1 and 255 visible ascii characters", result.Detail)
+		})
 	}
-	defer response.Body.Close()
+}
 
-	var result webResultGetStatus
+func TestCreateFruitWithUnusableIdempotencyKey(t *testing.T) {
+	t.Parallel()
 
-	err = json.NewDecoder(response.Body).Decode(&result)
-	if err != nil {
-		t.Error("unexpected error", err)
-		t.FailNow()
+	cases := map[string]struct {
+		err            error
+		expectedStatus int
+	}{
+		"in_use": {
+			err:            fruits.ErrIdempotencyKeyInUse,
+			expectedStatus: http.StatusConflict,
+		},
+		"reused": {
+			err:            fruits.ErrIdempotencyKeyReused,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 	}
 
-	assert.Equal(t, expectedResponse, result)
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				CreateFruitEndpoint: makeDummyCreateFruitSuccessfullyEndpoint(st, "", test.err),
+			}
+			header := http.Header{}
+			header.Set("Idempotency-Key", "3a5f7c1e")
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruitEndpoints, http.MethodPut, "/fruit", header, web.NewFruit{Name: "Nicosia"}, &result)
+
+			assert.Equal(st, test.expectedStatus, response.StatusCode)
+			assert.Equal(st, test.expectedStatus, result.Status)
+			assert.Equal(st, test.err.Error(), result.Detail)
+		})
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"json_array": {
+			contentType: "application/json",
+			body:        `[{"name":"Nicosia 2013 Vulka Bianco  (Etna)","price":12.5},{"name":"Quinta dos Avidagos"}]`,
+		},
+		"ndjson": {
+			contentType: "application/x-ndjson",
+			body:        "{\"name\":\"Nicosia 2013 Vulka Bianco  (Etna)\",\"price\":12.5}\n{\"name\":\"Quinta dos Avidagos\"}\n",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			expectedFruits := []fruits.NewFruit{
+				{Name: "Nicosia 2013 Vulka Bianco  (Etna)", Price: 12.5},
+				{Name: "Quinta dos Avidagos"},
+			}
+			rejection := fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "vault", Reason: "is mandatory"}},
+			}
+			fruitEndpoints := fruits.Endpoints{
+				CreateBatchEndpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+					batchRequest, ok := request.(*fruits.CreateBatchRequest)
+					if !ok {
+						st.Fatalf("batch parameter is not valid: %T", request)
+					}
+
+					assert.Equal(st, expectedFruits, batchRequest.Fruits)
+
+					return fruits.CreateBatchResult{
+						Items: []fruits.BatchItemResult{
+							{Index: 0, ID: "1234"},
+							{Index: 1, Err: rejection},
+						},
+						Created:  1,
+						Rejected: 1,
+					}, nil
+				},
+			}
+			expectedResponse := web.CreateBatchResponse{
+				Created:  1,
+				Rejected: 1,
+				Items: []web.BatchItemResponse{
+					{Index: 0, ID: "1234"},
+					{
+						Index: 1,
+						Problem: &web.Problem{
+							Type:          "about:blank",
+							Title:         "Unprocessable Entity",
+							Status:        http.StatusUnprocessableEntity,
+							Detail:        "invalid fruit: vault is mandatory",
+							InvalidParams: []web.InvalidParam{{Name: "vault", Reason: "is mandatory"}},
+						},
+					},
+				},
+			}
+
+			response, body := doRawRequest(st, fruitEndpoints, http.MethodPost, "/fruit/batch", test.contentType, test.body)
+
+			var result struct {
+				Success bool                    `json:"success"`
+				Data    web.CreateBatchResponse `json:"data"`
+			}
+
+			err := json.Unmarshal(body, &result)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			assert.Equal(st, http.StatusOK, response.StatusCode)
+			assert.True(st, result.Success)
+			assert.Equal(st, expectedResponse, result.Data)
+		})
+	}
+}
+
+func TestCreateBatchRejectedAsAWhole(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		"malformed_line": {
+			contentType:    "application/x-ndjson",
+			body:           "{\"name\":\"Nicosia\"}\n{\"name\":\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"too_large": {
+			contentType:    "application/x-ndjson",
+			body:           strings.Repeat("{\"name\":\"Nicosia\"}\n", fruits.MaxBatchSize+1),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		"json_body_too_large": {
+			contentType:    "application/json",
+			body:           `[{"name":"Nicosia","description":"` + strings.Repeat("a", 8<<20) + `"}]`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		"ndjson_body_too_large": {
+			contentType:    "application/x-ndjson",
+			body:           `{"name":"Nicosia","description":"` + strings.Repeat("a", 8<<20) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				CreateBatchEndpoint: makeUnexpectedEndpoint(st),
+			}
+
+			response, body := doRawRequest(st, fruitEndpoints, http.MethodPost, "/fruit/batch", test.contentType, test.body)
+
+			var result web.Problem
+
+			err := json.Unmarshal(body, &result)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			assert.Equal(st, test.expectedStatus, response.StatusCode)
+			assert.Equal(st, test.expectedStatus, result.Status)
+		})
+	}
+}
+
+func TestExportFruits(t *testing.T) {
+	t.Parallel()
+
+	storedFruits := []repository.Fruit{
+		{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)", Year: 2013, Price: repository.FruitPrice(12.5), Country: "Italy", Version: 1},
+		{ID: "1240", Name: "Quinta dos Avidagos", Description: "ripe, fruity", Version: 2},
+	}
+	csvExport := "id,country,description,classification,year,price,province,region,finca,local_name,wiki_page,name,variety,vault\n" +
+		"1234,Italy,,,2013,12.5,,,,,,Nicosia 2013 Vulka Bianco  (Etna),,\n" +
+		"1240,,\"ripe, fruity\",,,,,,,,,Quinta dos Avidagos,,\n"
+	ndjsonExport := `{"id":"1234","name":"Nicosia 2013 Vulka Bianco  (Etna)","variety":"","vault":"","year":2013,"price":12.5,"country":"Italy","province":"","description":"","classification":"","local_name":"","wiki_page":"","version":1}` + "\n" +
+		`{"id":"1240","name":"Quinta dos Avidagos","variety":"","vault":"","year":0,"country":"","province":"","description":"ripe, fruity","classification":"","local_name":"","wiki_page":"","version":2}` + "\n"
+	jsonExport := "[" + strings.Replace(ndjsonExport, "}\n{", "}\n,{", 1) + "]\n"
+
+	cases := map[string]struct {
+		path                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		"default": {
+			path:                "/fruit/export",
+			expectedContentType: "application/json",
+			expectedBody:        jsonExport,
+		},
+		"csv_parameter": {
+			path:                "/fruit/export?format=csv",
+			accept:              "application/json",
+			expectedContentType: "text/csv",
+			expectedBody:        csvExport,
+		},
+		"ndjson_parameter": {
+			path:                "/fruit/export?format=ndjson",
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        ndjsonExport,
+		},
+		"csv_accept": {
+			path:                "/fruit/export",
+			accept:              "application/xml, text/*;q=0.5, application/json;q=0.2",
+			expectedContentType: "text/csv",
+			expectedBody:        csvExport,
+		},
+		"any_accept": {
+			path:                "/fruit/export",
+			accept:              "*/*",
+			expectedContentType: "application/json",
+			expectedBody:        jsonExport,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				ExportFruitsEndpoint: makeExportFruitsEndpoint(&exportRepository{fruits: storedFruits}),
+			}
+
+			response, body := doGetRequest(st, fruitEndpoints, test.path, test.accept)
+
+			assert.Equal(st, http.StatusOK, response.StatusCode)
+			assert.Equal(st, test.expectedContentType, response.Header.Get("Content-Type"))
+			assert.Equal(st, test.expectedBody, string(body))
+		})
+	}
+}
+
+func TestExportFruitsFails(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		path           string
+		accept         string
+		repository     *exportRepository
+		expectedStatus int
+	}{
+		"invalid_format": {
+			path:           "/fruit/export?format=xml",
+			repository:     &exportRepository{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not_acceptable": {
+			path:           "/fruit/export",
+			accept:         "application/xml, text/csv;q=0",
+			repository:     &exportRepository{},
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		"unreachable_repository": {
+			path:           "/fruit/export",
+			repository:     &exportRepository{err: errAnyError},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				ExportFruitsEndpoint: makeExportFruitsEndpoint(test.repository),
+			}
+
+			response, body := doGetRequest(st, fruitEndpoints, test.path, test.accept)
+
+			var result web.Problem
+
+			err := json.Unmarshal(body, &result)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			assert.Equal(st, test.expectedStatus, response.StatusCode)
+			assert.Equal(st, test.expectedStatus, result.Status)
+		})
+	}
+}
+
+func TestImportJob(t *testing.T) {
+	t.Parallel()
+
+	datasetCSV := "id,country,classification,year,name,vault\n" +
+		"1,Italy,Vulka Bianco,2013,Nicosia 2013 Vulka Bianco  (Etna),Nicosia\n" +
+		"2,Portugal,Avidagos,not-a-year,Quinta dos Avidagos 2011 Avidagos Red (Douro),Quinta dos Avidagos\n"
+	expectedReport := "line,reason\n3,year must be an integer\n"
+
+	var multipartBody bytes.Buffer
+
+	form := multipart.NewWriter(&multipartBody)
+
+	file, err := form.CreateFormFile("file", "fruits.csv")
+	if err != nil {
+		t.Fatalf("unexpected error creating form: %s", err)
+	}
+
+	_, _ = file.Write([]byte(datasetCSV))
+	form.Close()
+
+	cases := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"csv_body": {
+			contentType: "text/csv",
+			body:        datasetCSV,
+		},
+		"multipart_form": {
+			contentType: form.FormDataContentType(),
+			body:        multipartBody.String(),
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := makeMemoryEndpoints()
+
+			response, body := doRawRequest(st, fruitEndpoints, http.MethodPost, "/fruit/import", test.contentType, test.body)
+
+			var started webResultImportJob
+
+			err := json.Unmarshal(body, &started)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			location := response.Header.Get("Location")
+
+			assert.Equal(st, http.StatusAccepted, response.StatusCode)
+			assert.Equal(st, "/fruit/import/"+started.Data.JobID, location)
+
+			var got webResultImportJob
+
+			assert.Eventually(st, func() bool {
+				doJSONRequest(st, fruitEndpoints, http.MethodGet, location, nil, nil, &got)
+
+				return got.Data.Status != string(fruits.DatasetStateLoading)
+			}, 5*time.Second, 10*time.Millisecond)
+
+			reportResponse, report := doRawRequest(st, fruitEndpoints, http.MethodGet, location+"/report", "", "")
+			cancelResponse, _ := doRawRequest(st, fruitEndpoints, http.MethodDelete, location, "", "")
+
+			assert.Equal(st, string(fruits.DatasetStateError), got.Data.Status)
+			assert.Equal(st, 2, got.Data.Processed)
+			assert.Equal(st, 1, got.Data.Loaded)
+			assert.Equal(st, 1, got.Data.Rejected)
+			assert.Equal(st, "text/csv", reportResponse.Header.Get("Content-Type"))
+			assert.Equal(st, expectedReport, string(report))
+			assert.Equal(st, http.StatusConflict, cancelResponse.StatusCode)
+		})
+	}
+}
+
+func TestExportedFruitsCanBeImportedAgain(t *testing.T) {
+	t.Parallel()
+
+	datasetCSV := "country,classification,year,name,vault\n" +
+		"Italy,Vulka Bianco,2013,Nicosia 2013 Vulka Bianco  (Etna),Nicosia\n" +
+		"Portugal,Avidagos,2011,Quinta dos Avidagos 2011 Avidagos Red (Douro),Quinta dos Avidagos\n"
+	fruitEndpoints := makeMemoryEndpoints()
+
+	loaded := importDataset(t, fruitEndpoints, datasetCSV)
+	_, export := doGetRequest(t, fruitEndpoints, "/fruit/export?format=csv", "")
+	imported := importDataset(t, fruitEndpoints, string(export))
+	_, got := doGetRequest(t, fruitEndpoints, "/fruit/export?format=csv", "")
+
+	assert.Equal(t, 2, loaded.Data.Loaded)
+	assert.Equal(t, string(fruits.DatasetStateOK), imported.Data.Status)
+	assert.Equal(t, 2, imported.Data.Loaded)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(got)), "\n"), 3)
+	assert.Equal(t, string(export), string(got))
+}
+
+func TestImportJobFails(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		"unknown_job": {
+			method:         http.MethodGet,
+			path:           "/fruit/import/4b1d6d2a",
+			expectedStatus: http.StatusNotFound,
+		},
+		"unknown_job_report": {
+			method:         http.MethodGet,
+			path:           "/fruit/import/4b1d6d2a/report",
+			expectedStatus: http.StatusNotFound,
+		},
+		"cancel_unknown_job": {
+			method:         http.MethodDelete,
+			path:           "/fruit/import/4b1d6d2a",
+			expectedStatus: http.StatusNotFound,
+		},
+		"form_without_file": {
+			method:         http.MethodPost,
+			path:           "/fruit/import",
+			contentType:    "multipart/form-data; boundary=fruits",
+			body:           "--fruits\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nfruits.csv\r\n--fruits--\r\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			response, body := doRawRequest(st, makeMemoryEndpoints(), test.method, test.path, test.contentType, test.body)
+
+			var result web.Problem
+
+			err := json.Unmarshal(body, &result)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			assert.Equal(st, test.expectedStatus, response.StatusCode)
+			assert.Equal(st, test.expectedStatus, result.Status)
+		})
+	}
+}
+
+func TestGetFruitRepresentations(t *testing.T) {
+	t.Parallel()
+
+	fruit := fruits.Fruit{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)", Country: "Italy", Year: 2013, Price: 12.5, Version: 2}
+	jsonFruit := `{"success":true,"data":{"id":"1234","name":"Nicosia 2013 Vulka Bianco  (Etna)","variety":"","vault":"","year":2013,"price":12.5,"country":"Italy","province":"","description":"","classification":"","local_name":"","wiki_page":"","version":2},"errors":null}` + "\n"
+	xmlFruit := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<result><success>true</success><data><id>1234</id><name>Nicosia 2013 Vulka Bianco  (Etna)</name><variety></variety><vault></vault><year>2013</year><price>12.5</price><country>Italy</country><province></province><description></description><classification></classification><local_name></local_name><wiki_page></wiki_page><version>2</version></data><errors></errors></result>`
+	csvFruit := "id,country,description,classification,year,price,province,region,finca,local_name,wiki_page,name,variety,vault\n" +
+		"1234,Italy,,,2013,12.5,,,,,,Nicosia 2013 Vulka Bianco  (Etna),,\n"
+
+	cases := map[string]struct {
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		"no_accept": {
+			expectedContentType: "application/json",
+			expectedBody:        jsonFruit,
+		},
+		"xml": {
+			accept:              "application/xml",
+			expectedContentType: "application/xml",
+			expectedBody:        xmlFruit,
+		},
+		"any_text": {
+			accept:              "text/*",
+			expectedContentType: "text/xml",
+			expectedBody:        xmlFruit,
+		},
+		"preferred_csv": {
+			accept:              "application/xml;q=0.5, text/csv;q=0.9",
+			expectedContentType: "text/csv",
+			expectedBody:        csvFruit,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(st, &fruit, nil),
+			}
+
+			response, body := doGetRequest(st, fruitEndpoints, "/fruit/1234", test.accept)
+
+			assert.Equal(st, http.StatusOK, response.StatusCode)
+			assert.Equal(st, test.expectedContentType, response.Header.Get("Content-Type"))
+			assert.Equal(st, "Accept", response.Header.Get("Vary"))
+			assert.Equal(st, test.expectedBody, string(body))
+		})
+	}
+}
+
+func TestSearchFruitsAsCSV(t *testing.T) {
+	t.Parallel()
+
+	expectedFilter := fruits.SearchFruitFilter{
+		Start:   1,
+		Count:   2,
+		Country: "Italy",
+	}
+	serviceResult := fruits.SearchFruitsResult{
+		Fruits: []fruits.FruitItem{
+			{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)"},
+			{ID: "1240", Name: "Quinta dos Avidagos, Red"},
+		},
+		Total:      3,
+		Start:      1,
+		Count:      2,
+		NextCursor: "eyJrIjoiMTI0MCJ9",
+	}
+	expectedBody := "id,name\n1234,Nicosia 2013 Vulka Bianco  (Etna)\n1240,\"Quinta dos Avidagos, Red\"\n"
+	fruitEndpoints := fruits.Endpoints{
+		SearchFruitsEndpoint: makeDummySearchFruitsSuccessfullyEndpoint(t, expectedFilter, &serviceResult, nil),
+	}
+
+	response, body := doGetRequest(t, fruitEndpoints, "/fruit?start=1&count=2&country=Italy", "text/csv")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/csv", response.Header.Get("Content-Type"))
+	assert.Equal(t, `</fruit?count=2&country=Italy&cursor=eyJrIjoiMTI0MCJ9>; rel="next"`, response.Header.Get("Link"))
+	assert.Equal(t, expectedBody, string(body))
+}
+
+func TestStatusRepresentations(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		"xml": {
+			accept:              "application/xml",
+			expectedContentType: "application/xml",
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<dataset_status><status>error</status><message>dataset could not be read</message><timestamp>1234</timestamp><processed>0</processed><loaded>0</loaded><rejected>0</rejected><rejections></rejections></dataset_status>`,
+		},
+		"csv": {
+			accept:              "text/csv",
+			expectedContentType: "text/csv",
+			expectedBody:        "job_id,status,message,timestamp,processed,loaded,rejected\n,error,dataset could not be read,1234,0,0,0\n",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				GetStatusEndpoint: makeDummyGetStatusEndpoint(fruits.DatasetStateError, "dataset could not be read", 1234),
+			}
+
+			response, body := doGetRequest(st, fruitEndpoints, "/status", test.accept)
+
+			assert.Equal(st, http.StatusOK, response.StatusCode)
+			assert.Equal(st, test.expectedContentType, response.Header.Get("Content-Type"))
+			assert.Equal(st, test.expectedBody, string(body))
+		})
+	}
+}
+
+func TestNotAcceptableResponses(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeUnexpectedEndpoint(t),
+		SearchFruitsEndpoint:   makeUnexpectedEndpoint(t),
+		GetStatusEndpoint:      makeUnexpectedEndpoint(t),
+	}
+
+	for _, path := range []string{"/fruit/1234", "/fruit?country=Italy", "/status"} {
+		response, body := doGetRequest(t, fruitEndpoints, path, "image/png, application/json;q=0")
+
+		var result web.Problem
+
+		err := json.Unmarshal(body, &result)
+		if err != nil {
+			t.Fatalf("unexpected error decoding response: %s", err)
+		}
+
+		assert.Equal(t, http.StatusNotAcceptable, response.StatusCode, path)
+		assert.Equal(t, http.StatusNotAcceptable, result.Status, path)
+	}
+}
+
+func TestV1RoutesArePrefixed(t *testing.T) {
+	t.Parallel()
+
+	fruit := fruits.Fruit{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)", Country: "Italy", Version: 1}
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, &fruit, nil),
+		GetStatusEndpoint:      makeDummyGetStatusEndpoint(fruits.DatasetStateOK, "", 1234),
+	}
+
+	for _, path := range []string{"/fruit/1234", "/status", "/heartbeat"} {
+		unversioned, unversionedBody := doGetRequest(t, fruitEndpoints, path, "")
+		versioned, versionedBody := doGetRequest(t, fruitEndpoints, "/v1"+path, "")
+
+		assert.Equal(t, http.StatusOK, unversioned.StatusCode, path)
+		assert.Equal(t, http.StatusOK, versioned.StatusCode, path)
+		assert.Equal(t, string(unversionedBody), string(versionedBody), path)
+	}
+}
+
+func TestV2FruitLifecycle(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := makeMemoryEndpoints()
+	newFruit := web.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+		Year:           2013,
+	}
+
+	var created webEnvelopeCreatedFruit
+
+	createResponse := doJSONRequest(t, fruitEndpoints, http.MethodPost, "/v2/fruits", nil, newFruit, &created)
+
+	location := createResponse.Header.Get("Location")
+
+	assert.Equal(t, http.StatusCreated, createResponse.StatusCode)
+	assert.Equal(t, "/v2/fruits/"+created.Data.ID, location)
+
+	var got webEnvelopeFruit
+
+	getResponse := doJSONRequest(t, fruitEndpoints, http.MethodGet, location, nil, nil, &got)
+
+	assert.Equal(t, http.StatusOK, getResponse.StatusCode)
+	assert.Equal(t, `"1-v2"`, getResponse.Header.Get("ETag"))
+	assert.Equal(t, created.Data.ID, got.Data.ID)
+	assert.Equal(t, newFruit.Name, got.Data.Name)
+
+	var found webEnvelopeSearchFruits
+
+	searchResponse := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/v2/fruits?country=Italy", nil, nil, &found)
+
+	assert.Equal(t, http.StatusOK, searchResponse.StatusCode)
+	assert.Equal(t, []web.FruitItemResult{{ID: created.Data.ID, Name: newFruit.Name}}, found.Data)
+	assert.Equal(t, 1, found.Meta.Total)
+
+	// the etag of the read is sent back as it is.
+	deleteResponse, deleteBody := doRequest(t, fruitEndpoints, http.MethodDelete, location, ifMatch(getResponse.Header.Get("ETag")), "")
+
+	assert.Equal(t, http.StatusNoContent, deleteResponse.StatusCode)
+	assert.Empty(t, deleteBody)
+
+	var problem web.Problem
+
+	goneResponse := doJSONRequest(t, fruitEndpoints, http.MethodGet, location, nil, nil, &problem)
+
+	assert.Equal(t, http.StatusNotFound, goneResponse.StatusCode)
+	assert.Equal(t, location, problem.Instance)
+}
+
+func TestStatusSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	expectedResponse := webResultGetStatus{
+		Status:    "ok",
+		Message:   "",
+		Timestamp: 1234,
+	}
+	status := fruits.DatasetStateOK
+	errorMessage := ""
+	timeStamp := int64(1234)
+	fruitEndpoints := fruits.Endpoints{
+		GetStatusEndpoint: makeDummyGetStatusEndpoint(status, errorMessage, timeStamp),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	httpHandler := web.NewHTTPServer(fruitEndpoints, logger)
+	dummyServer := httptest.NewServer(httpHandler)
+	defer dummyServer.Close()
+
+	ctx := context.TODO()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dummyServer.URL+"/status", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Error("unexpected error", err)
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var result webResultGetStatus
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		t.Error("unexpected error", err)
+		t.FailNow()
+	}
+
+	assert.Equal(t, expectedResponse, result)
+}
+
+func TestUpdateFruitSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	givenFruit := web.NewFruit{
+		Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	expectedRequest := fruits.UpdateFruitRequest{
+		ID:      fruitID,
+		Version: 1,
+		Fruit: fruits.NewFruit{
+			Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+			Vault:          "Nicosia",
+			Country:        "Italy",
+			Classification: "Vulka Bianco",
+		},
+	}
+	fruitToReturn := expectedRequest.Fruit.NewFruit(fruitID)
+	fruitToReturn.Version = 2
+	expectedResponse := webResultGetFruit{
+		Success: true,
+		Data: &web.Fruit{
+			ID:             fruitID,
+			Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+			Vault:          "Nicosia",
+			Country:        "Italy",
+			Classification: "Vulka Bianco",
+			Version:        2,
+		},
+	}
+	fruitEndpoints := fruits.Endpoints{
+		UpdateFruitEndpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+			assert.Equal(t, &expectedRequest, request)
+
+			return fruits.UpdateFruitResult{Fruit: &fruitToReturn}, nil
+		},
+	}
+
+	var result webResultGetFruit
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodPut, "/fruit/"+fruitID, ifMatch(`"1"`), givenFruit, &result)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, `"2"`, response.Header.Get("ETag"))
+	assert.Equal(t, expectedResponse, result)
+}
+
+func TestPatchFruitSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	newName := "Nicosia 2014 Vulka Bianco  (Etna)"
+	expectedRequest := fruits.PatchFruitRequest{
+		ID:      fruitID,
+		Version: fruits.AnyVersion,
+		Patch: fruits.FruitPatch{
+			Name: &newName,
+		},
+	}
+	expectedResponse := webResultGetFruit{
+		Success: true,
+		Data: &web.Fruit{
+			ID:   fruitID,
+			Name: newName,
+		},
+	}
+	fruitEndpoints := fruits.Endpoints{
+		PatchFruitEndpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+			assert.Equal(t, &expectedRequest, request)
+
+			return fruits.UpdateFruitResult{Fruit: &fruits.Fruit{ID: fruitID, Name: newName}}, nil
+		},
+	}
+
+	var result webResultGetFruit
+
+	doJSONRequest(t, fruitEndpoints, http.MethodPatch, "/fruit/"+fruitID, ifMatch("*"), map[string]string{"name": newName}, &result)
+
+	assert.Equal(t, expectedResponse, result)
+}
+
+func TestDeleteFruitNotFound(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	expectedProblem := web.Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "record not found",
+		Instance: "/fruit/" + fruitID,
+	}
+	fruitEndpoints := fruits.Endpoints{
+		DeleteFruitEndpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+			assert.Equal(t, &fruits.DeleteFruitRequest{ID: fruitID, Version: 3}, request)
+
+			return nil, fruits.ErrFruitNotFound
+		},
+	}
+
+	var result web.Problem
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodDelete, "/fruit/"+fruitID, ifMatch(`"3"`), nil, &result)
+
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, expectedProblem, result)
+}
+
+func TestStaleUpdateFails(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	givenFruit := web.NewFruit{
+		Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	expectedProblem := web.Problem{
+		Type:     "about:blank",
+		Title:    "Precondition Failed",
+		Status:   http.StatusPreconditionFailed,
+		Detail:   fruits.ErrVersionConflict.Error(),
+		Instance: "/fruit/" + fruitID,
+	}
+	fruitService := fruits.NewService(&staleRepository{}, nil, loggers.NewLoggerWithStdout("", loggers.Error))
+	fruitEndpoints := fruits.Endpoints{
+		UpdateFruitEndpoint: fruits.MakeUpdateFruitEndpoint(fruitService, loggers.NewLoggerWithStdout("", loggers.Error)),
+	}
+
+	var result web.Problem
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodPut, "/fruit/"+fruitID, ifMatch(`"1"`), givenFruit, &result)
+
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	assert.Equal(t, expectedProblem, result)
+}
+
+func TestMutationsRequireIfMatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		method     string
+		header     http.Header
+		wantStatus int
+	}{
+		"update_without_if_match": {
+			method:     http.MethodPut,
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		"patch_without_if_match": {
+			method:     http.MethodPatch,
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		"delete_without_if_match": {
+			method:     http.MethodDelete,
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		"delete_with_invalid_if_match": {
+			method:     http.MethodDelete,
+			header:     ifMatch("three"),
+			wantStatus: http.StatusBadRequest,
+		},
+		"delete_with_negative_if_match": {
+			method:     http.MethodDelete,
+			header:     ifMatch(`"-1"`),
+			wantStatus: http.StatusBadRequest,
+		},
+		"delete_with_unknown_if_match": {
+			method:     http.MethodDelete,
+			header:     ifMatch(`"3-yaml"`),
+			wantStatus: http.StatusBadRequest,
+		},
+		"delete_with_weak_if_match": {
+			method:     http.MethodDelete,
+			header:     ifMatch(`W/"3"`),
+			wantStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				UpdateFruitEndpoint: makeUnexpectedEndpoint(st),
+				PatchFruitEndpoint:  makeUnexpectedEndpoint(st),
+				DeleteFruitEndpoint: makeUnexpectedEndpoint(st),
+			}
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruitEndpoints, test.method, "/fruit/1234", test.header, web.NewFruit{}, &result)
+
+			assert.Equal(st, test.wantStatus, response.StatusCode)
+			assert.Equal(st, test.wantStatus, result.Status)
+			assert.NotEmpty(st, result.Detail)
+		})
+	}
+}
+
+func TestInvalidFruitIsUnprocessable(t *testing.T) {
+	t.Parallel()
+
+	expectedProblem := web.Problem{
+		Type:     "about:blank",
+		Title:    "Unprocessable Entity",
+		Status:   http.StatusUnprocessableEntity,
+		Detail:   "invalid fruit: name is mandatory, vault is mandatory, country must be an ISO 3166 country code or name, price must be a non-negative number",
+		Instance: "/fruit",
+		InvalidParams: []web.InvalidParam{
+			{Name: "name", Reason: "is mandatory"},
+			{Name: "vault", Reason: "is mandatory"},
+			{Name: "country", Reason: "must be an ISO 3166 country code or name"},
+			{Name: "price", Reason: "must be a non-negative number"},
+		},
+	}
+	fruitService := fruits.NewService(&staleRepository{}, nil, loggers.NewLoggerWithStdout("", loggers.Error))
+	fruitEndpoints := fruits.Endpoints{
+		CreateFruitEndpoint: fruits.MakeCreateFruitEndpoint(fruitService, loggers.NewLoggerWithStdout("", loggers.Error)),
+	}
+	givenFruit := web.NewFruit{
+		Country:        "Atlantis",
+		Classification: "Vulka Bianco",
+		Price:          -10,
+	}
+
+	var result web.Problem
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodPut, "/fruit", nil, givenFruit, &result)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	assert.Equal(t, expectedProblem, result)
+}
+
+func TestUnreadableRequestsAreBadRequests(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := fruits.Endpoints{
+		CreateFruitEndpoint: makeUnexpectedEndpoint(t),
+	}
+
+	var result web.Problem
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodPut, "/fruit", nil, "not a fruit", &result)
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "application/problem+json", response.Header.Get("Content-Type"))
+	assert.Equal(t, http.StatusBadRequest, result.Status)
+	assert.Equal(t, "/fruit", result.Instance)
+}
+
+func TestLargeFruitBodiesAreRejected(t *testing.T) {
+	t.Parallel()
+
+	body := `{"name":"Nicosia","description":"` + strings.Repeat("a", 64<<10) + `"}`
+
+	cases := map[string]struct {
+		method string
+		path   string
+		header http.Header
+	}{
+		"create": {
+			method: http.MethodPut,
+			path:   "/fruit",
+		},
+		"update": {
+			method: http.MethodPut,
+			path:   "/fruit/1234",
+			header: ifMatch(`"1"`),
+		},
+		"patch": {
+			method: http.MethodPatch,
+			path:   "/fruit/1234",
+			header: ifMatch(`"1"`),
+		},
+		"v2_create": {
+			method: http.MethodPost,
+			path:   "/v2/fruits",
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := fruits.Endpoints{
+				CreateFruitEndpoint: makeUnexpectedEndpoint(st),
+				UpdateFruitEndpoint: makeUnexpectedEndpoint(st),
+				PatchFruitEndpoint:  makeUnexpectedEndpoint(st),
+			}
+
+			response, responseBody := doRequest(st, fruitEndpoints, test.method, test.path, test.header, body)
+
+			var result web.Problem
+
+			err := json.Unmarshal(responseBody, &result)
+			if err != nil {
+				st.Fatalf("unexpected error decoding response: %s", err)
+			}
+
+			assert.Equal(st, http.StatusRequestEntityTooLarge, response.StatusCode)
+			assert.Equal(st, http.StatusRequestEntityTooLarge, result.Status)
+		})
+	}
+}
+
+func TestUnknownRoutesAreProblems(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		"unknown_path": {
+			method:     http.MethodGet,
+			path:       "/vegetable",
+			wantStatus: http.StatusNotFound,
+		},
+		"unknown_method": {
+			method:     http.MethodPost,
+			path:       "/fruit",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"unknown_v1_path": {
+			method:     http.MethodGet,
+			path:       "/v1/vegetable",
+			wantStatus: http.StatusNotFound,
+		},
+		"v1_path_in_v2": {
+			method:     http.MethodGet,
+			path:       "/v2/fruit",
+			wantStatus: http.StatusNotFound,
+		},
+		"unknown_v2_method": {
+			method:     http.MethodPost,
+			path:       "/v2/fruits/1234",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			var result web.Problem
+
+			response := doJSONRequest(st, fruits.Endpoints{}, test.method, test.path, nil, nil, &result)
+
+			assert.Equal(st, test.wantStatus, response.StatusCode)
+			assert.Equal(st, "application/problem+json", response.Header.Get("Content-Type"))
+			assert.Equal(st, web.Problem{
+				Type:     "about:blank",
+				Title:    http.StatusText(test.wantStatus),
+				Status:   test.wantStatus,
+				Instance: test.path,
+			}, result)
+		})
+	}
+}
+
+func TestGetFruitSetsETag(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, &fruits.Fruit{ID: "1234", Version: 7}, nil),
+	}
+
+	var result webResultGetFruit
+
+	response := doJSONRequest(t, fruitEndpoints, http.MethodGet, "/fruit/1234", nil, nil, &result)
+
+	assert.Equal(t, `"7"`, response.Header.Get("ETag"))
+	assert.Equal(t, int64(7), result.Data.Version)
+}
+
+func TestGetFruitRepresentationsHaveTheirOwnETag(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, &fruits.Fruit{ID: "1234", Version: 7}, nil),
+	}
+	cases := map[string]struct {
+		path     string
+		accept   string
+		wantETag string
+	}{
+		"v1_json": {
+			path:     "/fruit/1234",
+			accept:   "application/json",
+			wantETag: `"7"`,
+		},
+		"v1_xml": {
+			path:     "/fruit/1234",
+			accept:   "application/xml",
+			wantETag: `"7-xml"`,
+		},
+		"v1_csv": {
+			path:     "/fruit/1234",
+			accept:   "text/csv",
+			wantETag: `"7-csv"`,
+		},
+		"v2_json": {
+			path:     "/v2/fruits/1234",
+			accept:   "application/json",
+			wantETag: `"7-v2"`,
+		},
+		"v2_xml": {
+			path:     "/v2/fruits/1234",
+			accept:   "text/xml",
+			wantETag: `"7-v2-xml"`,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			response, _ := doRequest(st, fruitEndpoints, http.MethodGet, test.path, http.Header{"Accept": {test.accept}}, "")
+
+			assert.Equal(st, http.StatusOK, response.StatusCode)
+			assert.Equal(st, test.wantETag, response.Header.Get("ETag"))
+			assert.Contains(st, response.Header.Values("Vary"), "Accept")
+		})
+	}
+}
+
+func TestETagOfReadsIsTheIfMatchOfWrites(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		path   string
+		accept string
+		method string
+		body   string
+	}{
+		"v1_update": {
+			path:   "/fruit/",
+			accept: "application/json",
+			method: http.MethodPut,
+			body:   `{"name":"Nicosia 2014 Vulka Bianco  (Etna)","vault":"Nicosia","country":"Italy","classification":"Vulka Bianco"}`,
+		},
+		"v1_patch_with_xml_read": {
+			path:   "/fruit/",
+			accept: "application/xml",
+			method: http.MethodPatch,
+			body:   `{"name":"Nicosia 2014 Vulka Bianco  (Etna)"}`,
+		},
+		"v2_update": {
+			path:   "/v2/fruits/",
+			accept: "application/json",
+			method: http.MethodPut,
+			body:   `{"name":"Nicosia 2014 Vulka Bianco  (Etna)","vault":"Nicosia","country":"Italy","classification":"Vulka Bianco"}`,
+		},
+		"v2_patch_with_csv_read": {
+			path:   "/v2/fruits/",
+			accept: "text/csv",
+			method: http.MethodPatch,
+			body:   `{"name":"Nicosia 2014 Vulka Bianco  (Etna)"}`,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitEndpoints := makeMemoryEndpoints()
+			newFruit := web.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Country:        "Italy",
+				Classification: "Vulka Bianco",
+			}
+
+			var created webEnvelopeCreatedFruit
+
+			doJSONRequest(st, fruitEndpoints, http.MethodPost, "/v2/fruits", nil, newFruit, &created)
+
+			location := test.path + created.Data.ID
+			accept := http.Header{"Accept": {test.accept}}
+
+			read, _ := doRequest(st, fruitEndpoints, http.MethodGet, location, accept, "")
+			etag := read.Header.Get("ETag")
+
+			// a weak tag never matches, even the one of the current version.
+			weakWrite, _ := doRequest(st, fruitEndpoints, test.method, location, ifMatch("W/"+etag), test.body)
+
+			assert.Equal(st, http.StatusPreconditionFailed, weakWrite.StatusCode)
+
+			write, _ := doRequest(st, fruitEndpoints, test.method, location, ifMatch(etag), test.body)
+
+			assert.Equal(st, http.StatusOK, write.StatusCode)
+
+			// the write answers the tag of the new version the read gets.
+			reread, _ := doRequest(st, fruitEndpoints, http.MethodGet, location, nil, "")
+
+			assert.Equal(st, reread.Header.Get("ETag"), write.Header.Get("ETag"))
+			assert.NotEqual(st, etag, reread.Header.Get("ETag"))
+
+			staleWrite, _ := doRequest(st, fruitEndpoints, test.method, location, ifMatch(etag), test.body)
+
+			assert.Equal(st, http.StatusPreconditionFailed, staleWrite.StatusCode)
+		})
+	}
+}
+
+func TestConditionalGetFruit(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2022, time.October, 7, 10, 30, 15, 500, time.UTC)
+	fruit := fruits.Fruit{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)", Version: 3, LastModified: lastModified}
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, &fruit, nil),
+	}
+	cases := map[string]struct {
+		path       string
+		header     http.Header
+		wantStatus int
+		wantETag   string
+	}{
+		"no_conditions": {
+			path:       "/fruit/1234",
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+		},
+		"same_etag": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-None-Match": {`"3"`}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"3"`,
+		},
+		"weak_etag_in_list": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-None-Match": {`"1", W/"3"`}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"3"`,
+		},
+		"any_etag": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-None-Match": {"*"}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"3"`,
+		},
+		"old_etag": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-None-Match": {`"2"`}},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+		},
+		"not_modified_since": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"3"`,
+		},
+		"modified_since": {
+			path:       "/fruit/1234",
+			header:     http.Header{"If-Modified-Since": {lastModified.Add(-time.Second).Format(http.TimeFormat)}},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+		},
+		"etag_takes_precedence": {
+			path: "/fruit/1234",
+			header: http.Header{
+				"If-None-Match":     {`"2"`},
+				"If-Modified-Since": {lastModified.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+		},
+		"v2_same_etag": {
+			path:       "/v2/fruits/1234",
+			header:     http.Header{"If-None-Match": {`"3-v2"`}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"3-v2"`,
+		},
+		"v2_etag_of_v1": {
+			path:       "/v2/fruits/1234",
+			header:     http.Header{"If-None-Match": {`"3"`}},
+			wantStatus: http.StatusOK,
+			wantETag:   `"3-v2"`,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			response, body := doRequest(st, fruitEndpoints, http.MethodGet, test.path, test.header, "")
+
+			assert.Equal(st, test.wantStatus, response.StatusCode)
+			assert.Equal(st, test.wantETag, response.Header.Get("ETag"))
+			assert.Equal(st, "Fri, 07 Oct 2022 10:30:15 GMT", response.Header.Get("Last-Modified"))
+			assert.Equal(st, "no-cache", response.Header.Get("Cache-Control"))
+
+			if test.wantStatus == http.StatusNotModified {
+				assert.Empty(st, body)
+			} else {
+				assert.Contains(st, string(body), fruit.Name)
+			}
+		})
+	}
+}
+
+func TestConditionalSearchFruits(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2022, time.October, 7, 10, 30, 15, 0, time.UTC)
+	serviceResult := fruits.SearchFruitsResult{
+		Fruits: []fruits.FruitItem{
+			{ID: "1234", Name: "Nicosia 2013 Vulka Bianco  (Etna)", LastModified: lastModified.Add(-time.Hour)},
+			{ID: "1240", Name: "Quinta dos Avidagos 2011 Avidagos Red (Douro)", LastModified: lastModified},
+		},
+		Total: 2,
+		Start: 1,
+		Count: 10,
+	}
+	fruitEndpoints := fruits.Endpoints{
+		SearchFruitsEndpoint: makeDummySearchFruitsSuccessfullyEndpoint(t, fruits.SearchFruitFilter{Start: 1, Count: 10}, &serviceResult, nil),
+	}
+
+	response, body := doGetRequest(t, fruitEndpoints, "/fruit", "")
+	etag := response.Header.Get("ETag")
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+	assert.Equal(t, "Fri, 07 Oct 2022 10:30:15 GMT", response.Header.Get("Last-Modified"))
+	assert.NotEmpty(t, body)
+
+	notModified, notModifiedBody := doRequest(t, fruitEndpoints, http.MethodGet, "/fruit", http.Header{"If-None-Match": {etag}}, "")
+
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+	assert.Equal(t, etag, notModified.Header.Get("ETag"))
+	assert.Empty(t, notModifiedBody)
+
+	otherRepresentation, _ := doRequest(t, fruitEndpoints, http.MethodGet, "/fruit", http.Header{"If-None-Match": {etag}, "Accept": {"text/csv"}}, "")
+
+	assert.Equal(t, http.StatusOK, otherRepresentation.StatusCode)
+	assert.NotEqual(t, etag, otherRepresentation.Header.Get("ETag"))
+
+	// a deleted fruit doesn't change the newest Last-Modified of a page.
+	modifiedSince, _ := doRequest(t, fruitEndpoints, http.MethodGet, "/fruit", http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}}, "")
+
+	assert.Equal(t, http.StatusOK, modifiedSince.StatusCode)
+}
+
+func TestCacheControlIsConfigurable(t *testing.T) {
+	t.Parallel()
+
+	fruitEndpoints := fruits.Endpoints{
+		GetFruitWithIDEndpoint: makeDummyGetFruitWithIDSuccessfullyEndpoint(t, &fruits.Fruit{ID: "1234", Version: 1}, nil),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	dummyServer := httptest.NewServer(web.NewHTTPServer(fruitEndpoints, logger, web.WithCacheControl("private, max-age=60")))
+	defer dummyServer.Close()
+
+	request, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, dummyServer.URL+"/fruit/1234", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "private, max-age=60", response.Header.Get("Cache-Control"))
+	assert.Empty(t, response.Header.Get("Last-Modified"))
+}
+
+func ifMatch(etag string) http.Header {
+	header := make(http.Header)
+	header.Set("If-Match", etag)
+
+	return header
+}
+
+func makeUnexpectedEndpoint(t *testing.T) endpoint.Endpoint {
+	t.Helper()
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		t.Errorf("endpoint was not expected to be called with: %+v", request)
+
+		return nil, errAnyError
+	}
+}
+
+// staleRepository is a fruit repository whose fruits were always changed by someone else.
+type staleRepository struct {
+	fruits.Repository
+}
+
+func (s *staleRepository) FindByID(_ context.Context, fruitID repository.FruitID) (*repository.Fruit, error) {
+	return &repository.Fruit{ID: fruitID, Version: 2}, nil
+}
+
+func (s *staleRepository) Update(_ context.Context, _ repository.Fruit) error {
+	return repository.ErrVersionConflict
+}
+
+// makeMemoryEndpoints returns the endpoints of a service that stores fruits in memory.
+func makeMemoryEndpoints() fruits.Endpoints {
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(memorydb.New(memorydb.Setup{Logger: logger}), discardPublisher{}, logger)
+
+	return fruits.NewEndpoints(fruitService, logger)
+}
+
+// importDataset imports the given csv dataset and returns the status of the finished import job.
+func importDataset(t *testing.T, fruitEndpoints fruits.Endpoints, datasetCSV string) webResultImportJob {
+	t.Helper()
+
+	response, _ := doRawRequest(t, fruitEndpoints, http.MethodPost, "/fruit/import", "text/csv", datasetCSV)
+
+	var got webResultImportJob
+
+	assert.Eventually(t, func() bool {
+		doJSONRequest(t, fruitEndpoints, http.MethodGet, response.Header.Get("Location"), nil, nil, &got)
+
+		return got.Data.Status != string(fruits.DatasetStateLoading)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return got
+}
+
+// discardPublisher is a fruit publisher that drops every event.
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(_ context.Context, _ repository.Event) error {
+	return nil
+}
+
+// exportRepository is a fruit repository whose fruits fit in one search page.
+type exportRepository struct {
+	fruits.Repository
+	fruits []repository.Fruit
+	err    error
+}
+
+func (e *exportRepository) ListFruits(_ context.Context, _ *repository.Cursor, _ int) (repository.FruitPage, error) {
+	return repository.FruitPage{Fruits: e.fruits}, e.err
+}
+
+func makeExportFruitsEndpoint(fruitRepository fruits.Repository) endpoint.Endpoint {
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+
+	return fruits.MakeExportFruitsEndpoint(fruits.NewService(fruitRepository, nil, logger), logger)
+}
+
+// doGetRequest gets the given path with the given Accept header and returns the response body.
+func doGetRequest(t *testing.T, fruitEndpoints fruits.Endpoints, path, accept string) (*http.Response, []byte) {
+	t.Helper()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	dummyServer := httptest.NewServer(web.NewHTTPServer(fruitEndpoints, logger))
+	defer dummyServer.Close()
+
+	request, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, dummyServer.URL+path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("unexpected error reading response: %s", err)
+	}
+
+	return response, body
+}
+
+// doJSONRequest sends the given body as json to the given path and decodes the json response in result.
+func doJSONRequest(t *testing.T, fruitEndpoints fruits.Endpoints, method, path string, header http.Header, body, result interface{}) *http.Response {
+	t.Helper()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	httpHandler := web.NewHTTPServer(fruitEndpoints, logger)
+	dummyServer := httptest.NewServer(httpHandler)
+	defer dummyServer.Close()
+
+	var requestBody bytes.Buffer
+
+	if body != nil {
+		err := json.NewEncoder(&requestBody).Encode(body)
+		if err != nil {
+			t.Fatalf("unexpected error encoding request: %s", err)
+		}
+	}
+
+	request, err := http.NewRequestWithContext(context.TODO(), method, dummyServer.URL+path, &requestBody)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for key := range header {
+		request.Header.Set(key, header.Get(key))
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(result)
+	if err != nil {
+		t.Fatalf("unexpected error decoding response: %s", err)
+	}
+
+	return response
+}
+
+// doRawRequest sends the given body with the given content type to the given path and returns the response body.
+func doRawRequest(t *testing.T, fruitEndpoints fruits.Endpoints, method, path, contentType, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	return doRequest(t, fruitEndpoints, method, path, http.Header{"Content-Type": {contentType}}, body)
+}
+
+// doRequest sends the given body with the given headers to the given path and returns the response body.
+func doRequest(t *testing.T, fruitEndpoints fruits.Endpoints, method, path string, header http.Header, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	dummyServer := httptest.NewServer(web.NewHTTPServer(fruitEndpoints, logger))
+	defer dummyServer.Close()
+
+	request, err := http.NewRequestWithContext(context.TODO(), method, dummyServer.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for key := range header {
+		request.Header.Set(key, header.Get(key))
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("unexpected error reading response: %s", err)
+	}
+
+	return response, responseBody
 }
 
 func makeDummyGetFruitWithIDSuccessfullyEndpoint(t *testing.T, fruitToReturn *fruits.Fruit, err error) endpoint.Endpoint {
@@ -445,13 +2243,11 @@ func makeDummyGetFruitWithIDSuccessfullyEndpoint(t *testing.T, fruitToReturn *fr
 			t.FailNow()
 		}
 
-		var errMessage string
 		if err != nil {
-			errMessage = err.Error()
+			return nil, err
 		}
 		result := fruits.GetFruitWithIDResult{
 			Fruit: fruitToReturn,
-			Err:   errMessage,
 		}
 		return result, nil
 	}
@@ -468,13 +2264,11 @@ func makeDummySearchFruitsSuccessfullyEndpoint(t *testing.T, expectedFilter frui
 
 		assert.Equal(t, expectedFilter, filter)
 
-		var errMessage string
 		if err != nil {
-			errMessage = err.Error()
+			return nil, err
 		}
 		result := fruits.SearchFruitsDataResult{
 			SearchResult: resultToReturn,
-			Err:          errMessage,
 		}
 		return result, nil
 	}
@@ -489,13 +2283,11 @@ func makeDummyCreateFruitSuccessfullyEndpoint(t *testing.T, newFruitID string, e
 			t.FailNow()
 		}
 		t.Log("using newFruitID", newFruitID)
-		var errMessage string
 		if err != nil {
-			errMessage = err.Error()
+			return nil, err
 		}
 		result := fruits.CreateFruitResult{
-			ID:  newFruitID,
-			Err: errMessage,
+			ID: newFruitID,
 		}
 		return result, nil
 	}
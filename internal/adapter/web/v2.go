@@ -0,0 +1,331 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// addV2Routes adds the resource oriented routes of the second version of
+// the api, whose responses are an Envelope.
+func addV2Routes(router *mux.Router, fruitEndpoints fruits.Endpoints, encodeError httptransport.ErrorEncoder, options []httptransport.ServerOption, settings serverSettings, logger *loggers.Logger) {
+	router.Methods(http.MethodGet).Path("/fruits/export").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.ExportFruitsEndpoint,
+			makeDecodeExportFruitsRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeExportFruitsResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPost).Path("/fruits/batch").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.CreateBatchEndpoint,
+			makeDecodeCreateBatchRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2CreateBatchResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPost).Path("/fruits").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.CreateFruitEndpoint,
+			makeDecodeCreateFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2CreateFruitResponse(logger)),
+			append(options, httptransport.ServerBefore(putIdempotencyKey))...),
+	)
+	router.Methods(http.MethodGet).Path("/fruits").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.SearchFruitsEndpoint,
+			makeDecodeSearchFruitsRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2SearchFruitsResponse(settings.cacheControl, logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/fruits/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetFruitWithIDEndpoint,
+			makeDecodeGetFruitWithIDRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2GetFruitResponse(settings.cacheControl, logger)),
+			options...),
+	)
+	router.Methods(http.MethodPut).Path("/fruits/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.UpdateFruitEndpoint,
+			makeDecodeUpdateFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2FruitResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodPatch).Path("/fruits/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.PatchFruitEndpoint,
+			makeDecodePatchFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2FruitResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodDelete).Path("/fruits/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.DeleteFruitEndpoint,
+			makeDecodeDeleteFruitRequest(logger),
+			makeEncodeFailedResponse(encodeError, encodeV2NoContentResponse),
+			options...),
+	)
+	router.Methods(http.MethodPost).Path("/imports").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.StartImportEndpoint,
+			makeDecodeStartImportRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2ImportJobResponse(http.StatusAccepted, logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/imports/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetImportJobEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2ImportJobResponse(http.StatusOK, logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/imports/{id}/report").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetImportReportEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeImportReportResponse(logger)),
+			options...),
+	)
+	router.Methods(http.MethodDelete).Path("/imports/{id}").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.CancelImportEndpoint,
+			makeDecodeImportJobRequest(logger),
+			makeEncodeFailedResponse(encodeError, makeEncodeV2ImportJobResponse(http.StatusOK, logger)),
+			options...),
+	)
+	router.Methods(http.MethodGet).Path("/status").Handler(
+		httptransport.NewServer(
+			fruitEndpoints.GetStatusEndpoint,
+			makeDecodeGetStatusRequest(logger),
+			makeEncodeV2StatusResponse(logger),
+			options...),
+	)
+}
+
+// makeEncodeV2CreateFruitResponse answers 201 Created with the location of the new fruit.
+func makeEncodeV2CreateFruitResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.CreateFruitResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2CreateFruitResponse")
+
+			return errBuildingCreateFruitResponse
+		}
+
+		requestPath, _ := ctx.Value(httptransport.ContextKeyRequestPath).(string)
+
+		res.Header().Set("Location", path.Join(requestPath, result.ID))
+
+		return writeV2JSON(res, http.StatusCreated, Envelope{Data: CreatedFruitResponse{ID: result.ID}}, logger)
+	}
+}
+
+func makeEncodeV2CreateBatchResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.CreateBatchResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2CreateBatchResponse")
+
+			return errBuildingCreateBatchResponse
+		}
+
+		return writeV2JSON(res, http.StatusOK, Envelope{Data: toCreateBatchData(result)}, logger)
+	}
+}
+
+// makeEncodeV2GetFruitResponse writes the fruit found with its validators,
+// or 304 Not Modified if the client already has its version.
+func makeEncodeV2GetFruitResponse(cacheControl string, logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.GetFruitWithIDResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2GetFruitResponse")
+
+			return errBuildingGetFruitResponse
+		}
+
+		err := writeCacheable(ctx, res, cacheControl, fruitValidators(ctx, result.Fruit, apiV2), func(res http.ResponseWriter) error {
+			return writeV2Fruit(ctx, res, result.Fruit)
+		})
+		if err != nil {
+			logger.Error(
+				"cannot encode Envelope",
+				loggers.Fields{
+					"method": "encodeV2GetFruitResponse",
+					"error":  err,
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+// makeEncodeV2FruitResponse writes the fruit of an update or patch with its ETag.
+func makeEncodeV2FruitResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.UpdateFruitResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2FruitResponse")
+
+			return errBuildingUpdateFruitResponse
+		}
+
+		setFruitETag(res, result.Fruit, apiV2, representationFromContext(ctx).format)
+
+		err := writeV2Fruit(ctx, res, result.Fruit)
+		if err != nil {
+			logger.Error(
+				"cannot encode Envelope",
+				loggers.Fields{
+					"method": "encodeV2FruitResponse",
+					"error":  err,
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+// writeV2Fruit writes the fruit as data in the negotiated representation.
+func writeV2Fruit(ctx context.Context, res http.ResponseWriter, fruit *fruits.Fruit) error {
+	return writeRepresentation(ctx, res, Envelope{Data: toFruit(fruit)}, resultXMLRoot, func(w io.Writer) error {
+		return writeFruitCSV(w, fruit)
+	})
+}
+
+// makeEncodeV2SearchFruitsResponse writes the fruits found as data and the page as meta.
+func makeEncodeV2SearchFruitsResponse(cacheControl string, logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.SearchFruitsDataResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2SearchFruitsResponse")
+
+			return errBuildingSearchFruitResponse
+		}
+
+		if result.SearchResult != nil {
+			setNextLink(ctx, res, result.SearchResult.NextCursor)
+		}
+
+		err := writeCacheable(ctx, res, cacheControl, searchValidators(result.SearchResult), func(res http.ResponseWriter) error {
+			return writeRepresentation(ctx, res, toSearchEnvelope(result.SearchResult), resultXMLRoot, func(w io.Writer) error {
+				return writeSearchCSV(w, result.SearchResult)
+			})
+		})
+		if err != nil {
+			logger.Error(
+				"cannot encode Envelope",
+				loggers.Fields{
+					"method": "encodeV2SearchFruitsResponse",
+					"error":  err,
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+// makeEncodeV2ImportJobResponse writes the import job with the given status
+// code, a new job also gets its location.
+func makeEncodeV2ImportJobResponse(status int, logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.ImportJobResult)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2ImportJobResponse")
+
+			return errBuildingImportJobResponse
+		}
+
+		if status == http.StatusAccepted {
+			requestPath, _ := ctx.Value(httptransport.ContextKeyRequestPath).(string)
+
+			res.Header().Set("Location", path.Join(requestPath, result.Job.JobID))
+		}
+
+		return writeV2JSON(res, status, Envelope{Data: toFruitDatasetStatusResponse(result.Job)}, logger)
+	}
+}
+
+func makeEncodeV2StatusResponse(logger *loggers.Logger) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, res http.ResponseWriter, response interface{}) error {
+		result, ok := response.(fruits.DatasetStatus)
+		if !ok {
+			logV2ResponseError(logger, response, "encodeV2StatusResponse")
+
+			return errBuildingFruitDatasetStatus
+		}
+
+		status := toFruitDatasetStatusResponse(result)
+
+		err := writeRepresentation(ctx, res, Envelope{Data: status}, resultXMLRoot, func(w io.Writer) error {
+			return writeDatasetStatusCSV(w, status)
+		})
+		if err != nil {
+			logger.Error(
+				"cannot encode Envelope",
+				loggers.Fields{
+					"method": "encodeV2StatusResponse",
+					"error":  err,
+				},
+			)
+
+			return errEncodingResultResponse
+		}
+
+		return nil
+	}
+}
+
+// encodeV2NoContentResponse answers 204 No Content to requests that succeeded without data.
+func encodeV2NoContentResponse(_ context.Context, res http.ResponseWriter, _ interface{}) error {
+	res.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func writeV2JSON(res http.ResponseWriter, status int, message Envelope, logger *loggers.Logger) error {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+
+	err := json.NewEncoder(res).Encode(message)
+	if err != nil {
+		logger.Error(
+			"cannot encode Envelope",
+			loggers.Fields{
+				"result": fmt.Sprintf("%+v", message),
+				"method": "writeV2JSON",
+			},
+		)
+
+		return errEncodingResultResponse
+	}
+
+	return nil
+}
+
+func logV2ResponseError(logger *loggers.Logger, response interface{}, method string) {
+	logger.Error(
+		"cannot transform to a v2 response",
+		loggers.Fields{
+			"received": fmt.Sprintf("%T", response),
+			"method":   method,
+		},
+	)
+}
@@ -7,13 +7,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/fernandoocampo/fruits/internal/adapter/dataset"
 	"github.com/fernandoocampo/fruits/internal/adapter/document"
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/memorydb"
 	"github.com/fernandoocampo/fruits/internal/adapter/metrics"
 	"github.com/fernandoocampo/fruits/internal/adapter/monitoring"
+	"github.com/fernandoocampo/fruits/internal/adapter/publishing"
+	"github.com/fernandoocampo/fruits/internal/adapter/queue"
+	"github.com/fernandoocampo/fruits/internal/adapter/textindex"
 	"github.com/fernandoocampo/fruits/internal/adapter/topic"
 	"github.com/fernandoocampo/fruits/internal/adapter/web"
 	"github.com/fernandoocampo/fruits/internal/configurations"
@@ -34,10 +40,20 @@ type Instance struct {
 	logger        *loggers.Logger
 }
 
+// fruitRepository defines the behavior expected from any fruit storage backend.
+type fruitRepository interface {
+	fruits.Repository
+	fruits.Outbox
+	monitoring.FruitRepository
+}
+
 var (
-	errCreatingTopic      = errors.New("unable to create topic client")
-	errCreatingRepository = errors.New("unable to create repository client")
-	errLoadingApplication = errors.New("application setup could not be loaded")
+	errCreatingTopic         = errors.New("unable to create topic client")
+	errCreatingQueue         = errors.New("unable to create queue client")
+	errCreatingRepository    = errors.New("unable to create repository client")
+	errLoadingApplication    = errors.New("application setup could not be loaded")
+	errUnknownRepositoryType = errors.New("unknown repository type")
+	errUnknownPublisherType  = errors.New("unknown publisher type")
 )
 
 // NewInstance creates a new application instance.
@@ -76,12 +92,24 @@ func (i *Instance) Run() error {
 		return errLoadingApplication
 	}
 
-	repoTopic, err := i.createFruitTopic(ctx)
+	fruitPublisher, err := i.createPublisher(ctx)
 	if err != nil {
 		return errLoadingApplication
 	}
 
-	serviceFruit := fruits.NewService(repoFruit, repoTopic, i.logger)
+	publisher := i.createResilientPublisher(fruitPublisher)
+	options := append(
+		i.serviceOptions(textindex.New(), i.createIdempotencyStore(repoFruit), repoFruit),
+		fruits.WithDeadLetters(publisher),
+	)
+
+	serviceFruit := fruits.NewService(repoFruit, publisher, i.logger, options...)
+
+	i.rebuildTextIndex(ctx, serviceFruit)
+
+	i.relayEvents(ctx, serviceFruit)
+
+	i.loadDataset(ctx, serviceFruit)
 
 	monitorWorker := i.createMonitoringWorker(ctx, repoFruit)
 	defer monitorWorker.Shutdown()
@@ -145,11 +173,96 @@ func (i *Instance) createMonitoringWorker(ctx context.Context, repoFruit monitor
 	return monitorWorker
 }
 
+// serviceOptions returns the optional fruit service settings found in the configuration.
+func (i *Instance) serviceOptions(textIndex fruits.TextIndex, idempotencyKeys fruits.IdempotencyStore, outbox fruits.Outbox) []fruits.ServiceOption {
+	options := []fruits.ServiceOption{
+		fruits.WithTextIndex(textIndex),
+		fruits.WithOutbox(
+			outbox,
+			time.Duration(i.configuration.OutboxRetryDelayMillis)*time.Millisecond,
+			time.Duration(i.configuration.OutboxMaxRetryDelayMillis)*time.Millisecond,
+		),
+	}
+
+	if i.configuration.IdempotencyWindowMinutes > 0 {
+		window := time.Duration(i.configuration.IdempotencyWindowMinutes) * time.Minute
+		options = append(options, fruits.WithIdempotencyStore(idempotencyKeys, window))
+	}
+
+	if i.configuration.CursorSecret != "" {
+		options = append(options, fruits.WithCursorSecret([]byte(i.configuration.CursorSecret)))
+	} else {
+		i.logger.Info("CURSOR_SECRET is not set, search cursors will not survive a restart", loggers.Fields{"pkg": "application"})
+	}
+
+	return options
+}
+
+// rebuildTextIndex indexes the stored fruits in background.
+func (i *Instance) rebuildTextIndex(ctx context.Context, service *fruits.Service) {
+	go func() {
+		_, err := service.RebuildTextIndex(ctx)
+		if err != nil {
+			i.logger.Error("text index could not be rebuilt, full-text searches will miss stored fruits", loggers.Fields{"error": err})
+		}
+	}()
+}
+
+// relayEvents publishes the events of the outbox in background until ctx is done.
+func (i *Instance) relayEvents(ctx context.Context, service *fruits.Service) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(i.configuration.OutboxIntervalMillis) * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			published, err := service.RelayEvents(ctx)
+			if err != nil {
+				i.logger.Error("outbox events could not be relayed, they will be tried again", loggers.Fields{"error": err})
+			}
+
+			if published > 0 {
+				i.logger.Debug("outbox events relayed", loggers.Fields{"published": published})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// loadDataset loads the fruit dataset in background if it is enabled.
+func (i *Instance) loadDataset(ctx context.Context, service *fruits.Service) {
+	if !i.configuration.LoadDataset {
+		return
+	}
+
+	go func() {
+		i.logger.Info("loading dataset", loggers.Fields{"path": i.configuration.FilePath})
+
+		source := dataset.NewCSVFile(i.configuration.FilePath)
+		defer source.Close()
+
+		status := service.LoadDataset(ctx, source)
+
+		i.logger.Info(
+			"dataset load finished",
+			loggers.Fields{
+				"status":   status.Status,
+				"loaded":   status.Loaded,
+				"rejected": status.Rejected,
+			},
+		)
+	}()
+}
+
 // startWebServer starts the web server.
 func (i *Instance) startWebServer(endpoints fruits.Endpoints, eventStream chan<- Event) {
 	go func() {
 		i.logger.Info("starting http server", loggers.Fields{"http": i.configuration.ApplicationPort})
-		handler := web.NewHTTPServer(endpoints, i.logger)
+		handler := web.NewHTTPServer(endpoints, i.logger, web.WithCacheControl(i.configuration.CacheControl))
 
 		err := http.ListenAndServe(i.configuration.ApplicationPort, handler)
 		if err != nil {
@@ -186,9 +299,44 @@ func (i *Instance) loadConfiguration() error {
 	return nil
 }
 
-func (i *Instance) createFruitRepository(ctx context.Context) (*document.DynamoDB, error) {
-	i.logger.Info("initializing database", loggers.Fields{})
+func (i *Instance) createFruitRepository(ctx context.Context) (fruitRepository, error) {
+	i.logger.Info("initializing database", loggers.Fields{"type": i.configuration.RepositoryType})
+
+	switch i.configuration.RepositoryType {
+	case configurations.DynamoDBRepository:
+		return i.createDynamoDBRepository(ctx)
+	case configurations.MemoryRepository:
+		return i.createMemoryRepository(), nil
+	default:
+		i.logger.Error(
+			"unknown repository type",
+			loggers.Fields{
+				"type": i.configuration.RepositoryType,
+			},
+		)
+
+		return nil, errUnknownRepositoryType
+	}
+}
+
+// createIdempotencyStore creates the store of idempotency keys in the backend the fruits are stored.
+func (i *Instance) createIdempotencyStore(repoFruit fruitRepository) fruits.IdempotencyStore {
+	if dynamoDB, ok := repoFruit.(*document.DynamoDB); ok {
+		return dynamoDB.IdempotencyKeys()
+	}
+
+	return memorydb.NewIdempotencyKeys(memorydb.Setup{Logger: i.logger})
+}
+
+func (i *Instance) createMemoryRepository() *memorydb.MemoryDB {
+	dbSetup := memorydb.Setup{
+		Logger: i.logger,
+	}
 
+	return memorydb.New(dbSetup)
+}
+
+func (i *Instance) createDynamoDBRepository(ctx context.Context) (*document.DynamoDB, error) {
 	dbSetup := document.Setup{
 		Logger:   i.logger,
 		Region:   i.configuration.CloudRegion,
@@ -205,16 +353,76 @@ func (i *Instance) createFruitRepository(ctx context.Context) (*document.DynamoD
 	return newRepository, nil
 }
 
+// createPublisher creates the sinks of the configuration, the events are
+// published to all of them at once if there are several.
+func (i *Instance) createPublisher(ctx context.Context) (publishing.Publisher, error) {
+	sinks := make([]publishing.Sink, 0, len(i.configuration.Publishers))
+
+	for _, name := range i.configuration.Publishers {
+		name = strings.TrimSpace(name)
+
+		publisher, err := i.createSink(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, publishing.Sink{Name: name, Publisher: publisher})
+	}
+
+	switch len(sinks) {
+	case 0:
+		i.logger.Error("no publisher is configured", loggers.Fields{})
+
+		return nil, errUnknownPublisherType
+	case 1:
+		return sinks[0].Publisher, nil
+	default:
+		return publishing.NewFanOut(i.logger, sinks...), nil
+	}
+}
+
+func (i *Instance) createSink(ctx context.Context, name string) (publishing.Publisher, error) {
+	i.logger.Info("initializing publisher", loggers.Fields{"type": name})
+
+	switch name {
+	case configurations.SNSPublisher:
+		return i.createFruitTopic(ctx)
+	case configurations.SQSPublisher:
+		return i.createFruitQueue(ctx)
+	case configurations.FilePublisher:
+		return publishing.NewFileSink(i.configuration.EventsFile, i.configuration.EventSource, i.logger), nil
+	case configurations.NoopPublisher:
+		return publishing.Noop{}, nil
+	default:
+		i.logger.Error(
+			"unknown publisher type",
+			loggers.Fields{
+				"type": name,
+			},
+		)
+
+		return nil, errUnknownPublisherType
+	}
+}
+
 func (i *Instance) createFruitTopic(ctx context.Context) (*topic.SNS, error) {
-	i.logger.Info("initializing topic client", loggers.Fields{})
+	i.logger.Info("initializing topic client", loggers.Fields{"topic": i.configuration.TopicARN})
 
-	dbSetup := topic.Setup{
+	if i.configuration.TopicARN == "" {
+		i.logger.Error("TOPIC_ARN is required by the sns publisher", loggers.Fields{})
+
+		return nil, errCreatingTopic
+	}
+
+	snsSetup := topic.Setup{
 		Logger:   i.logger,
 		Region:   i.configuration.CloudRegion,
 		Endpoint: i.configuration.CloudEndpointURL,
+		TopicARN: i.configuration.TopicARN,
+		Source:   i.configuration.EventSource,
 	}
 
-	newTopic, err := topic.NewSNSClient(ctx, dbSetup)
+	newTopic, err := topic.NewSNSClient(ctx, snsSetup)
 	if err != nil {
 		i.logger.Error("unable to create sns client", loggers.Fields{"error": err})
 
@@ -223,3 +431,45 @@ func (i *Instance) createFruitTopic(ctx context.Context) (*topic.SNS, error) {
 
 	return newTopic, nil
 }
+
+func (i *Instance) createFruitQueue(ctx context.Context) (*queue.SQS, error) {
+	i.logger.Info("initializing queue client", loggers.Fields{"queue": i.configuration.QueueURL})
+
+	if i.configuration.QueueURL == "" {
+		i.logger.Error("QUEUE_URL is required by the sqs publisher", loggers.Fields{})
+
+		return nil, errCreatingQueue
+	}
+
+	sqsSetup := queue.Setup{
+		Logger:   i.logger,
+		Region:   i.configuration.CloudRegion,
+		Endpoint: i.configuration.CloudEndpointURL,
+		QueueURL: i.configuration.QueueURL,
+		Source:   i.configuration.EventSource,
+	}
+
+	newQueue, err := queue.NewSQSClient(ctx, sqsSetup)
+	if err != nil {
+		i.logger.Error("unable to create sqs client", loggers.Fields{"error": err})
+
+		return nil, errCreatingQueue
+	}
+
+	return newQueue, nil
+}
+
+// createResilientPublisher decorates the publisher with retries and a circuit
+// breaker, the events that exhaust their attempts are kept in the dead-letter file.
+func (i *Instance) createResilientPublisher(fruitPublisher publishing.Publisher) *publishing.Resilient {
+	setup := publishing.Setup{
+		Logger:           i.logger,
+		MaxAttempts:      i.configuration.PublishMaxAttempts,
+		RetryDelay:       time.Duration(i.configuration.PublishRetryDelayMillis) * time.Millisecond,
+		MaxRetryDelay:    time.Duration(i.configuration.PublishMaxRetryDelayMillis) * time.Millisecond,
+		FailureThreshold: i.configuration.PublishFailureThreshold,
+		OpenDuration:     time.Duration(i.configuration.PublishCircuitOpenMillis) * time.Millisecond,
+	}
+
+	return publishing.NewResilient(fruitPublisher, publishing.NewFileDeadLetters(i.configuration.DeadLetterFile, i.logger), setup)
+}
@@ -15,8 +15,70 @@ type Application struct {
 	MetricsIntervalMillis int    `env:"METRICS_INTERVAL_MILLIS" envDefault:"60000"`
 	CloudRegion           string `env:"CLOUD_REGION" envDefault:"us-east-1"`
 	CloudEndpointURL      string `env:"CLOUD_ENDPOINT_URL" envDefault:"aws"`
+	// FilePath path of the csv fruit dataset loaded at startup.
+	FilePath string `env:"FILE_PATH"`
+	// LoadDataset loads the fruit dataset in FilePath at startup.
+	LoadDataset bool `env:"LOAD_DATASET" envDefault:"false"`
+	// RepositoryType storage backend for fruits: dynamodb or memory.
+	RepositoryType string `env:"REPOSITORY_TYPE" envDefault:"dynamodb"`
+	// CursorSecret secret search cursors are signed with, every replica must
+	// share it. If it is empty a random one is generated at startup.
+	CursorSecret string `env:"CURSOR_SECRET"`
+	// IdempotencyWindowMinutes time a create Idempotency-Key is remembered,
+	// 0 disables idempotent creation.
+	IdempotencyWindowMinutes int `env:"IDEMPOTENCY_WINDOW_MINUTES" envDefault:"1440"`
+	// CacheControl Cache-Control header of fruit reads, no-cache makes
+	// clients revalidate their copy with If-None-Match every time.
+	CacheControl string `env:"CACHE_CONTROL" envDefault:"no-cache"`
+	// OutboxIntervalMillis how often the events of new fruits are relayed
+	// from the outbox to the topic.
+	OutboxIntervalMillis int `env:"OUTBOX_INTERVAL_MILLIS" envDefault:"1000"`
+	// OutboxRetryDelayMillis delay before an event that could not be
+	// published is tried again, it doubles on every failure up to
+	// OutboxMaxRetryDelayMillis.
+	OutboxRetryDelayMillis    int `env:"OUTBOX_RETRY_DELAY_MILLIS" envDefault:"1000"`
+	OutboxMaxRetryDelayMillis int `env:"OUTBOX_MAX_RETRY_DELAY_MILLIS" envDefault:"300000"`
+	// PublishMaxAttempts times an event is tried to be published before it
+	// is dead-lettered.
+	PublishMaxAttempts int `env:"PUBLISH_MAX_ATTEMPTS" envDefault:"3"`
+	// PublishRetryDelayMillis delay after the first failed attempt, it
+	// doubles on every attempt up to PublishMaxRetryDelayMillis and is jittered.
+	PublishRetryDelayMillis    int `env:"PUBLISH_RETRY_DELAY_MILLIS" envDefault:"200"`
+	PublishMaxRetryDelayMillis int `env:"PUBLISH_MAX_RETRY_DELAY_MILLIS" envDefault:"5000"`
+	// PublishFailureThreshold consecutive failed attempts that pause
+	// publishing for PublishCircuitOpenMillis, 0 never pauses it.
+	PublishFailureThreshold  int `env:"PUBLISH_FAILURE_THRESHOLD" envDefault:"5"`
+	PublishCircuitOpenMillis int `env:"PUBLISH_CIRCUIT_OPEN_MILLIS" envDefault:"30000"`
+	// EventSource CloudEvents source of the published events, it identifies
+	// this service to their consumers.
+	EventSource string `env:"EVENT_SOURCE" envDefault:"https://github.com/fernandoocampo/fruits"`
+	// DeadLetterFile file the events that exhaust their attempts are kept in.
+	DeadLetterFile string `env:"DEAD_LETTER_FILE" envDefault:"fruit-dead-letters.ndjson"`
+	// Publishers comma separated sinks every event is published to: sns,
+	// sqs, file or none.
+	Publishers []string `env:"PUBLISHERS" envSeparator:"," envDefault:"sns"`
+	// TopicARN arn of the sns topic of the sns publisher, it is required by it.
+	TopicARN string `env:"TOPIC_ARN"`
+	// QueueURL url of the sqs queue of the sqs publisher, it is required by it.
+	QueueURL string `env:"QUEUE_URL"`
+	// EventsFile file the file publisher appends the events to.
+	EventsFile string `env:"EVENTS_FILE" envDefault:"fruit-events.ndjson"`
 }
 
+// Storage backends allowed in RepositoryType.
+const (
+	DynamoDBRepository = "dynamodb"
+	MemoryRepository   = "memory"
+)
+
+// Event sinks allowed in Publishers.
+const (
+	SNSPublisher  = "sns"
+	SQSPublisher  = "sqs"
+	FilePublisher = "file"
+	NoopPublisher = "none"
+)
+
 // Load load application configuration.
 func Load() (Application, error) {
 	cfg := new(Application)
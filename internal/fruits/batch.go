@@ -0,0 +1,89 @@
+package fruits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// MaxBatchSize is the largest number of fruits created at once.
+const MaxBatchSize = 1000
+
+// ErrBatchTooLarge is returned when a batch has more than MaxBatchSize fruits.
+var ErrBatchTooLarge = fmt.Errorf("a batch cannot have more than %d fruits", MaxBatchSize)
+
+// CreateBatch creates every valid fruit of the batch, a fruit that cannot be
+// created doesn't stop the rest. The results are in the same order as the fruits.
+func (s *Service) CreateBatch(ctx context.Context, newfruits []NewFruit) ([]BatchItemResult, error) {
+	s.logger.Debug(
+		"creating fruits",
+		loggers.Fields{
+			"method": "Service.CreateBatch",
+			"fruits": len(newfruits),
+		},
+	)
+
+	if len(newfruits) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]BatchItemResult, len(newfruits))
+	fruitsToSave := make([]repository.NewFruit, 0, len(newfruits))
+	positions := make([]int, 0, len(newfruits))
+
+	for index, newfruit := range newfruits {
+		results[index].Index = index
+
+		err := newfruit.Validate()
+		if err != nil {
+			results[index].Err = err
+
+			continue
+		}
+
+		fruitsToSave = append(fruitsToSave, newfruit.ToFruitPortOut())
+		positions = append(positions, index)
+	}
+
+	if len(fruitsToSave) == 0 {
+		return results, nil
+	}
+
+	for position, saved := range s.fruitRepository.SaveAll(ctx, fruitsToSave) {
+		index := positions[position]
+
+		if saved.Err != nil {
+			s.logger.Error(
+				"something goes wrong creating a fruit of a batch",
+				loggers.Fields{
+					"method": "Service.CreateBatch",
+					"index":  index,
+					"error":  saved.Err,
+				},
+			)
+
+			results[index].Err = ErrDataAccess
+
+			continue
+		}
+
+		results[index].ID = repository.FruitIDValue(saved.ID)
+
+		newFruit := fruitsToSave[position].ToFruit(saved.ID)
+		s.indexFruit(newFruit)
+		s.notify(repository.NewFruitCreated(newFruit, time.Now().UTC()))
+	}
+
+	s.logger.Info(
+		"fruit batch was processed",
+		loggers.Fields{
+			"method": "Service.CreateBatch",
+			"fruits": len(newfruits),
+		},
+	)
+
+	return results, nil
+}
@@ -0,0 +1,108 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateBatch(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	textIndex := newTextIndexMock()
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithTextIndex(textIndex))
+	givenFruits := []fruits.NewFruit{
+		{
+			Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+			Vault:          "Nicosia",
+			Country:        "Italy",
+			Classification: "Vulka Bianco",
+		},
+		{
+			Name:    "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+			Vault:   "Quinta dos Avidagos",
+			Country: "Atlantis",
+		},
+		{
+			Name:           "Rainstorm 2013 Pinot Gris (Willamette Valley)",
+			Vault:          "Rainstorm",
+			Country:        "US",
+			Classification: "Willamette Valley",
+		},
+	}
+	expectedRejection := fruits.ValidationError{
+		Violations: []fruits.Violation{
+			{Field: "classification", Reason: "is mandatory"},
+			{Field: "country", Reason: "must be an ISO 3166 country code or name"},
+		},
+	}
+
+	got, err := fruitService.CreateBatch(context.TODO(), givenFruits)
+
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+	assert.Len(t, fruitRepository.repo, 2)
+
+	for index, item := range got {
+		assert.Equal(t, index, item.Index)
+	}
+
+	assert.NoError(t, got[0].Err)
+	assert.Equal(t, givenFruits[0].Name, fruitRepository.repo[got[0].ID].Name)
+	assert.Empty(t, got[1].ID)
+	assert.Equal(t, expectedRejection, got[1].Err)
+	assert.NoError(t, got[2].Err)
+	assert.Equal(t, givenFruits[2].Name, fruitRepository.repo[got[2].ID].Name)
+	assert.Len(t, textIndex.fruits, 2)
+}
+
+func TestCreateBatchWithRepositoryError(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		err: errors.New("any error"),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	givenFruits := []fruits.NewFruit{
+		{
+			Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+			Vault:          "Nicosia",
+			Country:        "Italy",
+			Classification: "Vulka Bianco",
+		},
+	}
+	expectedResult := []fruits.BatchItemResult{
+		{Index: 0, Err: fruits.ErrDataAccess},
+	}
+
+	got, err := fruitService.CreateBatch(context.TODO(), givenFruits)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, got)
+}
+
+func TestCreateBatchTooLarge(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.CreateBatch(context.TODO(), make([]fruits.NewFruit, fruits.MaxBatchSize+1))
+
+	assert.Equal(t, fruits.ErrBatchTooLarge, err)
+	assert.Nil(t, got)
+	assert.Empty(t, fruitRepository.repo)
+}
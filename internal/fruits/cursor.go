@@ -0,0 +1,112 @@
+package fruits
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// cursorSecretSize is the size in bytes of the generated cursor secrets.
+const cursorSecretSize = 32
+
+// ErrInvalidCursor is returned when a search cursor was not signed by this
+// service or belongs to a search with other filters.
+var ErrInvalidCursor = InvalidFilterError{Filter: "cursor", Reason: "is invalid or belongs to another search"}
+
+// cursorCodec encodes repository cursors as opaque strings signed with a
+// secret, so clients cannot forge them.
+type cursorCodec struct {
+	secret []byte
+}
+
+// cursorPayload is the signed content of a cursor.
+type cursorPayload struct {
+	// Filter is the fingerprint of the filters of the search.
+	Filter   string            `json:"f"`
+	Position repository.Cursor `json:"p"`
+}
+
+// newCursorSecret generates a random secret to sign cursors.
+func newCursorSecret() []byte {
+	secret := make([]byte, cursorSecretSize)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		panic("unable to generate cursor secret: " + err.Error())
+	}
+
+	return secret
+}
+
+// encode returns the cursor to continue the search of the given filter at the given position.
+func (c cursorCodec) encode(filter SearchFruitFilter, position *repository.Cursor) string {
+	payload, err := json.Marshal(cursorPayload{
+		Filter:   filterFingerprint(filter),
+		Position: *position,
+	})
+	if err != nil {
+		return ""
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(c.sign(encodedPayload))
+}
+
+// decode returns the position of the cursor of the given filter or ErrInvalidCursor.
+func (c cursorCodec) decode(filter SearchFruitFilter) (*repository.Cursor, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(filter.Cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, c.sign(encodedPayload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor cursorPayload
+
+	err = json.Unmarshal(payload, &cursor)
+	if err != nil || cursor.Filter != filterFingerprint(filter) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor.Position, nil
+}
+
+func (c cursorCodec) sign(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(encodedPayload))
+
+	return mac.Sum(nil)
+}
+
+// filterFingerprint identifies the search of the given filter, the window
+// and the cursor are not part of it.
+func filterFingerprint(filter SearchFruitFilter) string {
+	filter.Start = 0
+	filter.Count = 0
+	filter.Cursor = ""
+	filter.Facets = nil
+
+	content, err := json.Marshal(filter)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,228 @@
+package fruits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// maxDatasetRejections is the number of rejected rows whose details are
+// kept in the status, the job report keeps all of them.
+const maxDatasetRejections = 100
+
+// DatasetSource defines portin behavior to read fruits from a dataset.
+// Next returns io.EOF when there are no more rows to read.
+type DatasetSource interface {
+	Next() (DatasetRow, error)
+}
+
+// DatasetRow contains a fruit read from a dataset. ID is the id of the
+// fruit in the dataset, empty if it has none. Err is set when the row could
+// not be read as a fruit.
+type DatasetRow struct {
+	Line  int
+	ID    string
+	Fruit NewFruit
+	Err   error
+}
+
+// DatasetRejection describes a dataset row that was not loaded.
+type DatasetRejection struct {
+	Line   int
+	Reason string
+}
+
+// LoadDataset reads every row of the given source, validates it and stores
+// it in the fruit repository. Rows are stored with their id, so rows whose
+// fruit is already stored are counted as loaded and loading a dataset again
+// doesn't duplicate it. The load is kept as an import job and its outcome is
+// reported by DatasetStatus.
+func (s *Service) LoadDataset(ctx context.Context, source DatasetSource) DatasetStatus {
+	s.logger.Info(
+		"loading fruit dataset",
+		loggers.Fields{
+			"method": "Service.LoadDataset",
+		},
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	job := s.addImportJob(cancel)
+
+	s.importMu.Lock()
+	s.datasetJobID = job.status.JobID
+	s.importMu.Unlock()
+
+	s.runImport(ctx, job, source)
+
+	return s.currentDatasetStatus()
+}
+
+// runImport loads every row of the source, the job status is updated after each row.
+func (s *Service) runImport(ctx context.Context, job *importJob, source DatasetSource) {
+	defer close(job.done)
+
+	for {
+		if ctx.Err() != nil {
+			s.finishImport(job, DatasetStateCancelled, "dataset load was cancelled")
+
+			return
+		}
+
+		row, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			s.logger.Error(
+				"fruit dataset could not be read",
+				loggers.Fields{
+					"method": "Service.runImport",
+					"jobID":  job.status.JobID,
+					"error":  err,
+				},
+			)
+
+			s.finishImport(job, DatasetStateError, fmt.Sprintf("dataset could not be read: %s", err))
+
+			return
+		}
+
+		err = s.loadDatasetRow(ctx, row)
+		if err != nil && ctx.Err() != nil {
+			continue
+		}
+
+		s.importMu.Lock()
+		if err != nil {
+			job.addRejection(row.Line, err)
+		} else {
+			job.status.Loaded++
+		}
+		s.importMu.Unlock()
+	}
+
+	s.importMu.RLock()
+	loaded, rejected := job.status.Loaded, job.status.Rejected
+	s.importMu.RUnlock()
+
+	if rejected > 0 {
+		s.finishImport(job, DatasetStateError, fmt.Sprintf("%d fruits were loaded, %d rows were rejected", loaded, rejected))
+
+		return
+	}
+
+	s.finishImport(job, DatasetStateOK, fmt.Sprintf("%d fruits were loaded", loaded))
+}
+
+func (s *Service) loadDatasetRow(ctx context.Context, row DatasetRow) error {
+	if row.Err != nil {
+		return row.Err
+	}
+
+	err := row.Fruit.Validate()
+	if err != nil {
+		return err
+	}
+
+	newFruit := row.Fruit.ToFruitPortOut()
+	newFruit.ID = repository.FruitID(row.ID)
+
+	fruitID, err := s.fruitRepository.Save(ctx, newFruit)
+	if errors.Is(err, repository.ErrFruitExists) {
+		s.logger.Debug(
+			"dataset fruit is already stored",
+			loggers.Fields{
+				"method":  "Service.loadDatasetRow",
+				"line":    row.Line,
+				"fruitID": row.ID,
+			},
+		)
+
+		return nil
+	}
+
+	if err != nil {
+		s.logger.Error(
+			"dataset row could not be stored",
+			loggers.Fields{
+				"method": "Service.loadDatasetRow",
+				"line":   row.Line,
+				"error":  err,
+			},
+		)
+
+		return ErrDataAccess
+	}
+
+	storedFruit := newFruit.ToFruit(fruitID)
+	s.indexFruit(storedFruit)
+	s.notify(repository.NewFruitCreated(storedFruit, time.Now().UTC()))
+
+	return nil
+}
+
+// finishImport sets the final state of the job.
+func (s *Service) finishImport(job *importJob, state DatasetState, message string) {
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
+
+	job.status.Status = state
+	job.status.Message = message
+
+	s.logger.Info(
+		"fruit dataset load finished",
+		loggers.Fields{
+			"method":   "Service.finishImport",
+			"jobID":    job.status.JobID,
+			"status":   state,
+			"loaded":   job.status.Loaded,
+			"rejected": job.status.Rejected,
+		},
+	)
+}
+
+// currentDatasetStatus returns the status of the dataset loaded at startup.
+func (s *Service) currentDatasetStatus() DatasetStatus {
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	return s.importJobs[s.datasetJobID].snapshot()
+}
+
+func (s *Service) datasetWasLoaded() bool {
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	return s.datasetJobID != ""
+}
+
+// snapshot returns a copy of the job status that is safe to read once the lock is released.
+func (j *importJob) snapshot() DatasetStatus {
+	result := j.status
+	result.Rejections = append([]DatasetRejection(nil), j.status.Rejections...)
+	result.Timestamp = time.Now().Unix()
+
+	return result
+}
+
+func (j *importJob) addRejection(line int, reason error) {
+	rejection := DatasetRejection{
+		Line:   line,
+		Reason: reason.Error(),
+	}
+
+	j.status.Rejected++
+	j.report = append(j.report, rejection)
+
+	if len(j.status.Rejections) < maxDatasetRejections {
+		j.status.Rejections = append(j.status.Rejections, rejection)
+	}
+}
@@ -0,0 +1,173 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+var errReadingDataset = errors.New("disk failure")
+
+func TestLoadDatasetSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	source := datasetSourceMock{
+		rows: []fruits.DatasetRow{
+			{Line: 2, Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+			{Line: 3, Fruit: validNewFruit("Quinta dos Avidagos 2011 Avidagos Red (Douro)")},
+		},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	got := fruitService.LoadDataset(ctx, &source)
+
+	assert.Equal(t, fruits.DatasetStateOK, got.Status)
+	assert.Equal(t, 2, got.Loaded)
+	assert.Equal(t, 0, got.Rejected)
+	assert.Empty(t, got.Rejections)
+	assert.Len(t, fruitRepository.repo, 2)
+	assert.Equal(t, got.Status, fruitService.DatasetStatus(ctx).Status)
+}
+
+func TestLoadDatasetTwice(t *testing.T) {
+	t.Parallel()
+
+	rows := []fruits.DatasetRow{
+		{Line: 2, ID: "1234", Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+		{Line: 3, ID: "1240", Fruit: validNewFruit("Quinta dos Avidagos 2011 Avidagos Red (Douro)")},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	fruitService.LoadDataset(ctx, &datasetSourceMock{rows: rows})
+	got := fruitService.LoadDataset(ctx, &datasetSourceMock{rows: rows})
+
+	assert.Equal(t, fruits.DatasetStateOK, got.Status)
+	assert.Equal(t, 2, got.Loaded)
+	assert.Equal(t, 0, got.Rejected)
+	assert.Len(t, fruitRepository.repo, 2)
+	assert.Equal(t, "Nicosia 2013 Vulka Bianco  (Etna)", fruitRepository.repo["1234"].Name)
+}
+
+func TestLoadDatasetPublishesFruitsWithoutOutbox(t *testing.T) {
+	t.Parallel()
+
+	source := datasetSourceMock{
+		rows: []fruits.DatasetRow{
+			{Line: 2, Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+			{Line: 3, Fruit: validNewFruit("Quinta dos Avidagos 2011 Avidagos Red (Douro)")},
+		},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	publisher := newFlakyPublisherMock(0)
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, publisher, logger)
+
+	fruitService.LoadDataset(context.TODO(), &source)
+
+	assert.Eventually(t, func() bool { return len(publisher.events()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"Nicosia 2013 Vulka Bianco  (Etna)", "Quinta dos Avidagos 2011 Avidagos Red (Douro)"}, publisher.names())
+}
+
+func TestLoadDatasetWithRejectedRows(t *testing.T) {
+	t.Parallel()
+
+	source := datasetSourceMock{
+		rows: []fruits.DatasetRow{
+			{Line: 2, Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+			{Line: 3, Fruit: fruits.NewFruit{Name: "without mandatory fields"}},
+			{Line: 4, Err: errors.New("price must be a number")},
+		},
+	}
+	expectedRejections := []fruits.DatasetRejection{
+		{Line: 3, Reason: "invalid fruit: classification is mandatory, country is mandatory, vault is mandatory"},
+		{Line: 4, Reason: "price must be a number"},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	fruitService.LoadDataset(ctx, &source)
+	got := fruitService.DatasetStatus(ctx)
+
+	assert.Equal(t, fruits.DatasetStateError, got.Status)
+	assert.Equal(t, 1, got.Loaded)
+	assert.Equal(t, 2, got.Rejected)
+	assert.Equal(t, expectedRejections, got.Rejections)
+	assert.Len(t, fruitRepository.repo, 1)
+}
+
+func TestLoadDatasetThatCannotBeRead(t *testing.T) {
+	t.Parallel()
+
+	source := datasetSourceMock{
+		rows: []fruits.DatasetRow{
+			{Line: 2, Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+		},
+		err: errReadingDataset,
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	got := fruitService.LoadDataset(ctx, &source)
+
+	assert.Equal(t, fruits.DatasetStateError, got.Status)
+	assert.Equal(t, "dataset could not be read: disk failure", got.Message)
+	assert.Equal(t, 1, got.Loaded)
+}
+
+func validNewFruit(name string) fruits.NewFruit {
+	return fruits.NewFruit{
+		Name:           name,
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+}
+
+// datasetSourceMock returns the given rows and then err, or io.EOF if err is nil.
+type datasetSourceMock struct {
+	rows []fruits.DatasetRow
+	err  error
+}
+
+func (d *datasetSourceMock) Next() (fruits.DatasetRow, error) {
+	if len(d.rows) == 0 {
+		if d.err != nil {
+			return fruits.DatasetRow{}, d.err
+		}
+
+		return fruits.DatasetRow{}, io.EOF
+	}
+
+	row := d.rows[0]
+	d.rows = d.rows[1:]
+
+	return row, nil
+}
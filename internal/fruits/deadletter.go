@@ -0,0 +1,90 @@
+package fruits
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// DeadLetters defines portout behavior to inspect and re-drive the events
+// that could not be published.
+type DeadLetters interface {
+	// DeadLetters returns the dead-lettered events, the oldest first.
+	DeadLetters(ctx context.Context) ([]repository.DeadLetter, error)
+	// Redrive publishes a dead-lettered event again, it is removed once published.
+	Redrive(ctx context.Context, id string) error
+}
+
+var (
+	// ErrDeadLetterNotFound is returned when there is no dead-lettered event with the given id.
+	ErrDeadLetterNotFound = errors.New("dead-lettered event not found")
+	// ErrRedrivingEvent is returned when a dead-lettered event could not be published again.
+	ErrRedrivingEvent = errors.New("dead-lettered event could not be published")
+)
+
+// WithDeadLetters lets the events the publisher dead-lettered be listed and re-driven.
+func WithDeadLetters(deadLetters DeadLetters) ServiceOption {
+	return func(s *Service) {
+		s.deadLetters = deadLetters
+	}
+}
+
+// ListDeadLetters returns the events that could not be published, the oldest first.
+func (s *Service) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	if s.deadLetters == nil {
+		return []DeadLetter{}, nil
+	}
+
+	deadLetters, err := s.deadLetters.DeadLetters(ctx)
+	if err != nil {
+		s.logger.Error(
+			"unable to list dead-lettered events",
+			loggers.Fields{
+				"method": "Service.ListDeadLetters",
+				"error":  err,
+			},
+		)
+
+		return nil, ErrDataAccess
+	}
+
+	return transformDeadLetters(deadLetters), nil
+}
+
+// RedriveDeadLetter publishes the dead-lettered event with the given id
+// again, it is removed once published and kept if it fails again.
+func (s *Service) RedriveDeadLetter(ctx context.Context, id string) error {
+	if s.deadLetters == nil {
+		return ErrDeadLetterNotFound
+	}
+
+	err := s.deadLetters.Redrive(ctx, id)
+	if errors.Is(err, repository.ErrDeadLetterNotFound) {
+		return ErrDeadLetterNotFound
+	}
+
+	if err != nil {
+		s.logger.Error(
+			"unable to re-drive dead-lettered event",
+			loggers.Fields{
+				"method": "Service.RedriveDeadLetter",
+				"id":     id,
+				"error":  err,
+			},
+		)
+
+		return ErrRedrivingEvent
+	}
+
+	s.logger.Info(
+		"dead-lettered event re-driven",
+		loggers.Fields{
+			"method": "Service.RedriveDeadLetter",
+			"id":     id,
+		},
+	)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	deadLetteredAt := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	deadLetters := deadLettersMock{
+		deadLetters: []repository.DeadLetter{
+			{
+				ID:             "a",
+				Event:          repository.NewFruitCreated(repository.Fruit{ID: "1", Name: "Mango"}, deadLetteredAt),
+				Attempts:       3,
+				LastError:      "topic not available",
+				DeadLetteredAt: deadLetteredAt,
+			},
+		},
+	}
+	expectedDeadLetters := []fruits.DeadLetter{
+		{
+			ID:             "a",
+			SourceID:       "1",
+			Name:           "Mango",
+			Attempts:       3,
+			LastError:      "topic not available",
+			DeadLetteredAt: deadLetteredAt,
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, &publisherMock{}, logger, fruits.WithDeadLetters(&deadLetters))
+
+	got, err := fruitService.ListDeadLetters(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedDeadLetters, got)
+}
+
+func TestRedriveDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		deadLetters fruits.DeadLetters
+		want        error
+	}{
+		"redriven": {
+			deadLetters: &deadLettersMock{},
+			want:        nil,
+		},
+		"not_found": {
+			deadLetters: &deadLettersMock{err: repository.ErrDeadLetterNotFound},
+			want:        fruits.ErrDeadLetterNotFound,
+		},
+		"publish_fails": {
+			deadLetters: &deadLettersMock{err: errors.New("topic not available")},
+			want:        fruits.ErrRedrivingEvent,
+		},
+		"without_dead_letters": {
+			deadLetters: nil,
+			want:        fruits.ErrDeadLetterNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			logger := loggers.NewLoggerWithStdout("", loggers.Error)
+			options := []fruits.ServiceOption{}
+
+			if test.deadLetters != nil {
+				options = append(options, fruits.WithDeadLetters(test.deadLetters))
+			}
+
+			fruitService := fruits.NewService(&fruitRepoMock{}, &publisherMock{}, logger, options...)
+
+			err := fruitService.RedriveDeadLetter(context.TODO(), "a")
+
+			assert.Equal(st, test.want, err)
+		})
+	}
+}
+
+type deadLettersMock struct {
+	err         error
+	deadLetters []repository.DeadLetter
+}
+
+func (d *deadLettersMock) DeadLetters(_ context.Context) ([]repository.DeadLetter, error) {
+	return d.deadLetters, d.err
+}
+
+func (d *deadLettersMock) Redrive(_ context.Context, _ string) error {
+	return d.err
+}
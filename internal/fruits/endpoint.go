@@ -11,25 +11,54 @@ import (
 
 // Endpoints is a wrapper for endpoints.
 type Endpoints struct {
-	GetFruitWithIDEndpoint endpoint.Endpoint
-	CreateFruitEndpoint    endpoint.Endpoint
-	SearchFruitsEndpoint   endpoint.Endpoint
-	GetStatusEndpoint      endpoint.Endpoint
+	GetFruitWithIDEndpoint    endpoint.Endpoint
+	CreateFruitEndpoint       endpoint.Endpoint
+	CreateBatchEndpoint       endpoint.Endpoint
+	UpdateFruitEndpoint       endpoint.Endpoint
+	PatchFruitEndpoint        endpoint.Endpoint
+	DeleteFruitEndpoint       endpoint.Endpoint
+	SearchFruitsEndpoint      endpoint.Endpoint
+	ExportFruitsEndpoint      endpoint.Endpoint
+	GetStatusEndpoint         endpoint.Endpoint
+	StartImportEndpoint       endpoint.Endpoint
+	GetImportJobEndpoint      endpoint.Endpoint
+	GetImportReportEndpoint   endpoint.Endpoint
+	CancelImportEndpoint      endpoint.Endpoint
+	ListDeadLettersEndpoint   endpoint.Endpoint
+	RedriveDeadLetterEndpoint endpoint.Endpoint
 }
 
 var (
 	errInvalidFruitID      = errors.New("invalid fruit id")
 	errInvalidFruitFilters = errors.New("invalid fruit filters")
 	errInvalidNewFruitType = errors.New("invalid new fruit type")
+	errInvalidUpdateType   = errors.New("invalid update fruit type")
+	errInvalidPatchType    = errors.New("invalid patch fruit type")
+	errInvalidDeleteType   = errors.New("invalid delete fruit type")
+	errInvalidBatchType    = errors.New("invalid create batch type")
+	errInvalidImportType   = errors.New("invalid start import type")
+	errInvalidImportJobID  = errors.New("invalid import job id")
+	errInvalidDeadLetterID = errors.New("invalid dead-lettered event id")
 )
 
 // NewEndpoints Create the endpoints for fruits-micro application.
 func NewEndpoints(service FruitService, logger *loggers.Logger) Endpoints {
 	return Endpoints{
-		GetFruitWithIDEndpoint: MakeGetFruitWithIDEndpoint(service, logger),
-		CreateFruitEndpoint:    MakeCreateFruitEndpoint(service, logger),
-		SearchFruitsEndpoint:   MakeSearchFruitsEndpoint(service, logger),
-		GetStatusEndpoint:      MakeGetStatusEndpoint(service, logger),
+		GetFruitWithIDEndpoint:    MakeGetFruitWithIDEndpoint(service, logger),
+		CreateFruitEndpoint:       MakeCreateFruitEndpoint(service, logger),
+		CreateBatchEndpoint:       MakeCreateBatchEndpoint(service, logger),
+		UpdateFruitEndpoint:       MakeUpdateFruitEndpoint(service, logger),
+		PatchFruitEndpoint:        MakePatchFruitEndpoint(service, logger),
+		DeleteFruitEndpoint:       MakeDeleteFruitEndpoint(service, logger),
+		SearchFruitsEndpoint:      MakeSearchFruitsEndpoint(service, logger),
+		ExportFruitsEndpoint:      MakeExportFruitsEndpoint(service, logger),
+		GetStatusEndpoint:         MakeGetStatusEndpoint(service, logger),
+		StartImportEndpoint:       MakeStartImportEndpoint(service, logger),
+		GetImportJobEndpoint:      MakeGetImportJobEndpoint(service, logger),
+		GetImportReportEndpoint:   MakeGetImportReportEndpoint(service, logger),
+		CancelImportEndpoint:      MakeCancelImportEndpoint(service, logger),
+		ListDeadLettersEndpoint:   MakeListDeadLettersEndpoint(service, logger),
+		RedriveDeadLetterEndpoint: MakeRedriveDeadLetterEndpoint(service, logger),
 	}
 }
 
@@ -103,6 +132,130 @@ func MakeCreateFruitEndpoint(srv FruitService, logger *loggers.Logger) endpoint.
 	}
 }
 
+// MakeCreateBatchEndpoint create endpoint for create fruits at once service.
+func MakeCreateBatchEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		batchRequest, ok := request.(*CreateBatchRequest)
+		if !ok {
+			logger.Error(
+				"invalid create batch type",
+				loggers.Fields{
+					"method":   "CreateBatchEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidBatchType
+		}
+
+		items, err := srv.CreateBatch(ctx, batchRequest.Fruits)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to create a fruit batch",
+				loggers.Fields{
+					"method": "CreateBatchEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newCreateBatchResult(items, err), nil
+	}
+}
+
+// MakeUpdateFruitEndpoint create endpoint for update fruit service.
+func MakeUpdateFruitEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		updateRequest, ok := request.(*UpdateFruitRequest)
+		if !ok {
+			logger.Error(
+				"invalid update fruit type",
+				loggers.Fields{
+					"method":   "UpdateFruitEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidUpdateType
+		}
+
+		fruitUpdated, err := srv.Update(ctx, updateRequest.ID, updateRequest.Version, updateRequest.Fruit)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to update a fruit with the given id",
+				loggers.Fields{
+					"method": "UpdateFruitEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newUpdateFruitResult(fruitUpdated, err), nil
+	}
+}
+
+// MakePatchFruitEndpoint create endpoint for patch fruit service.
+func MakePatchFruitEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		patchRequest, ok := request.(*PatchFruitRequest)
+		if !ok {
+			logger.Error(
+				"invalid patch fruit type",
+				loggers.Fields{
+					"method":   "PatchFruitEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidPatchType
+		}
+
+		fruitPatched, err := srv.Patch(ctx, patchRequest.ID, patchRequest.Version, patchRequest.Patch)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to patch a fruit with the given id",
+				loggers.Fields{
+					"method": "PatchFruitEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newUpdateFruitResult(fruitPatched, err), nil
+	}
+}
+
+// MakeDeleteFruitEndpoint create endpoint for delete fruit service.
+func MakeDeleteFruitEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		deleteRequest, ok := request.(*DeleteFruitRequest)
+		if !ok {
+			logger.Error(
+				"invalid delete fruit type",
+				loggers.Fields{
+					"method":   "DeleteFruitEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidDeleteType
+		}
+
+		err := srv.Delete(ctx, deleteRequest.ID, deleteRequest.Version)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to delete a fruit with the given id",
+				loggers.Fields{
+					"method": "DeleteFruitEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newDeleteFruitResult(deleteRequest.ID, err), nil
+	}
+}
+
 // MakeSearchFruitsEndpoint fruit endpoint to search fruits with filters.
 func MakeSearchFruitsEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
@@ -150,3 +303,185 @@ func MakeGetStatusEndpoint(srv FruitService, logger *loggers.Logger) endpoint.En
 		return dataSetStatus, nil
 	}
 }
+
+// MakeExportFruitsEndpoint create endpoint for export every fruit service.
+func MakeExportFruitsEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		fruitsFound, err := srv.ExportFruits(ctx)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to export the fruits",
+				loggers.Fields{
+					"method": "ExportFruitsEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newExportFruitsResult(fruitsFound, err), nil
+	}
+}
+
+// MakeStartImportEndpoint create endpoint for load a dataset in background service.
+func MakeStartImportEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		importRequest, ok := request.(*StartImportRequest)
+		if !ok {
+			logger.Error(
+				"invalid start import type",
+				loggers.Fields{
+					"method":   "StartImportEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidImportType
+		}
+
+		job := srv.StartImport(ctx, importRequest.Source)
+
+		return newImportJobResult(job, nil), nil
+	}
+}
+
+// MakeGetImportJobEndpoint create endpoint for get an import job service.
+func MakeGetImportJobEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		jobID, ok := request.(string)
+		if !ok {
+			logger.Error(
+				"invalid import job id",
+				loggers.Fields{
+					"method":   "GetImportJobEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidImportJobID
+		}
+
+		job, err := srv.ImportJob(ctx, jobID)
+		if err != nil {
+			logger.Debug(
+				"could not get the import job",
+				loggers.Fields{
+					"method": "GetImportJobEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newImportJobResult(job, err), nil
+	}
+}
+
+// MakeGetImportReportEndpoint create endpoint for get the rows an import job rejected service.
+func MakeGetImportReportEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		jobID, ok := request.(string)
+		if !ok {
+			logger.Error(
+				"invalid import job id",
+				loggers.Fields{
+					"method":   "GetImportReportEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidImportJobID
+		}
+
+		rejections, err := srv.ImportReport(ctx, jobID)
+		if err != nil {
+			logger.Debug(
+				"could not get the import job report",
+				loggers.Fields{
+					"method": "GetImportReportEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newImportReportResult(rejections, err), nil
+	}
+}
+
+// MakeCancelImportEndpoint create endpoint for cancel an import job service.
+func MakeCancelImportEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		jobID, ok := request.(string)
+		if !ok {
+			logger.Error(
+				"invalid import job id",
+				loggers.Fields{
+					"method":   "CancelImportEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidImportJobID
+		}
+
+		job, err := srv.CancelImport(ctx, jobID)
+		if err != nil {
+			logger.Error(
+				"something went wrong trying to cancel the import job",
+				loggers.Fields{
+					"method": "CancelImportEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newImportJobResult(job, err), nil
+	}
+}
+
+// MakeListDeadLettersEndpoint create endpoint for list the dead-lettered events service.
+func MakeListDeadLettersEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		deadLetters, err := srv.ListDeadLetters(ctx)
+		if err != nil {
+			logger.Error(
+				"could not list the dead-lettered events",
+				loggers.Fields{
+					"method": "ListDeadLettersEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newDeadLettersResult(deadLetters, err), nil
+	}
+}
+
+// MakeRedriveDeadLetterEndpoint create endpoint for re-drive a dead-lettered event service.
+func MakeRedriveDeadLetterEndpoint(srv FruitService, logger *loggers.Logger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		id, ok := request.(string)
+		if !ok {
+			logger.Error(
+				"invalid dead-lettered event id",
+				loggers.Fields{
+					"method":   "RedriveDeadLetterEndpoint",
+					"received": fmt.Sprintf("%t", request),
+				},
+			)
+
+			return nil, errInvalidDeadLetterID
+		}
+
+		err := srv.RedriveDeadLetter(ctx, id)
+		if err != nil {
+			logger.Error(
+				"could not re-drive the dead-lettered event",
+				loggers.Fields{
+					"method": "RedriveDeadLetterEndpoint",
+					"error":  err,
+				},
+			)
+		}
+
+		return newRedriveDeadLetterResult(id, err), nil
+	}
+}
@@ -59,6 +59,29 @@ func TestGetFruitNotFound(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResponse, fruitFound)
+	assert.Equal(t, fruits.ErrFruitNotFound, fruitFound.(fruits.GetFruitWithIDResult).Failed())
+}
+
+func TestCreateFruitFailed(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		err: errAnyError,
+	}
+	newFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	createFruitEndpoint := fruits.MakeCreateFruitEndpoint(fruitService, logger)
+
+	result, err := createFruitEndpoint(context.TODO(), &newFruit)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fruits.ErrDataAccess, result.(fruits.CreateFruitResult).Failed())
 }
 
 func TestCreateFruitSuccessfully(t *testing.T) {
@@ -91,6 +114,41 @@ func TestCreateFruitSuccessfully(t *testing.T) {
 	assert.NotEmpty(t, result)
 }
 
+func TestCreateBatchEndpoint(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	batchRequest := fruits.CreateBatchRequest{
+		Fruits: []fruits.NewFruit{
+			{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Country:        "Italy",
+				Classification: "Vulka Bianco",
+			},
+			{
+				Name: "Quinta dos Avidagos 2011 Avidagos Red (Douro)",
+			},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	createBatchEndpoint := fruits.MakeCreateBatchEndpoint(fruitService, logger)
+
+	result, err := createBatchEndpoint(context.TODO(), &batchRequest)
+
+	assert.NoError(t, err)
+
+	batchResult, ok := result.(fruits.CreateBatchResult)
+	assert.True(t, ok)
+	assert.NoError(t, batchResult.Failed())
+	assert.Equal(t, 1, batchResult.Created)
+	assert.Equal(t, 1, batchResult.Rejected)
+	assert.Len(t, batchResult.Items, 2)
+}
+
 func TestSearchFruitsEndpointSuccessfully(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,86 @@
+package fruits
+
+import (
+	"context"
+	"io"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// exportPageSize is the number of fruits read at once to export them.
+const exportPageSize = 500
+
+// FruitIterator reads every fruit of the repository page by page, so only
+// one page is kept in memory at a time.
+type FruitIterator struct {
+	repository Repository
+	logger     *loggers.Logger
+	after      *repository.Cursor
+	page       []repository.Fruit
+	// last is true when page is the last one.
+	last bool
+}
+
+// ExportFruits returns an iterator over every fruit. The first page is read
+// right away, so an unreachable repository is reported before any fruit is.
+func (s *Service) ExportFruits(ctx context.Context) (*FruitIterator, error) {
+	s.logger.Debug(
+		"exporting fruits",
+		loggers.Fields{
+			"method": "Service.ExportFruits",
+		},
+	)
+
+	iterator := FruitIterator{
+		repository: s.fruitRepository,
+		logger:     s.logger,
+	}
+
+	err := iterator.readPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iterator, nil
+}
+
+// Next returns the next fruit, or io.EOF when every fruit was returned.
+func (f *FruitIterator) Next(ctx context.Context) (*Fruit, error) {
+	for len(f.page) == 0 {
+		if f.last {
+			return nil, io.EOF
+		}
+
+		err := f.readPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fruit := transformFruitPortOuttoFruit(&f.page[0])
+	f.page = f.page[1:]
+
+	return fruit, nil
+}
+
+func (f *FruitIterator) readPage(ctx context.Context) error {
+	result, err := f.repository.ListFruits(ctx, f.after, exportPageSize)
+	if err != nil {
+		f.logger.Error(
+			"fruits could not be read to export them",
+			loggers.Fields{
+				"method": "FruitIterator.readPage",
+				"error":  err,
+			},
+		)
+
+		return ErrDataAccess
+	}
+
+	f.page = result.Fruits
+	f.last = result.Next == nil
+	f.after = result.Next
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFruits(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := pagedRepoMock{
+		pages: []repository.FruitPage{
+			{Fruits: []repository.Fruit{{ID: "1", Name: "Nicosia"}, {ID: "2"}}, Next: &repository.Cursor{Key: "2"}},
+			{Fruits: []repository.Fruit{}, Next: &repository.Cursor{Key: "2"}},
+			{Fruits: []repository.Fruit{{ID: "3", Version: 2}}},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	iterator, err := fruitService.ExportFruits(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, fruitRepository.afters, 1)
+
+	var got []fruits.Fruit
+
+	for {
+		fruit, err := iterator.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		assert.NoError(t, err)
+
+		got = append(got, *fruit)
+	}
+
+	assert.Equal(t, []fruits.Fruit{{ID: "1", Name: "Nicosia"}, {ID: "2"}, {ID: "3", Version: 2}}, got)
+	assert.Equal(t, []*repository.Cursor{nil, {Key: "2"}, {Key: "2"}}, fruitRepository.afters)
+}
+
+func TestExportFruitsWithError(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		err: errAnyError,
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	iterator, err := fruitService.ExportFruits(context.TODO())
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Nil(t, iterator)
+}
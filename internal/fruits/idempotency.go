@@ -0,0 +1,199 @@
+package fruits
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// IdempotencyStore defines portout behavior to remember the fruits created
+// by requests sent with an idempotency key.
+type IdempotencyStore interface {
+	// Reserve keeps the record if its key is unknown or expired, otherwise
+	// it returns the record already kept for the key.
+	Reserve(ctx context.Context, record repository.IdempotencyRecord) (*repository.IdempotencyRecord, error)
+	// Complete sets the fruit created by the request that holds the
+	// reservation and keeps the key until expiresAt. It returns
+	// repository.ErrIdempotencyKeyLost if the key expired or another
+	// request reserved it.
+	Complete(ctx context.Context, reservation repository.IdempotencyRecord, fruitID repository.FruitID, expiresAt time.Time) error
+	// Release forgets the key if the reservation still holds it, so a
+	// failed request can be sent again.
+	Release(ctx context.Context, reservation repository.IdempotencyRecord) error
+}
+
+var (
+	// ErrIdempotencyKeyInUse is returned when the request that sent the same key is still running.
+	ErrIdempotencyKeyInUse = errors.New("a request with the same idempotency key is in progress")
+	// ErrIdempotencyKeyReused is returned when the key was sent before with another fruit.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with another fruit")
+)
+
+const (
+	// idempotencyLease is how long a key is in progress, a request that
+	// crashed before completing its key blocks it only for this time.
+	idempotencyLease = time.Minute
+	// maxCompleteAttempts is the number of times the key of a created fruit
+	// is tried to be completed.
+	maxCompleteAttempts = 3
+)
+
+// idempotencyKeyContextKey is the context key of the request idempotency key.
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyStore makes fruit creation idempotent: a create sent again
+// with the same idempotency key within the window returns the fruit created
+// the first time instead of creating another one.
+func WithIdempotencyStore(store IdempotencyStore, window time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.idempotencyStore = store
+		s.idempotencyWindow = window
+	}
+}
+
+// ContextWithIdempotencyKey returns a copy of ctx that carries the idempotency key of the request.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the request, empty if it has none.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+
+	return key
+}
+
+// createOnce creates the fruit only if the key was not sent before, a replay
+// of the key returns the id of the fruit created by the first request. The
+// key is reserved for a short lease and kept for the whole window only once
+// the fruit is created. The fruit must be created within the lease, later
+// a retry could reserve the key again and create another fruit.
+func (s *Service) createOnce(ctx context.Context, key string, newfruit NewFruit) (string, error) {
+	lease := idempotencyLease
+	if s.idempotencyWindow < lease {
+		lease = s.idempotencyWindow
+	}
+
+	reservation := repository.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: newFruitFingerprint(newfruit),
+		// stores may keep the lease in seconds, so it is truncated to be
+		// compared with the stored one.
+		ExpiresAt: time.Now().Add(lease).Truncate(time.Second),
+	}
+
+	record, err := s.idempotencyStore.Reserve(ctx, reservation)
+	if err != nil {
+		s.logger.Error(
+			"idempotency key could not be reserved",
+			loggers.Fields{
+				"method": "Service.createOnce",
+				"key":    key,
+				"error":  err,
+			},
+		)
+
+		return "", ErrDataAccess
+	}
+
+	if record != nil {
+		return replay(*record, reservation)
+	}
+
+	leaseCtx, cancel := context.WithDeadline(ctx, reservation.ExpiresAt)
+	defer cancel()
+
+	fruitID, err := s.create(leaseCtx, newfruit)
+	if err != nil {
+		s.releaseIdempotencyKey(ctx, reservation)
+
+		return "", err
+	}
+
+	err = s.completeIdempotencyKey(ctx, reservation, repository.FruitID(fruitID))
+	if err != nil {
+		return "", err
+	}
+
+	return fruitID, nil
+}
+
+// completeIdempotencyKey keeps the created fruit with its key for the whole
+// window. If it cannot be kept, the key is freed when its lease expires and
+// a retry of the request would create the fruit again, so the request fails.
+// A lost key is not tried again, the reservation no longer holds it.
+func (s *Service) completeIdempotencyKey(ctx context.Context, reservation repository.IdempotencyRecord, fruitID repository.FruitID) error {
+	var err error
+
+	attempts := 0
+	for attempts < maxCompleteAttempts {
+		attempts++
+
+		err = s.idempotencyStore.Complete(ctx, reservation, fruitID, time.Now().Add(s.idempotencyWindow))
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, repository.ErrIdempotencyKeyLost) {
+			break
+		}
+	}
+
+	s.logger.Error(
+		"created fruit could not be kept with its idempotency key",
+		loggers.Fields{
+			"method":   "Service.completeIdempotencyKey",
+			"key":      reservation.Key,
+			"fruitID":  fruitID,
+			"attempts": attempts,
+			"error":    err,
+		},
+	)
+
+	return ErrDataAccess
+}
+
+// replay returns the result of the request that reserved the key first.
+func replay(record, reservation repository.IdempotencyRecord) (string, error) {
+	if record.Fingerprint != reservation.Fingerprint {
+		return "", ErrIdempotencyKeyReused
+	}
+
+	if record.FruitID == "" {
+		return "", ErrIdempotencyKeyInUse
+	}
+
+	return repository.FruitIDValue(record.FruitID), nil
+}
+
+func (s *Service) releaseIdempotencyKey(ctx context.Context, reservation repository.IdempotencyRecord) {
+	err := s.idempotencyStore.Release(ctx, reservation)
+	if err != nil {
+		s.logger.Error(
+			"idempotency key could not be released, it cannot be retried until it expires",
+			loggers.Fields{
+				"method": "Service.releaseIdempotencyKey",
+				"key":    reservation.Key,
+				"error":  err,
+			},
+		)
+	}
+}
+
+// newFruitFingerprint identifies the content of a new fruit.
+func newFruitFingerprint(newfruit NewFruit) string {
+	data, err := json.Marshal(newfruit)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
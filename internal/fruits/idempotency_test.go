@@ -0,0 +1,280 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateFruitWithIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	fruitID, err := fruitService.Create(ctx, givenFruit)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fruitID)
+	assert.Equal(t, repository.FruitID(fruitID), store.records["3a5f7c1e"].FruitID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), store.records["3a5f7c1e"].ExpiresAt, time.Minute)
+
+	replayedID, err := fruitService.Create(ctx, givenFruit)
+	assert.NoError(t, err)
+	assert.Equal(t, fruitID, replayedID)
+	assert.Len(t, fruitRepository.repo, 1)
+
+	otherFruit := givenFruit
+	otherFruit.Name = "Nicosia 2014 Vulka Bianco  (Etna)"
+
+	_, err = fruitService.Create(ctx, otherFruit)
+	assert.Equal(t, fruits.ErrIdempotencyKeyReused, err)
+	assert.Len(t, fruitRepository.repo, 1)
+
+	otherID, err := fruitService.Create(context.TODO(), givenFruit)
+	assert.NoError(t, err)
+	assert.NotEqual(t, fruitID, otherID)
+	assert.Len(t, fruitRepository.repo, 2)
+}
+
+func TestCreateFruitWithIdempotencyKeyInUse(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	_, err := fruitService.Create(ctx, givenFruit)
+	assert.NoError(t, err)
+
+	// the first request is still running.
+	record := store.records["3a5f7c1e"]
+	record.FruitID = ""
+	store.records["3a5f7c1e"] = record
+
+	_, err = fruitService.Create(ctx, givenFruit)
+	assert.Equal(t, fruits.ErrIdempotencyKeyInUse, err)
+	assert.Len(t, fruitRepository.repo, 1)
+}
+
+func TestFailedCreateReleasesIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		err: errors.New("any error"),
+	}
+	store := newIdempotencyStoreMock()
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	_, err := fruitService.Create(ctx, givenFruit)
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Empty(t, store.records)
+}
+
+func TestCreateFruitWhenIdempotencyStoreFails(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	store.err = errors.New("any error")
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	_, err := fruitService.Create(ctx, givenFruit)
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Empty(t, fruitRepository.repo)
+}
+
+func TestCreateFruitRetriesIdempotencyKeyCompletion(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	store.completeFailures = 2
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	fruitID, err := fruitService.Create(ctx, givenFruit)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, store.completeCalls)
+	assert.Equal(t, repository.FruitID(fruitID), store.records["3a5f7c1e"].FruitID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), store.records["3a5f7c1e"].ExpiresAt, time.Minute)
+}
+
+func TestCreateFruitWhenIdempotencyKeyCannotBeCompleted(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	store.completeFailures = 3
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	_, err := fruitService.Create(ctx, givenFruit)
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Equal(t, 3, store.completeCalls)
+	// the key is in progress only until its short lease expires.
+	assert.Empty(t, store.records["3a5f7c1e"].FruitID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), store.records["3a5f7c1e"].ExpiresAt, 5*time.Second)
+}
+
+func TestCreateFruitWhenIdempotencyKeyIsLost(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	store := newIdempotencyStoreMock()
+	// the lease expired while the fruit was created and a retry reserved the key.
+	retryReservation := repository.IdempotencyRecord{
+		Key:         "3a5f7c1e",
+		Fingerprint: "retry",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	store.beforeComplete = func() {
+		store.records[retryReservation.Key] = retryReservation
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithIdempotencyStore(store, time.Hour))
+	ctx := fruits.ContextWithIdempotencyKey(context.TODO(), "3a5f7c1e")
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	_, err := fruitService.Create(ctx, givenFruit)
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Equal(t, 1, store.completeCalls)
+	assert.Equal(t, retryReservation, store.records["3a5f7c1e"])
+}
+
+type idempotencyStoreMock struct {
+	err error
+	// completeFailures is the number of times Complete fails before it works.
+	completeFailures int
+	completeCalls    int
+	// beforeComplete runs before the key is completed, to change it meanwhile.
+	beforeComplete func()
+	records        map[string]repository.IdempotencyRecord
+}
+
+func newIdempotencyStoreMock() *idempotencyStoreMock {
+	return &idempotencyStoreMock{
+		records: make(map[string]repository.IdempotencyRecord),
+	}
+}
+
+func (i *idempotencyStoreMock) Reserve(_ context.Context, record repository.IdempotencyRecord) (*repository.IdempotencyRecord, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+
+	current, ok := i.records[record.Key]
+	if ok {
+		return &current, nil
+	}
+
+	i.records[record.Key] = record
+
+	return nil, nil
+}
+
+func (i *idempotencyStoreMock) Complete(_ context.Context, reservation repository.IdempotencyRecord, fruitID repository.FruitID, expiresAt time.Time) error {
+	if i.beforeComplete != nil {
+		i.beforeComplete()
+	}
+
+	i.completeCalls++
+	if i.completeCalls <= i.completeFailures {
+		return errors.New("any error")
+	}
+
+	record, ok := i.records[reservation.Key]
+	if !ok || !record.Holds(reservation, time.Now()) {
+		return repository.ErrIdempotencyKeyLost
+	}
+
+	record.FruitID = fruitID
+	record.ExpiresAt = expiresAt
+	i.records[reservation.Key] = record
+
+	return nil
+}
+
+func (i *idempotencyStoreMock) Release(_ context.Context, reservation repository.IdempotencyRecord) error {
+	record, ok := i.records[reservation.Key]
+	if ok && record.Holds(reservation, time.Now()) {
+		delete(i.records, reservation.Key)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package fruits
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/google/uuid"
+)
+
+// maxImportJobs is the number of import jobs kept, the oldest finished ones are forgotten first.
+const maxImportJobs = 100
+
+var (
+	// ErrImportJobNotFound is returned when there is no import job with the given id.
+	ErrImportJobNotFound = errors.New("import job not found")
+	// ErrImportJobFinished is returned when a job that already finished is cancelled.
+	ErrImportJobFinished = errors.New("import job already finished")
+)
+
+// ImportSource defines portin behavior to read an uploaded dataset, the
+// service closes it once the import job ends.
+type ImportSource interface {
+	DatasetSource
+	Close() error
+}
+
+// importJob is a dataset load and its progress.
+type importJob struct {
+	status DatasetStatus
+	// report has every rejected row, the status only has the first ones.
+	report []DatasetRejection
+	cancel context.CancelFunc
+	// done is closed when the load ends.
+	done chan struct{}
+}
+
+// StartImport loads the given dataset in background and returns the status
+// of the new import job, its progress is checked with ImportJob.
+func (s *Service) StartImport(ctx context.Context, source ImportSource) DatasetStatus {
+	jobCtx, cancel := context.WithCancel(context.Background())
+	job := s.addImportJob(cancel)
+
+	s.logger.Info(
+		"starting import job",
+		loggers.Fields{
+			"method": "Service.StartImport",
+			"jobID":  job.status.JobID,
+		},
+	)
+
+	go func() {
+		defer cancel()
+		defer s.closeImportSource(job.status.JobID, source)
+
+		s.runImport(jobCtx, job, source)
+	}()
+
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	return job.snapshot()
+}
+
+// ImportJob returns the status of the import job with the given id.
+func (s *Service) ImportJob(ctx context.Context, jobID string) (DatasetStatus, error) {
+	s.logger.Debug(
+		"getting import job",
+		loggers.Fields{
+			"method": "Service.ImportJob",
+			"jobID":  jobID,
+		},
+	)
+
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	job, ok := s.importJobs[jobID]
+	if !ok {
+		return DatasetStatus{}, ErrImportJobNotFound
+	}
+
+	return job.snapshot(), nil
+}
+
+// ImportReport returns every row the import job with the given id rejected so far.
+func (s *Service) ImportReport(ctx context.Context, jobID string) ([]DatasetRejection, error) {
+	s.logger.Debug(
+		"getting import job report",
+		loggers.Fields{
+			"method": "Service.ImportReport",
+			"jobID":  jobID,
+		},
+	)
+
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	job, ok := s.importJobs[jobID]
+	if !ok {
+		return nil, ErrImportJobNotFound
+	}
+
+	return append([]DatasetRejection(nil), job.report...), nil
+}
+
+// CancelImport stops the import job with the given id and waits until it
+// ends, the fruits loaded before are kept.
+func (s *Service) CancelImport(ctx context.Context, jobID string) (DatasetStatus, error) {
+	s.logger.Info(
+		"cancelling import job",
+		loggers.Fields{
+			"method": "Service.CancelImport",
+			"jobID":  jobID,
+		},
+	)
+
+	s.importMu.RLock()
+	job, ok := s.importJobs[jobID]
+	finished := ok && job.finished()
+	s.importMu.RUnlock()
+
+	if !ok {
+		return DatasetStatus{}, ErrImportJobNotFound
+	}
+
+	if finished {
+		return DatasetStatus{}, ErrImportJobFinished
+	}
+
+	job.cancel()
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return DatasetStatus{}, ctx.Err()
+	}
+
+	s.importMu.RLock()
+	defer s.importMu.RUnlock()
+
+	return job.snapshot(), nil
+}
+
+// addImportJob keeps a new loading job, forgetting the oldest finished job if there are too many.
+func (s *Service) addImportJob(cancel context.CancelFunc) *importJob {
+	job := importJob{
+		status: DatasetStatus{
+			JobID:  uuid.New().String(),
+			Status: DatasetStateLoading,
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
+
+	if len(s.importJobIDs) >= maxImportJobs {
+		s.forgetOldestImportJob()
+	}
+
+	s.importJobs[job.status.JobID] = &job
+	s.importJobIDs = append(s.importJobIDs, job.status.JobID)
+
+	return &job
+}
+
+// forgetOldestImportJob removes the oldest finished job, except the dataset loaded at startup.
+func (s *Service) forgetOldestImportJob() {
+	for index, jobID := range s.importJobIDs {
+		if jobID == s.datasetJobID || !s.importJobs[jobID].finished() {
+			continue
+		}
+
+		delete(s.importJobs, jobID)
+		s.importJobIDs = append(s.importJobIDs[:index], s.importJobIDs[index+1:]...)
+
+		return
+	}
+}
+
+func (s *Service) closeImportSource(jobID string, source ImportSource) {
+	err := source.Close()
+	if err != nil {
+		s.logger.Error(
+			"import source could not be closed",
+			loggers.Fields{
+				"method": "Service.closeImportSource",
+				"jobID":  jobID,
+				"error":  err,
+			},
+		)
+	}
+}
+
+func (j *importJob) finished() bool {
+	return j.status.Status != DatasetStateLoading
+}
@@ -0,0 +1,135 @@
+package fruits_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportJob(t *testing.T) {
+	t.Parallel()
+
+	source := newImportSourceMock(&datasetSourceMock{
+		rows: []fruits.DatasetRow{
+			{Line: 2, Fruit: validNewFruit("Nicosia 2013 Vulka Bianco  (Etna)")},
+			{Line: 3, Err: errors.New("price must be a number")},
+			{Line: 4, Fruit: validNewFruit("Quinta dos Avidagos 2011 Avidagos Red (Douro)")},
+		},
+	})
+	expectedReport := []fruits.DatasetRejection{
+		{Line: 3, Reason: "price must be a number"},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	started := fruitService.StartImport(ctx, source)
+
+	source.waitClosed(t)
+
+	got, err := fruitService.ImportJob(ctx, started.JobID)
+	assert.NoError(t, err)
+
+	report, err := fruitService.ImportReport(ctx, started.JobID)
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, started.JobID)
+	assert.Equal(t, fruits.DatasetStateLoading, started.Status)
+	assert.Equal(t, started.JobID, got.JobID)
+	assert.Equal(t, fruits.DatasetStateError, got.Status)
+	assert.Equal(t, "2 fruits were loaded, 1 rows were rejected", got.Message)
+	assert.Equal(t, 2, got.Loaded)
+	assert.Equal(t, 1, got.Rejected)
+	assert.Equal(t, expectedReport, report)
+	assert.Len(t, fruitRepository.repo, 2)
+}
+
+func TestCancelImportJob(t *testing.T) {
+	t.Parallel()
+
+	source := newImportSourceMock(&endlessSourceMock{})
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	started := fruitService.StartImport(ctx, source)
+
+	got, err := fruitService.CancelImport(ctx, started.JobID)
+	assert.NoError(t, err)
+
+	_, err = fruitService.CancelImport(ctx, started.JobID)
+	assert.Equal(t, fruits.ErrImportJobFinished, err)
+
+	source.waitClosed(t)
+
+	assert.Equal(t, fruits.DatasetStateCancelled, got.Status)
+	assert.Len(t, fruitRepository.repo, got.Loaded)
+}
+
+func TestUnknownImportJob(t *testing.T) {
+	t.Parallel()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	_, jobErr := fruitService.ImportJob(ctx, "4b1d6d2a")
+	_, reportErr := fruitService.ImportReport(ctx, "4b1d6d2a")
+	_, cancelErr := fruitService.CancelImport(ctx, "4b1d6d2a")
+
+	assert.Equal(t, fruits.ErrImportJobNotFound, jobErr)
+	assert.Equal(t, fruits.ErrImportJobNotFound, reportErr)
+	assert.Equal(t, fruits.ErrImportJobNotFound, cancelErr)
+}
+
+// importSourceMock is an uploaded dataset that tells when the service closed it.
+type importSourceMock struct {
+	fruits.DatasetSource
+	closed chan struct{}
+}
+
+func newImportSourceMock(source fruits.DatasetSource) *importSourceMock {
+	return &importSourceMock{
+		DatasetSource: source,
+		closed:        make(chan struct{}),
+	}
+}
+
+func (i *importSourceMock) Close() error {
+	close(i.closed)
+
+	return nil
+}
+
+func (i *importSourceMock) waitClosed(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-i.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("import source was not closed")
+	}
+}
+
+// endlessSourceMock returns valid rows until the import is cancelled.
+type endlessSourceMock struct {
+	line int
+}
+
+func (e *endlessSourceMock) Next() (fruits.DatasetRow, error) {
+	e.line++
+
+	return fruits.DatasetRow{Line: e.line, Fruit: validNewFruit("Rainstorm 2013 Pinot Gris")}, nil
+}
@@ -57,6 +57,70 @@ func (w *FruitMiddleware) Create(ctx context.Context, newfruit NewFruit) (string
 	return fruitID, err
 }
 
+// CreateBatch creates the fruits of a batch.
+func (w *FruitMiddleware) CreateBatch(ctx context.Context, newfruits []NewFruit) ([]BatchItemResult, error) {
+	w.counter.CountRequest()
+
+	results, err := w.next.CreateBatch(ctx, newfruits)
+	if err != nil {
+		w.counter.CountError()
+
+		return results, err
+	}
+
+	w.counter.CountSuccess()
+
+	return results, err
+}
+
+// Update replaces the data of the fruit with the given id.
+func (w *FruitMiddleware) Update(ctx context.Context, fruitID string, version int64, fruit NewFruit) (*Fruit, error) {
+	w.counter.CountRequest()
+
+	result, err := w.next.Update(ctx, fruitID, version, fruit)
+	if err != nil {
+		w.counter.CountError()
+
+		return result, err
+	}
+
+	w.counter.CountSuccess()
+
+	return result, err
+}
+
+// Patch changes only the given fields of the fruit with the given id.
+func (w *FruitMiddleware) Patch(ctx context.Context, fruitID string, version int64, patch FruitPatch) (*Fruit, error) {
+	w.counter.CountRequest()
+
+	result, err := w.next.Patch(ctx, fruitID, version, patch)
+	if err != nil {
+		w.counter.CountError()
+
+		return result, err
+	}
+
+	w.counter.CountSuccess()
+
+	return result, err
+}
+
+// Delete deletes the fruit with the given id.
+func (w *FruitMiddleware) Delete(ctx context.Context, fruitID string, version int64) error {
+	w.counter.CountRequest()
+
+	err := w.next.Delete(ctx, fruitID, version)
+	if err != nil {
+		w.counter.CountError()
+
+		return err
+	}
+
+	w.counter.CountSuccess()
+
+	return err
+}
+
 // SearchFruits search fruits who match the given filters.
 func (w *FruitMiddleware) SearchFruits(ctx context.Context, givenFilter SearchFruitFilter) (*SearchFruitsResult, error) {
 	w.counter.CountRequest()
@@ -73,6 +137,22 @@ func (w *FruitMiddleware) SearchFruits(ctx context.Context, givenFilter SearchFr
 	return result, err
 }
 
+// ExportFruits returns an iterator over every fruit.
+func (w *FruitMiddleware) ExportFruits(ctx context.Context) (*FruitIterator, error) {
+	w.counter.CountRequest()
+
+	result, err := w.next.ExportFruits(ctx)
+	if err != nil {
+		w.counter.CountError()
+
+		return result, err
+	}
+
+	w.counter.CountSuccess()
+
+	return result, err
+}
+
 // DatasetStatus check the status of the fruit dataset.
 func (w *FruitMiddleware) DatasetStatus(ctx context.Context) DatasetStatus {
 	w.counter.CountRequest()
@@ -81,3 +161,92 @@ func (w *FruitMiddleware) DatasetStatus(ctx context.Context) DatasetStatus {
 
 	return status
 }
+
+// StartImport loads a dataset in background.
+func (w *FruitMiddleware) StartImport(ctx context.Context, source ImportSource) DatasetStatus {
+	w.counter.CountRequest()
+	job := w.next.StartImport(ctx, source)
+	w.counter.CountSuccess()
+
+	return job
+}
+
+// ImportJob returns the status of an import job.
+func (w *FruitMiddleware) ImportJob(ctx context.Context, jobID string) (DatasetStatus, error) {
+	w.counter.CountRequest()
+
+	job, err := w.next.ImportJob(ctx, jobID)
+	if err != nil {
+		w.counter.CountError()
+
+		return job, err
+	}
+
+	w.counter.CountSuccess()
+
+	return job, err
+}
+
+// ImportReport returns the rows an import job rejected.
+func (w *FruitMiddleware) ImportReport(ctx context.Context, jobID string) ([]DatasetRejection, error) {
+	w.counter.CountRequest()
+
+	rejections, err := w.next.ImportReport(ctx, jobID)
+	if err != nil {
+		w.counter.CountError()
+
+		return rejections, err
+	}
+
+	w.counter.CountSuccess()
+
+	return rejections, err
+}
+
+// CancelImport stops an import job.
+func (w *FruitMiddleware) CancelImport(ctx context.Context, jobID string) (DatasetStatus, error) {
+	w.counter.CountRequest()
+
+	job, err := w.next.CancelImport(ctx, jobID)
+	if err != nil {
+		w.counter.CountError()
+
+		return job, err
+	}
+
+	w.counter.CountSuccess()
+
+	return job, err
+}
+
+// ListDeadLetters returns the events that could not be published.
+func (w *FruitMiddleware) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	w.counter.CountRequest()
+
+	deadLetters, err := w.next.ListDeadLetters(ctx)
+	if err != nil {
+		w.counter.CountError()
+
+		return deadLetters, err
+	}
+
+	w.counter.CountSuccess()
+
+	return deadLetters, err
+}
+
+// RedriveDeadLetter publishes a dead-lettered event again.
+func (w *FruitMiddleware) RedriveDeadLetter(ctx context.Context, id string) error {
+	w.counter.CountRequest()
+
+	err := w.next.RedriveDeadLetter(ctx, id)
+	if err != nil {
+		w.counter.CountError()
+
+		return err
+	}
+
+	w.counter.CountSuccess()
+
+	return err
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"strings"
 	"time"
 
@@ -14,8 +16,19 @@ import (
 type FruitService interface {
 	GetFruitWithID(ctx context.Context, fruitID string) (*Fruit, error)
 	Create(ctx context.Context, newfruit NewFruit) (string, error)
+	CreateBatch(ctx context.Context, newfruits []NewFruit) ([]BatchItemResult, error)
+	ExportFruits(ctx context.Context) (*FruitIterator, error)
+	Update(ctx context.Context, fruitID string, version int64, fruit NewFruit) (*Fruit, error)
+	Patch(ctx context.Context, fruitID string, version int64, patch FruitPatch) (*Fruit, error)
+	Delete(ctx context.Context, fruitID string, version int64) error
 	SearchFruits(ctx context.Context, givenFilter SearchFruitFilter) (*SearchFruitsResult, error)
 	DatasetStatus(ctx context.Context) DatasetStatus
+	StartImport(ctx context.Context, source ImportSource) DatasetStatus
+	ImportJob(ctx context.Context, jobID string) (DatasetStatus, error)
+	ImportReport(ctx context.Context, jobID string) ([]DatasetRejection, error)
+	CancelImport(ctx context.Context, jobID string) (DatasetStatus, error)
+	ListDeadLetters(ctx context.Context) ([]DeadLetter, error)
+	RedriveDeadLetter(ctx context.Context, id string) error
 }
 
 // MandatoryError define an error for mandatory fields.
@@ -23,6 +36,29 @@ type MandatoryError struct {
 	Fields []string
 }
 
+// ValidationError define an error for fruits with invalid fields, it has
+// every violation found. When mandatory fields are missing it wraps a
+// MandatoryError with them.
+type ValidationError struct {
+	Violations []Violation
+}
+
+const (
+	// oldestYear is the oldest four digit year a fruit can have, smaller
+	// years are two digit vintages like the ones of the fruit dataset.
+	oldestYear = 1800
+	// lastTwoDigitYear is the greatest two digit vintage.
+	lastTwoDigitYear = 99
+	// mandatoryReason is the reason of the violations of mandatory fields.
+	mandatoryReason = "is mandatory"
+)
+
+// Violation is a fruit field that is not valid and why.
+type Violation struct {
+	Field  string
+	Reason string
+}
+
 // DatasetState define fruit dataset state.
 type DatasetState string
 
@@ -30,26 +66,163 @@ type DatasetState string
 type CreateFruitResult struct {
 	ID  string
 	Err string
+	err error
+}
+
+// CreateBatchRequest contains the fruits to create at once.
+type CreateBatchRequest struct {
+	Fruits []NewFruit
+}
+
+// BatchItemResult contains the outcome of creating one fruit of a batch.
+type BatchItemResult struct {
+	// Index is the position of the fruit in the batch.
+	Index int
+	// ID is the id of the created fruit, empty if it was not created.
+	ID  string
+	Err error
+}
+
+// CreateBatchResult standard response for create fruits at once.
+type CreateBatchResult struct {
+	Items    []BatchItemResult
+	Created  int
+	Rejected int
+	Err      string
+	err      error
+}
+
+// ExportFruitsResult standard response for export every fruit.
+type ExportFruitsResult struct {
+	Fruits *FruitIterator
+	Err    string
+	err    error
+}
+
+// StartImportRequest contains the dataset to load in background.
+type StartImportRequest struct {
+	Source ImportSource
+}
+
+// ImportJobResult standard response for import job operations.
+type ImportJobResult struct {
+	Job DatasetStatus
+	Err string
+	err error
+}
+
+// ImportReportResult standard response for get the rows an import job rejected.
+type ImportReportResult struct {
+	Rejections []DatasetRejection
+	Err        string
+	err        error
+}
+
+// DeadLettersResult standard response for list the dead-lettered events.
+type DeadLettersResult struct {
+	DeadLetters []DeadLetter
+	Err         string
+	err         error
+}
+
+// RedriveDeadLetterResult standard response for re-drive a dead-lettered event.
+type RedriveDeadLetterResult struct {
+	ID  string
+	Err string
+	err error
 }
 
 // GetFruitWithIDResult standard roespnse for get a Fruit with an ID.
 type GetFruitWithIDResult struct {
 	Fruit *Fruit
 	Err   string
+	err   error
+}
+
+// UpdateFruitResult standard response for update or patch a Fruit.
+type UpdateFruitResult struct {
+	Fruit *Fruit
+	Err   string
+	err   error
+}
+
+// DeleteFruitResult standard response for delete a Fruit.
+type DeleteFruitResult struct {
+	ID  string
+	Err string
+	err error
+}
+
+// UpdateFruitRequest contains the data to replace a fruit.
+type UpdateFruitRequest struct {
+	ID string
+	// Version the fruit must have to be replaced, or AnyVersion.
+	Version int64
+	Fruit   NewFruit
+}
+
+// PatchFruitRequest contains the fields to change in a fruit.
+type PatchFruitRequest struct {
+	ID string
+	// Version the fruit must have to be patched, or AnyVersion.
+	Version int64
+	Patch   FruitPatch
+}
+
+// DeleteFruitRequest contains the fruit to delete.
+type DeleteFruitRequest struct {
+	ID string
+	// Version the fruit must have to be deleted, or AnyVersion.
+	Version int64
 }
 
 // SearchFruitsDataResult standard roespnse for get a Fruit with an ID.
 type SearchFruitsDataResult struct {
 	SearchResult *SearchFruitsResult
 	Err          string
+	err          error
 }
 
-// SearchFruitFilter contains filters to search fruits.
+// SearchFruitFilter contains filters to search fruits. Empty text filters
+// and nil ranges are not applied.
 type SearchFruitFilter struct {
 	// Page page to query
 	Start int
 	// rows per page
 	Count int
+	// exact match filters
+	Country        string
+	Province       string
+	Region         string
+	Variety        string
+	Classification string
+	Vault          string
+	// inclusive ranges
+	MinYear  *int
+	MaxYear  *int
+	MinPrice *float32
+	MaxPrice *float32
+	// Sort fields to sort the results by, in order of precedence.
+	Sort []SortField
+	// Cursor is the next cursor of the previous page, if it is present Start is ignored.
+	Cursor string
+	// Query are the words to look for in the fruit name, local name and
+	// description. Results are sorted by relevance unless Sort is given.
+	Query string
+	// Facets are the fields to count every matching fruit by.
+	Facets []string
+}
+
+// SortField defines a field to sort search results by.
+type SortField struct {
+	Name       string
+	Descending bool
+}
+
+// InvalidFilterError define an error for search filters that cannot be applied.
+type InvalidFilterError struct {
+	Filter string
+	Reason string
 }
 
 // SearchFruitsResult contains search fruits result data.
@@ -58,6 +231,16 @@ type SearchFruitsResult struct {
 	Total  int
 	Start  int
 	Count  int
+	// NextCursor continues the search after this page, empty if this is the last one.
+	NextCursor string
+	// Facets are the buckets of every requested facet field, by field name.
+	Facets map[string][]FacetBucket
+}
+
+// FacetBucket contains the number of fruits found with a field value.
+type FacetBucket struct {
+	Value string
+	Count int
 }
 
 // NewFruit contains fruit data.
@@ -77,6 +260,23 @@ type NewFruit struct {
 	WikiPage       string  `json:"wiki_page"`
 }
 
+// FruitPatch contains the fruit fields to change, nil fields are left as they are.
+type FruitPatch struct {
+	Name           *string  `json:"name,omitempty"`
+	Variety        *string  `json:"variety,omitempty"`
+	Vault          *string  `json:"vault,omitempty"`
+	Year           *int     `json:"year,omitempty"`
+	Price          *float32 `json:"price,omitempty"`
+	Country        *string  `json:"country,omitempty"`
+	Province       *string  `json:"province,omitempty"`
+	Region         *string  `json:"region,omitempty"`
+	Finca          *string  `json:"finca,omitempty"`
+	Description    *string  `json:"description,omitempty"`
+	Classification *string  `json:"classification,omitempty"`
+	LocalName      *string  `json:"local_name,omitempty"`
+	WikiPage       *string  `json:"wiki_page,omitempty"`
+}
+
 // Fruit contains fruit data.
 type Fruit struct {
 	ID             string  `json:"id"`
@@ -93,6 +293,9 @@ type Fruit struct {
 	Classification string  `json:"classification"`
 	LocalName      string  `json:"local_name"`
 	WikiPage       string  `json:"wiki_page"`
+	Version        int64   `json:"version"`
+	// LastModified is when the fruit was created or last changed, zero if it is unknown.
+	LastModified time.Time `json:"last_modified"`
 }
 
 // FruitItem contains few fruit data, just to show reference data.
@@ -100,25 +303,77 @@ type FruitItem struct {
 	ID string `json:"id"`
 	// Name fruit's name.
 	Name string `json:"name"`
+	// LastModified is when the fruit was created or last changed, zero if it is unknown.
+	LastModified time.Time `json:"last_modified"`
+}
+
+// DeadLetter contains data about an event that could not be published.
+type DeadLetter struct {
+	ID       string
+	SourceID string
+	Name     string
+	// Attempts number of times the event was tried to be published.
+	Attempts  int
+	LastError string
+	// DeadLetteredAt when the event stopped being tried.
+	DeadLetteredAt time.Time
 }
 
 // DatasetStatus contains data about the fruit dataset result.
 type DatasetStatus struct {
+	// JobID is the id of the import job that loads the dataset.
+	JobID     string
 	Status    DatasetState
 	Message   string
 	Timestamp int64
+	// Loaded number of dataset rows stored.
+	Loaded int
+	// Rejected number of dataset rows that were not stored.
+	Rejected int
+	// Rejections details of the first rejected rows.
+	Rejections []DatasetRejection
 }
 
 const (
-	DatasetStateOK    DatasetState = "ok"
-	DatasetStateError DatasetState = "error"
+	DatasetStateOK        DatasetState = "ok"
+	DatasetStateError     DatasetState = "error"
+	DatasetStateLoading   DatasetState = "loading"
+	DatasetStateCancelled DatasetState = "cancelled"
 )
 
 func (m MandatoryError) Error() string {
-	return fmt.Sprintf(
-		"these fields are mandatory: %s.",
-		strings.Join(m.Fields, ", "),
-	)
+	return fmt.Sprintf("these fields are mandatory: %s.", strings.Join(m.Fields, ", "))
+}
+
+func (v ValidationError) Error() string {
+	violations := make([]string, len(v.Violations))
+	for index, violation := range v.Violations {
+		violations[index] = violation.Field + " " + violation.Reason
+	}
+
+	return fmt.Sprintf("invalid fruit: %s", strings.Join(violations, ", "))
+}
+
+// Unwrap returns a MandatoryError with the missing mandatory fields, nil if
+// there are none.
+func (v ValidationError) Unwrap() error {
+	var fields []string
+
+	for _, violation := range v.Violations {
+		if violation.Reason == mandatoryReason {
+			fields = append(fields, violation.Field)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return MandatoryError{Fields: fields}
+}
+
+func (i InvalidFilterError) Error() string {
+	return fmt.Sprintf("invalid filter %s: %s", i.Filter, i.Reason)
 }
 
 // ToFruitPortOut transforms new fruit to a fruit port out.
@@ -140,35 +395,74 @@ func (n NewFruit) ToFruitPortOut() repository.NewFruit {
 	}
 }
 
-// Validate check is the given data to create a fruit is correct.
+// Validate check is the given data to create a fruit is correct, it returns
+// a ValidationError with every invalid field otherwise.
 func (n NewFruit) Validate() error {
-	var invalidList []string
+	var violations []Violation
+
+	for _, field := range []struct{ name, value string }{
+		{"name", n.Name},
+		{"classification", n.Classification},
+		{"country", n.Country},
+		{"vault", n.Vault},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			violations = append(violations, Violation{Field: field.name, Reason: mandatoryReason})
+		}
+	}
 
-	if n.Name == "" {
-		invalidList = append(invalidList, "name")
+	if n.Country != "" && !isKnownCountry(n.Country) {
+		violations = append(violations, Violation{Field: "country", Reason: "must be an ISO 3166 country code or name"})
 	}
 
-	if n.Classification == "" {
-		invalidList = append(invalidList, "classification")
+	if !isValidYear(n.Year) {
+		violations = append(violations, Violation{Field: "year", Reason: fmt.Sprintf("must be a two digit vintage or a year between %d and next year", oldestYear)})
 	}
 
-	if n.Country == "" {
-		invalidList = append(invalidList, "country")
+	if math.IsNaN(float64(n.Price)) || math.IsInf(float64(n.Price), 0) || n.Price < 0 {
+		violations = append(violations, Violation{Field: "price", Reason: "must be a non-negative number"})
 	}
 
-	if n.Vault == "" {
-		invalidList = append(invalidList, "vault")
+	if n.WikiPage != "" && !isWebURL(n.WikiPage) && !isHandle(n.WikiPage) {
+		violations = append(violations, Violation{Field: "wiki_page", Reason: "must be an http or https URL or an @handle"})
 	}
 
-	if len(invalidList) == 0 {
+	if len(violations) == 0 {
 		return nil
 	}
 
-	return MandatoryError{
-		Fields: invalidList,
+	return ValidationError{
+		Violations: violations,
 	}
 }
 
+// isValidYear checks if the given year is unknown (zero), a two digit vintage
+// or a four digit year between oldestYear and next year.
+func isValidYear(year int) bool {
+	if year >= 0 && year <= lastTwoDigitYear {
+		return true
+	}
+
+	return year >= oldestYear && year <= time.Now().Year()+1
+}
+
+// isHandle checks if the given value is a social media handle like @kerinokeefe.
+func isHandle(value string) bool {
+	handle := strings.TrimPrefix(value, "@")
+
+	return handle != value && handle != "" && !strings.ContainsAny(handle, " \t\n/@")
+}
+
+// isWebURL checks if the given value is an absolute http or https URL.
+func isWebURL(value string) bool {
+	webURL, err := url.ParseRequestURI(value)
+	if err != nil {
+		return false
+	}
+
+	return (webURL.Scheme == "http" || webURL.Scheme == "https") && webURL.Host != ""
+}
+
 // NewFruit transforms new fruit to a fruit port out.
 func (n NewFruit) NewFruit(fruitID string) Fruit {
 	return Fruit{
@@ -189,6 +483,58 @@ func (n NewFruit) NewFruit(fruitID string) Fruit {
 	}
 }
 
+// apply returns the data of the given fruit with the patch fields changed.
+func (p FruitPatch) apply(fruit Fruit) NewFruit {
+	result := fruit.toNewFruit()
+
+	setString(&result.Name, p.Name)
+	setString(&result.Variety, p.Variety)
+	setString(&result.Vault, p.Vault)
+	setString(&result.Country, p.Country)
+	setString(&result.Province, p.Province)
+	setString(&result.Region, p.Region)
+	setString(&result.Finca, p.Finca)
+	setString(&result.Description, p.Description)
+	setString(&result.Classification, p.Classification)
+	setString(&result.LocalName, p.LocalName)
+	setString(&result.WikiPage, p.WikiPage)
+
+	if p.Year != nil {
+		result.Year = *p.Year
+	}
+
+	if p.Price != nil {
+		result.Price = *p.Price
+	}
+
+	return result
+}
+
+func setString(field *string, value *string) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// toNewFruit returns the fruit data without its id.
+func (u Fruit) toNewFruit() NewFruit {
+	return NewFruit{
+		Name:           u.Name,
+		Variety:        u.Variety,
+		Year:           u.Year,
+		Price:          u.Price,
+		Vault:          u.Vault,
+		Country:        u.Country,
+		Province:       u.Province,
+		Region:         u.Region,
+		Finca:          u.Finca,
+		Description:    u.Description,
+		Classification: u.Classification,
+		LocalName:      u.LocalName,
+		WikiPage:       u.WikiPage,
+	}
+}
+
 // transformFruitPortOuttoFruit transforms the given fruit port out to service fruit.
 func transformFruitPortOuttoFruit(fruitRepo *repository.Fruit) *Fruit {
 	if fruitRepo == nil {
@@ -210,6 +556,8 @@ func transformFruitPortOuttoFruit(fruitRepo *repository.Fruit) *Fruit {
 		Classification: fruitRepo.Classification,
 		LocalName:      fruitRepo.LocalName,
 		WikiPage:       fruitRepo.WikiPage,
+		Version:        fruitRepo.Version,
+		LastModified:   fruitRepo.LastModified,
 	}
 
 	return &newfruit
@@ -222,8 +570,9 @@ func transformFruitPortOuttoFruitItem(fruitRepo *repository.Fruit) *FruitItem {
 	}
 
 	newfruit := FruitItem{
-		ID:   repository.FruitIDValue(fruitRepo.ID),
-		Name: fruitRepo.Name,
+		ID:           repository.FruitIDValue(fruitRepo.ID),
+		Name:         fruitRepo.Name,
+		LastModified: fruitRepo.LastModified,
 	}
 
 	return &newfruit
@@ -244,7 +593,99 @@ func newGetFruitWithIDResult(fruit *Fruit, err error) GetFruitWithIDResult {
 	return GetFruitWithIDResult{
 		Fruit: fruit,
 		Err:   errmessage,
+		err:   err,
+	}
+}
+
+// newUpdateFruitResult create a new UpdateFruitResult.
+func newUpdateFruitResult(fruit *Fruit, err error) UpdateFruitResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return UpdateFruitResult{
+		Fruit: fruit,
+		Err:   errmessage,
+		err:   err,
+	}
+}
+
+// newDeleteFruitResult create a new DeleteFruitResult.
+func newDeleteFruitResult(fruitID string, err error) DeleteFruitResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return DeleteFruitResult{
+		ID:  fruitID,
+		Err: errmessage,
+		err: err,
+	}
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (c CreateFruitResult) Failed() error {
+	return c.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests,
+// fruits of the batch that were not created don't make the request fail.
+func (c CreateBatchResult) Failed() error {
+	return c.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (e ExportFruitsResult) Failed() error {
+	return e.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (i ImportJobResult) Failed() error {
+	return i.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (i ImportReportResult) Failed() error {
+	return i.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (d DeadLettersResult) Failed() error {
+	return d.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (r RedriveDeadLetterResult) Failed() error {
+	return r.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests,
+// a result without fruit means that it was not found.
+func (g GetFruitWithIDResult) Failed() error {
+	if g.err == nil && g.Fruit == nil {
+		return ErrFruitNotFound
 	}
+
+	return g.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (u UpdateFruitResult) Failed() error {
+	return u.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (d DeleteFruitResult) Failed() error {
+	return d.err
+}
+
+// Failed implements endpoint.Failer so errors are reported as failed requests.
+func (s SearchFruitsDataResult) Failed() error {
+	return s.err
 }
 
 // newSearchFruitsResult create a new SearchFruitsResult.
@@ -257,6 +698,7 @@ func newSearchFruitsDataResult(result *SearchFruitsResult, err error) SearchFrui
 
 	return SearchFruitsDataResult{
 		SearchResult: result,
+		err:          err,
 		Err:          errmessage,
 	}
 }
@@ -272,6 +714,7 @@ func newCreateFruitResult(fruitID string, err error) CreateFruitResult {
 	return CreateFruitResult{
 		ID:  fruitID,
 		Err: errmessage,
+		err: err,
 	}
 }
 
@@ -284,6 +727,15 @@ func (u Fruit) String() string {
 	return string(b)
 }
 
+func (p FruitPatch) String() string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
 func (n NewFruit) String() string {
 	b, err := json.Marshal(n)
 	if err != nil {
@@ -293,13 +745,118 @@ func (n NewFruit) String() string {
 	return string(b)
 }
 
+// Validate checks that the filter can be applied, it returns an InvalidFilterError otherwise.
+func (s SearchFruitFilter) Validate() error {
+	if s.Start < 1 {
+		return InvalidFilterError{Filter: "start", Reason: "must be greater than zero"}
+	}
+
+	if s.Count < 1 {
+		return InvalidFilterError{Filter: "count", Reason: "must be greater than zero"}
+	}
+
+	if len(s.Query) > maxQueryLength {
+		return InvalidFilterError{Filter: "q", Reason: fmt.Sprintf("must not be longer than %d characters", maxQueryLength)}
+	}
+
+	if s.MinYear != nil && s.MaxYear != nil && *s.MinYear > *s.MaxYear {
+		return InvalidFilterError{Filter: "min_year", Reason: "must not be greater than max_year"}
+	}
+
+	if s.MinPrice != nil && *s.MinPrice < 0 {
+		return InvalidFilterError{Filter: "min_price", Reason: "must not be negative"}
+	}
+
+	if s.MaxPrice != nil && *s.MaxPrice < 0 {
+		return InvalidFilterError{Filter: "max_price", Reason: "must not be negative"}
+	}
+
+	if s.MinPrice != nil && s.MaxPrice != nil && *s.MinPrice > *s.MaxPrice {
+		return InvalidFilterError{Filter: "min_price", Reason: "must not be greater than max_price"}
+	}
+
+	err := validateSort(s.Sort)
+	if err != nil {
+		return err
+	}
+
+	return validateFacets(s.Facets)
+}
+
+// validateSort checks that results can be sorted by every field and that no field is repeated.
+func validateSort(fields []SortField) error {
+	seen := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		if !repository.IsSortable(field.Name) {
+			return InvalidFilterError{Filter: "sort", Reason: fmt.Sprintf("unknown field %q", field.Name)}
+		}
+
+		if seen[field.Name] {
+			return InvalidFilterError{Filter: "sort", Reason: fmt.Sprintf("field %q is repeated", field.Name)}
+		}
+
+		seen[field.Name] = true
+	}
+
+	return nil
+}
+
+// validateFacets checks that fruits can be counted by every field and that no field is repeated.
+func validateFacets(fields []string) error {
+	seen := make(map[string]bool, len(fields))
+
+	for _, field := range fields {
+		if !repository.IsFacet(field) {
+			return InvalidFilterError{Filter: "facets", Reason: fmt.Sprintf("unknown field %q", field)}
+		}
+
+		if seen[field] {
+			return InvalidFilterError{Filter: "facets", Reason: fmt.Sprintf("field %q is repeated", field)}
+		}
+
+		seen[field] = true
+	}
+
+	return nil
+}
+
 func (s SearchFruitFilter) toRepositoryFilters() repository.FruitFilter {
 	return repository.FruitFilter{
-		Start: s.Start,
-		Count: s.Count,
+		Start:          s.Start,
+		Count:          s.Count,
+		Country:        s.Country,
+		Province:       s.Province,
+		Region:         s.Region,
+		Variety:        s.Variety,
+		Classification: s.Classification,
+		Vault:          s.Vault,
+		MinYear:        s.MinYear,
+		MaxYear:        s.MaxYear,
+		MinPrice:       s.MinPrice,
+		MaxPrice:       s.MaxPrice,
+		Sort:           toRepositorySort(s.Sort),
+		Facets:         s.Facets,
 	}
 }
 
+func toRepositorySort(fields []SortField) []repository.SortField {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	sortFields := make([]repository.SortField, len(fields))
+
+	for index, field := range fields {
+		sortFields[index] = repository.SortField{
+			Field:      field.Name,
+			Descending: field.Descending,
+		}
+	}
+
+	return sortFields
+}
+
 func toSearchFruitsResult(repoResult repository.FindFruitsResult) SearchFruitsResult {
 	fruitCollection := make([]FruitItem, len(repoResult.Fruits))
 
@@ -314,7 +871,27 @@ func toSearchFruitsResult(repoResult repository.FindFruitsResult) SearchFruitsRe
 		Total:  repoResult.Total,
 		Start:  repoResult.Start,
 		Count:  repoResult.Count,
+		Facets: toFacets(repoResult.Facets),
+	}
+}
+
+func toFacets(repoFacets repository.Facets) map[string][]FacetBucket {
+	if repoFacets == nil {
+		return nil
+	}
+
+	facets := make(map[string][]FacetBucket, len(repoFacets))
+
+	for field, repoBuckets := range repoFacets {
+		buckets := make([]FacetBucket, len(repoBuckets))
+		for index, bucket := range repoBuckets {
+			buckets[index] = FacetBucket{Value: bucket.Value, Count: bucket.Count}
+		}
+
+		facets[field] = buckets
 	}
+
+	return facets
 }
 
 func toDatasetStatus(datasetStatus repository.FruitDatasetStatus) DatasetStatus {
@@ -330,3 +907,129 @@ func toDatasetStatus(datasetStatus repository.FruitDatasetStatus) DatasetStatus
 		Timestamp: time.Now().Unix(),
 	}
 }
+
+// newCreateBatchResult create a new CreateBatchResult.
+func newCreateBatchResult(items []BatchItemResult, err error) CreateBatchResult {
+	result := CreateBatchResult{
+		Items: items,
+		err:   err,
+	}
+
+	if err != nil {
+		result.Err = err.Error()
+	}
+
+	for _, item := range items {
+		if item.Err != nil {
+			result.Rejected++
+
+			continue
+		}
+
+		result.Created++
+	}
+
+	return result
+}
+
+// newExportFruitsResult create a new ExportFruitsResult.
+func newExportFruitsResult(fruits *FruitIterator, err error) ExportFruitsResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return ExportFruitsResult{
+		Fruits: fruits,
+		Err:    errmessage,
+		err:    err,
+	}
+}
+
+// newImportJobResult create a new ImportJobResult.
+func newImportJobResult(job DatasetStatus, err error) ImportJobResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return ImportJobResult{
+		Job: job,
+		Err: errmessage,
+		err: err,
+	}
+}
+
+// newImportReportResult create a new ImportReportResult.
+func newImportReportResult(rejections []DatasetRejection, err error) ImportReportResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return ImportReportResult{
+		Rejections: rejections,
+		Err:        errmessage,
+		err:        err,
+	}
+}
+
+// newDeadLettersResult create a new DeadLettersResult.
+func newDeadLettersResult(deadLetters []DeadLetter, err error) DeadLettersResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return DeadLettersResult{
+		DeadLetters: deadLetters,
+		Err:         errmessage,
+		err:         err,
+	}
+}
+
+// newRedriveDeadLetterResult create a new RedriveDeadLetterResult.
+func newRedriveDeadLetterResult(id string, err error) RedriveDeadLetterResult {
+	var errmessage string
+
+	if err != nil {
+		errmessage = err.Error()
+	}
+
+	return RedriveDeadLetterResult{
+		ID:  id,
+		Err: errmessage,
+		err: err,
+	}
+}
+
+// transformDeadLetters transforms the dead-lettered events of the publisher.
+func transformDeadLetters(deadLetters []repository.DeadLetter) []DeadLetter {
+	result := make([]DeadLetter, 0, len(deadLetters))
+
+	for _, deadLetter := range deadLetters {
+		result = append(result, DeadLetter{
+			ID:             deadLetter.ID,
+			SourceID:       deadLetter.Event.FruitID(),
+			Name:           fruitNameOf(deadLetter.Event),
+			Attempts:       deadLetter.Attempts,
+			LastError:      deadLetter.LastError,
+			DeadLetteredAt: deadLetter.DeadLetteredAt,
+		})
+	}
+
+	return result
+}
+
+// fruitNameOf returns the name of the fruit the event is about, if the event has it.
+func fruitNameOf(event repository.Event) string {
+	if event.FruitCreated != nil {
+		return event.FruitCreated.Name
+	}
+
+	return ""
+}
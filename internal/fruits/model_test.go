@@ -1,6 +1,7 @@
 package fruits_test
 
 import (
+	"errors"
 	"strconv"
 	"testing"
 
@@ -124,8 +125,8 @@ func TestNewValidation(t *testing.T) {
 				LocalName:      "Kerin OaKeefe",
 				WikiPage:       "@kerinokeefe",
 			},
-			want: fruits.MandatoryError{
-				Fields: []string{"name"},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "name", Reason: "is mandatory"}},
 			},
 		},
 		"invalid_classification": {
@@ -141,8 +142,8 @@ func TestNewValidation(t *testing.T) {
 				LocalName:   "Kerin OaKeefe",
 				WikiPage:    "@kerinokeefe",
 			},
-			want: fruits.MandatoryError{
-				Fields: []string{"classification"},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "classification", Reason: "is mandatory"}},
 			},
 		},
 		"invalid_country": {
@@ -158,8 +159,8 @@ func TestNewValidation(t *testing.T) {
 				LocalName:      "Kerin OaKeefe",
 				WikiPage:       "@kerinokeefe",
 			},
-			want: fruits.MandatoryError{
-				Fields: []string{"country"},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "country", Reason: "is mandatory"}},
 			},
 		},
 		"invalid_vault": {
@@ -175,22 +176,101 @@ func TestNewValidation(t *testing.T) {
 				LocalName:      "Kerin OaKeefe",
 				WikiPage:       "@kerinokeefe",
 			},
-			want: fruits.MandatoryError{
-				Fields: []string{"vault"},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "vault", Reason: "is mandatory"}},
 			},
 		},
 		"all_invalid": {
 			data: fruits.NewFruit{
 				Variety:     "White Blend",
 				Year:        87,
+				Price:       -2,
 				Province:    "Sicily & Sardinia",
 				Region:      "Etna",
 				Description: "brisk acidity",
 				LocalName:   "Kerin OaKeefe",
 				WikiPage:    "@kerinokeefe",
 			},
-			want: fruits.MandatoryError{
-				Fields: []string{"name", "classification", "country", "vault"},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{
+					{Field: "name", Reason: "is mandatory"},
+					{Field: "classification", Reason: "is mandatory"},
+					{Field: "country", Reason: "is mandatory"},
+					{Field: "vault", Reason: "is mandatory"},
+					{Field: "price", Reason: "must be a non-negative number"},
+				},
+			},
+		},
+		"blank_name": {
+			data: fruits.NewFruit{
+				Name:           "  ",
+				Vault:          "Nicosia",
+				Country:        "Italy",
+				Classification: "Vulka Bianco",
+			},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "name", Reason: "is mandatory"}},
+			},
+		},
+		"unknown_country": {
+			data: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Country:        "Atlantis",
+				Classification: "Vulka Bianco",
+			},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{{Field: "country", Reason: "must be an ISO 3166 country code or name"}},
+			},
+		},
+		"country_codes": {
+			data: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Country:        "us",
+				Classification: "Vulka Bianco",
+			},
+		},
+		"future_year": {
+			data: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Year:           3000,
+				Country:        "ITA",
+				Classification: "Vulka Bianco",
+				WikiPage:       "ftp://example.com/nicosia",
+			},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{
+					{Field: "year", Reason: "must be a two digit vintage or a year between 1800 and next year"},
+					{Field: "wiki_page", Reason: "must be an http or https URL or an @handle"},
+				},
+			},
+		},
+		"three_digit_year": {
+			data: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Year:           870,
+				Country:        "ITA",
+				Classification: "Vulka Bianco",
+				WikiPage:       "@kerin okeefe",
+			},
+			want: fruits.ValidationError{
+				Violations: []fruits.Violation{
+					{Field: "year", Reason: "must be a two digit vintage or a year between 1800 and next year"},
+					{Field: "wiki_page", Reason: "must be an http or https URL or an @handle"},
+				},
+			},
+		},
+		"four_digit_year_and_url": {
+			data: fruits.NewFruit{
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Year:           2013,
+				Country:        "ITA",
+				Classification: "Vulka Bianco",
+				WikiPage:       "https://twitter.com/kerinokeefe",
 			},
 		},
 	}
@@ -205,3 +285,39 @@ func TestNewValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestValidationErrorWrapsMandatoryError(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := fruits.MandatoryError{
+		Fields: []string{"name", "vault"},
+	}
+	givenFruit := fruits.NewFruit{
+		Country:        "Atlantis",
+		Classification: "Vulka Bianco",
+	}
+
+	err := givenFruit.Validate()
+
+	var got fruits.MandatoryError
+
+	assert.True(t, errors.As(err, &got))
+	assert.Equal(t, expectedErr, got)
+	assert.Equal(t, "these fields are mandatory: name, vault.", got.Error())
+}
+
+func TestValidationErrorWithoutMandatoryFields(t *testing.T) {
+	t.Parallel()
+
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Atlantis",
+		Classification: "Vulka Bianco",
+	}
+
+	err := givenFruit.Validate()
+
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, new(fruits.MandatoryError)))
+}
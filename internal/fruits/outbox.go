@@ -0,0 +1,146 @@
+package fruits
+
+import (
+	"context"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+// Outbox defines portout behavior to read the events the repository stores
+// in the same write as the fruits they announce.
+type Outbox interface {
+	// PendingEvents returns up to limit events that are due at the given moment.
+	PendingEvents(ctx context.Context, now time.Time, limit int) ([]repository.OutboxEvent, error)
+	// AcknowledgeEvent removes a published event.
+	AcknowledgeEvent(ctx context.Context, eventID string) error
+	// RetryEvent stores the attempts of an event that could not be published.
+	RetryEvent(ctx context.Context, event repository.OutboxEvent) error
+}
+
+// outboxBatchSize is the number of events read from the outbox at once.
+const outboxBatchSize = 100
+
+// WithOutbox makes the events of new fruits be published by RelayEvents from
+// the outbox they are stored in, instead of right after the fruit is stored.
+// An event that cannot be published is tried again after retryDelay, the
+// delay doubles on every failure up to maxRetryDelay.
+func WithOutbox(outbox Outbox, retryDelay, maxRetryDelay time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.outbox = outbox
+		s.outboxRetryDelay = retryDelay
+		s.outboxMaxRetryDelay = maxRetryDelay
+	}
+}
+
+// RelayEvents publishes the events of the outbox that are due and returns how
+// many were published. Published events are acknowledged, the others are kept
+// to be tried again, so every event is published at least once.
+func (s *Service) RelayEvents(ctx context.Context) (int, error) {
+	if s.outbox == nil {
+		return 0, nil
+	}
+
+	published := 0
+
+	for {
+		pending, err := s.outbox.PendingEvents(ctx, time.Now(), outboxBatchSize)
+		if err != nil {
+			s.logger.Error(
+				"unable to read outbox events",
+				loggers.Fields{
+					"method": "Service.RelayEvents",
+					"error":  err,
+				},
+			)
+
+			return published, ErrDataAccess
+		}
+
+		for _, event := range pending {
+			ok, err := s.relayEvent(ctx, event)
+			if ok {
+				published++
+			}
+
+			if err != nil {
+				return published, err
+			}
+		}
+
+		if len(pending) < outboxBatchSize || ctx.Err() != nil {
+			return published, nil
+		}
+	}
+}
+
+// relayEvent publishes the event and acknowledges it, if it cannot be published
+// it is scheduled to be tried again. It returns if the event was published.
+func (s *Service) relayEvent(ctx context.Context, event repository.OutboxEvent) (bool, error) {
+	err := s.fruitPublisher.Publish(ctx, event.Event)
+	if err != nil {
+		event.Attempts++
+		event.LastError = err.Error()
+		event.NextAttemptAt = time.Now().Add(s.outboxRetryDelayAfter(event.Attempts))
+
+		s.logger.Error(
+			"unable to publish outbox event",
+			loggers.Fields{
+				"method":        "Service.relayEvent",
+				"event":         event.ID(),
+				"attempts":      event.Attempts,
+				"nextAttemptAt": event.NextAttemptAt,
+				"error":         err,
+			},
+		)
+
+		err = s.outbox.RetryEvent(ctx, event)
+		if err != nil {
+			s.logger.Error(
+				"unable to keep the attempts of outbox event",
+				loggers.Fields{
+					"method": "Service.relayEvent",
+					"event":  event.ID(),
+					"error":  err,
+				},
+			)
+
+			return false, ErrDataAccess
+		}
+
+		return false, nil
+	}
+
+	err = s.outbox.AcknowledgeEvent(ctx, event.ID())
+	if err != nil {
+		s.logger.Error(
+			"unable to acknowledge outbox event, it will be published again",
+			loggers.Fields{
+				"method": "Service.relayEvent",
+				"event":  event.ID(),
+				"error":  err,
+			},
+		)
+
+		return true, ErrDataAccess
+	}
+
+	return true, nil
+}
+
+// outboxRetryDelayAfter returns the delay before the next attempt to publish
+// an event that failed the given number of times.
+func (s *Service) outboxRetryDelayAfter(attempts int) time.Duration {
+	delay := s.outboxRetryDelay
+
+	for attempt := 1; attempt < attempts && delay < s.outboxMaxRetryDelay; attempt++ {
+		delay *= 2
+	}
+
+	if delay > s.outboxMaxRetryDelay {
+		return s.outboxMaxRetryDelay
+	}
+
+	return delay
+}
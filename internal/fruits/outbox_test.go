@@ -0,0 +1,292 @@
+package fruits_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayEventsPublishesAndAcknowledges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	outbox := newOutboxMock(
+		newOutboxEvent("1", "a", "Mango", now),
+		newOutboxEvent("2", "b", "Pear", now),
+		newOutboxEvent("3", "c", "Apple", now.Add(time.Hour)),
+	)
+	publisher := newFlakyPublisherMock(0)
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, publisher, logger, fruits.WithOutbox(outbox, time.Second, time.Minute))
+
+	published, err := fruitService.RelayEvents(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []string{"Mango", "Pear"}, publisher.names())
+	assert.Equal(t, []string{"3"}, outbox.ids())
+}
+
+func TestRelayEventsRetriesUntilPublished(t *testing.T) {
+	t.Parallel()
+
+	outbox := newOutboxMock(newOutboxEvent("1", "a", "Mango", time.Now()))
+	publisher := newFlakyPublisherMock(2)
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, publisher, logger, fruits.WithOutbox(outbox, time.Millisecond, time.Millisecond))
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		published, err := fruitService.RelayEvents(context.TODO())
+		assert.NoError(t, err)
+		assert.Zero(t, published)
+		assert.Equal(t, attempt, outbox.events["1"].Attempts)
+		assert.Equal(t, "topic not available", outbox.events["1"].LastError)
+
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	published, err := fruitService.RelayEvents(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"Mango"}, publisher.names())
+	assert.Empty(t, outbox.ids())
+}
+
+func TestRelayEventsBacksOff(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		attempts      int
+		expectedDelay time.Duration
+	}{
+		"first_failure": {
+			attempts:      0,
+			expectedDelay: time.Second,
+		},
+		"third_failure": {
+			attempts:      2,
+			expectedDelay: 4 * time.Second,
+		},
+		"capped": {
+			attempts:      30,
+			expectedDelay: 10 * time.Second,
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			outbox := newOutboxMock(repository.OutboxEvent{Event: repository.Event{ID: "1"}, Attempts: test.attempts, NextAttemptAt: time.Now()})
+			logger := loggers.NewLoggerWithStdout("", loggers.Error)
+			fruitService := fruits.NewService(&fruitRepoMock{}, newFlakyPublisherMock(1), logger, fruits.WithOutbox(outbox, time.Second, 10*time.Second))
+
+			_, err := fruitService.RelayEvents(context.TODO())
+
+			assert.NoError(st, err)
+			assert.Equal(st, test.attempts+1, outbox.events["1"].Attempts)
+			assert.WithinDuration(st, time.Now().Add(test.expectedDelay), outbox.events["1"].NextAttemptAt, 500*time.Millisecond)
+		})
+	}
+}
+
+func TestRelayEventsFailsIfOutboxCannotBeRead(t *testing.T) {
+	t.Parallel()
+
+	outbox := newOutboxMock()
+	outbox.err = errors.New("table not found")
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, newFlakyPublisherMock(0), logger, fruits.WithOutbox(outbox, time.Second, time.Minute))
+
+	published, err := fruitService.RelayEvents(context.TODO())
+
+	assert.Equal(t, fruits.ErrDataAccess, err)
+	assert.Zero(t, published)
+}
+
+func TestRelayEventsWithoutOutbox(t *testing.T) {
+	t.Parallel()
+
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepoMock{}, newFlakyPublisherMock(0), logger)
+
+	published, err := fruitService.RelayEvents(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+}
+
+func TestUpdateAndDeleteArePublishedWithoutOutbox(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {ID: repository.FruitID(fruitID), Name: "Mango", Variety: "Tommy Atkins", Vault: "Brazil", Country: "Brazil", Classification: "Fresh", Version: 1},
+		},
+	}
+	publisher := newFlakyPublisherMock(0)
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, publisher, logger)
+	ctx := context.TODO()
+
+	_, err := fruitService.Update(ctx, fruitID, 1, fruits.NewFruit{Name: "Mango", Variety: "Kent", Vault: "Brazil", Country: "Brazil", Classification: "Fresh"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(publisher.events()) == 1 }, time.Second, 10*time.Millisecond)
+
+	err = fruitService.Delete(ctx, fruitID, 2)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(publisher.events()) == 2 }, time.Second, 10*time.Millisecond)
+
+	events := publisher.events()
+	assert.Equal(t, &repository.FruitUpdatedEvent{
+		SourceID:      fruitID,
+		Version:       2,
+		ChangedFields: []string{"variety"},
+		Before:        map[string]json.RawMessage{"variety": json.RawMessage(`"Tommy Atkins"`)},
+		After:         map[string]json.RawMessage{"variety": json.RawMessage(`"Kent"`)},
+	}, events[0].FruitUpdated)
+	assert.Equal(t, &repository.FruitDeletedEvent{SourceID: fruitID, Version: 3}, events[1].FruitDeleted)
+}
+
+// newOutboxEvent returns the outbox event of a new fruit.
+func newOutboxEvent(id, sourceID, name string, nextAttemptAt time.Time) repository.OutboxEvent {
+	return repository.OutboxEvent{
+		Event: repository.Event{
+			ID:           id,
+			Type:         repository.EventFruitCreated,
+			FruitCreated: &repository.NewFruitEvent{SourceID: sourceID, Name: name},
+		},
+		NextAttemptAt: nextAttemptAt,
+	}
+}
+
+type outboxMock struct {
+	mu     sync.Mutex
+	err    error
+	events map[string]repository.OutboxEvent
+	order  []string
+}
+
+func newOutboxMock(events ...repository.OutboxEvent) *outboxMock {
+	newOutbox := outboxMock{
+		events: make(map[string]repository.OutboxEvent),
+	}
+
+	for _, event := range events {
+		newOutbox.events[event.ID()] = event
+		newOutbox.order = append(newOutbox.order, event.ID())
+	}
+
+	return &newOutbox
+}
+
+func (o *outboxMock) PendingEvents(_ context.Context, now time.Time, limit int) ([]repository.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	pending := make([]repository.OutboxEvent, 0)
+
+	for _, id := range o.order {
+		event, ok := o.events[id]
+		if ok && event.Due(now) && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+
+	return pending, nil
+}
+
+func (o *outboxMock) AcknowledgeEvent(_ context.Context, eventID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.events, eventID)
+
+	return nil
+}
+
+func (o *outboxMock) RetryEvent(_ context.Context, event repository.OutboxEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events[event.ID()] = event
+
+	return nil
+}
+
+func (o *outboxMock) ids() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ids := make([]string, 0, len(o.events))
+
+	for _, id := range o.order {
+		if _, ok := o.events[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// flakyPublisherMock fails the given number of times before it publishes.
+type flakyPublisherMock struct {
+	mu        sync.Mutex
+	failures  int
+	published []repository.Event
+}
+
+func newFlakyPublisherMock(failures int) *flakyPublisherMock {
+	return &flakyPublisherMock{failures: failures}
+}
+
+func (f *flakyPublisherMock) Publish(_ context.Context, event repository.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+
+		return errors.New("topic not available")
+	}
+
+	f.published = append(f.published, event)
+
+	return nil
+}
+
+func (f *flakyPublisherMock) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.published))
+
+	for _, event := range f.published {
+		names = append(names, event.FruitCreated.Name)
+	}
+
+	return names
+}
+
+func (f *flakyPublisherMock) events() []repository.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]repository.Event{}, f.published...)
+}
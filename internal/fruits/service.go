@@ -3,6 +3,7 @@ package fruits
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
@@ -13,13 +14,35 @@ import (
 type Repository interface {
 	FindByID(ctx context.Context, fruitID repository.FruitID) (*repository.Fruit, error)
 	Save(ctx context.Context, fruit repository.NewFruit) (repository.FruitID, error)
+	// SaveAll stores every new fruit, the results are in the same order as the fruits.
+	SaveAll(ctx context.Context, fruits []repository.NewFruit) []repository.SaveResult
+	// Update replaces a fruit if its stored version is fruit.Version, the stored fruit gets the next version.
+	Update(ctx context.Context, fruit repository.Fruit) error
+	// Delete deletes a fruit if its stored version is the given one.
+	Delete(ctx context.Context, fruitID repository.FruitID, version int64) error
 	SearchWithFilters(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error)
+	// ListFruits returns up to count fruits after the cursor, or from the first
+	// one if it is nil, without counting the rest. A page may be empty and still
+	// have a Next cursor.
+	ListFruits(ctx context.Context, after *repository.Cursor, count int) (repository.FruitPage, error)
 	DatasetStatus(ctx context.Context) (repository.FruitDatasetStatus, error)
 }
 
+// TextIndex defines portout behavior to search fruits by the words of their texts.
+type TextIndex interface {
+	// Index adds or replaces a fruit in the index.
+	Index(fruit repository.Fruit)
+	Remove(fruitID repository.FruitID)
+	// Search returns the fruits that match the query, the most relevant first.
+	Search(query string) []repository.TextMatch
+	// Rebuild replaces the indexed fruits with the ones load indexes once
+	// load succeeds, fruits changed or removed meanwhile stay so.
+	Rebuild(load func(index func(fruit repository.Fruit)) error) error
+}
+
 // Publisher defines portout behavior to publish new fruits.
 type Publisher interface {
-	Publish(ctx context.Context, event repository.NewFruitEvent) error
+	Publish(ctx context.Context, event repository.Event) error
 }
 
 // Service implements fruit management logic.
@@ -27,16 +50,63 @@ type Service struct {
 	fruitRepository Repository
 	fruitPublisher  Publisher
 	logger          *loggers.Logger
+	// importJobs are the dataset loads by job id, importJobIDs has their ids from the oldest.
+	importJobs   map[string]*importJob
+	importJobIDs []string
+	// datasetJobID is the job of the dataset loaded at startup, empty if none was loaded.
+	datasetJobID string
+	importMu     sync.RWMutex
+	cursors      cursorCodec
+	// textIndex supports full-text searches, nil if they are not available.
+	textIndex TextIndex
+	// idempotencyStore remembers the fruits created with an idempotency key,
+	// nil if creation is not idempotent.
+	idempotencyStore  IdempotencyStore
+	idempotencyWindow time.Duration
+	// outbox keeps the events of new fruits until RelayEvents publishes
+	// them, nil if they are published right after the fruit is stored.
+	outbox              Outbox
+	outboxRetryDelay    time.Duration
+	outboxMaxRetryDelay time.Duration
+	// deadLetters are the events the publisher could not publish, nil if it doesn't keep them.
+	deadLetters DeadLetters
 }
 
-var ErrDataAccess = errors.New("something went wrong accessing db")
+// ServiceOption sets optional service settings.
+type ServiceOption func(*Service)
+
+var (
+	ErrDataAccess      = errors.New("something went wrong accessing db")
+	ErrFruitNotFound   = errors.New("record not found")
+	ErrVersionConflict = errors.New("fruit was modified by someone else")
+)
+
+// AnyVersion allows to change a fruit whatever its current version is, no
+// fruit has it.
+const AnyVersion int64 = -1
 
 // NewService creates a new application service.
-func NewService(fruitRepository Repository, publisher Publisher, logger *loggers.Logger) *Service {
-	return &Service{
+func NewService(fruitRepository Repository, publisher Publisher, logger *loggers.Logger, options ...ServiceOption) *Service {
+	newService := Service{
 		fruitRepository: fruitRepository,
 		fruitPublisher:  publisher,
 		logger:          logger,
+		importJobs:      make(map[string]*importJob),
+		cursors:         cursorCodec{secret: newCursorSecret()},
+	}
+
+	for _, option := range options {
+		option(&newService)
+	}
+
+	return &newService
+}
+
+// WithCursorSecret sets the secret search cursors are signed with. Without
+// it a random secret is used and cursors don't survive a restart.
+func WithCursorSecret(secret []byte) ServiceOption {
+	return func(s *Service) {
+		s.cursors = cursorCodec{secret: secret}
 	}
 }
 
@@ -77,7 +147,9 @@ func (s *Service) GetFruitWithID(ctx context.Context, fruitID string) (*Fruit, e
 	return fruit, nil
 }
 
-// Create creates a fruit.
+// Create creates a fruit. If the context has an idempotency key and the
+// service has an idempotency store, a fruit already created with the same
+// key is returned instead of creating another one.
 func (s *Service) Create(ctx context.Context, newfruit NewFruit) (string, error) {
 	s.logger.Debug(
 		"creating fruit",
@@ -87,12 +159,26 @@ func (s *Service) Create(ctx context.Context, newfruit NewFruit) (string, error)
 		},
 	)
 
+	err := newfruit.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	key := IdempotencyKeyFromContext(ctx)
+	if key != "" && s.idempotencyStore != nil {
+		return s.createOnce(ctx, key, newfruit)
+	}
+
+	return s.create(ctx, newfruit)
+}
+
+func (s *Service) create(ctx context.Context, newfruit NewFruit) (string, error) {
 	fruitid, err := s.fruitRepository.Save(ctx, newfruit.ToFruitPortOut())
 	if err != nil {
 		s.logger.Error(
 			"something goes wrong creating a new fruit",
 			loggers.Fields{
-				"method": "Service.Create",
+				"method": "Service.create",
 				"fruit":  newfruit,
 			},
 		)
@@ -103,38 +189,275 @@ func (s *Service) Create(ctx context.Context, newfruit NewFruit) (string, error)
 	s.logger.Info(
 		"fruit was created successfully",
 		loggers.Fields{
-			"method": "Service.Create",
+			"method": "Service.create",
 			"fruit":  newfruit,
 		},
 	)
 
-	s.notifyNewFruit(repository.FruitIDValue(fruitid), newfruit)
+	newFruit := newfruit.ToFruitPortOut().ToFruit(fruitid)
+	s.indexFruit(newFruit)
+	s.notify(repository.NewFruitCreated(newFruit, time.Now().UTC()))
 
 	return repository.FruitIDValue(fruitid), nil
 }
 
-func (s *Service) notifyNewFruit(id string, newfruit NewFruit) {
-	go func() {
-		event := repository.NewFruitEvent{
-			SourceID: id,
-			Name:     newfruit.Name,
-			Variety:  newfruit.Variety,
-			Price:    newfruit.Price,
-		}
+// notify publishes the event of a fruit change in the background if there is
+// no outbox, otherwise the repository already recorded it with the change.
+func (s *Service) notify(event repository.Event) {
+	if s.outbox != nil {
+		return
+	}
 
+	go func() {
 		err := s.fruitPublisher.Publish(context.Background(), event)
 		if err != nil {
 			s.logger.Error(
-				"unable to publish new fruit",
+				"unable to publish fruit event",
 				loggers.Fields{
-					"method": "Service.notifyNewFruit",
+					"method": "Service.notify",
 					"event":  event,
+					"error":  err,
 				},
 			)
 		}
 	}()
 }
 
+// fruitToAnnounce returns the stored fruit whose change is announced by the
+// service, it is nil if there is an outbox because the repository does it.
+func (s *Service) fruitToAnnounce(ctx context.Context, fruitID string) *repository.Fruit {
+	if s.outbox != nil {
+		return nil
+	}
+
+	storedFruit, err := s.fruitRepository.FindByID(ctx, repository.FruitID(fruitID))
+	if err != nil {
+		s.logger.Error(
+			"unable to read the fruit to announce its change",
+			loggers.Fields{
+				"method":  "Service.fruitToAnnounce",
+				"fruitID": fruitID,
+				"error":   err,
+			},
+		)
+
+		return nil
+	}
+
+	return storedFruit
+}
+
+// notifyFruitUpdated publishes the update of the previous fruit if it has to be announced.
+func (s *Service) notifyFruitUpdated(previous *repository.Fruit, fruit repository.Fruit) {
+	if previous == nil {
+		return
+	}
+
+	event, err := repository.NewFruitUpdated(*previous, fruit, fruit.LastModified)
+	if err != nil {
+		s.logger.Error(
+			"unable to build fruit updated event",
+			loggers.Fields{
+				"method":  "Service.notifyFruitUpdated",
+				"fruitID": fruit.ID,
+				"error":   err,
+			},
+		)
+
+		return
+	}
+
+	s.notify(event)
+}
+
+// notifyFruitDeleted publishes the deletion of the previous fruit if it has to be announced.
+func (s *Service) notifyFruitDeleted(previous *repository.Fruit) {
+	if previous == nil {
+		return
+	}
+
+	s.notify(repository.NewFruitDeleted(*previous, time.Now().UTC()))
+}
+
+// Update replaces the data of the fruit with the given id if its current
+// version is the given one, use AnyVersion to skip the version check.
+func (s *Service) Update(ctx context.Context, fruitID string, version int64, fruit NewFruit) (*Fruit, error) {
+	s.logger.Debug(
+		"updating fruit",
+		loggers.Fields{
+			"method":  "Service.Update",
+			"fruitID": fruitID,
+			"version": version,
+			"fruit":   fruit,
+		},
+	)
+
+	err := fruit.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if version == AnyVersion {
+		currentFruit, err := s.getExistingFruit(ctx, fruitID)
+		if err != nil {
+			return nil, err
+		}
+
+		version = currentFruit.Version
+	}
+
+	return s.update(ctx, fruitID, version, fruit)
+}
+
+// Patch changes only the given fields of the fruit with the given id if its
+// current version is the given one, use AnyVersion to skip the version check.
+func (s *Service) Patch(ctx context.Context, fruitID string, version int64, patch FruitPatch) (*Fruit, error) {
+	s.logger.Debug(
+		"patching fruit",
+		loggers.Fields{
+			"method":  "Service.Patch",
+			"fruitID": fruitID,
+			"version": version,
+			"patch":   patch,
+		},
+	)
+
+	currentFruit, err := s.getExistingFruit(ctx, fruitID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != AnyVersion && version != currentFruit.Version {
+		return nil, ErrVersionConflict
+	}
+
+	fruit := patch.apply(*currentFruit)
+
+	err = fruit.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.update(ctx, fruitID, currentFruit.Version, fruit)
+}
+
+// getExistingFruit get the fruit with the given id or ErrFruitNotFound if it doesn't exist.
+func (s *Service) getExistingFruit(ctx context.Context, fruitID string) (*Fruit, error) {
+	currentFruit, err := s.GetFruitWithID(ctx, fruitID)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentFruit == nil {
+		return nil, ErrFruitNotFound
+	}
+
+	return currentFruit, nil
+}
+
+func (s *Service) update(ctx context.Context, fruitID string, version int64, fruit NewFruit) (*Fruit, error) {
+	fruitToStore := fruit.ToFruitPortOut().ToFruit(repository.FruitID(fruitID))
+	fruitToStore.Version = version
+	fruitToStore.LastModified = time.Now().UTC()
+	previous := s.fruitToAnnounce(ctx, fruitID)
+
+	err := s.fruitRepository.Update(ctx, fruitToStore)
+	if errors.Is(err, repository.ErrFruitNotFound) {
+		return nil, ErrFruitNotFound
+	}
+
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, ErrVersionConflict
+	}
+
+	if err != nil {
+		s.logger.Error(
+			"something goes wrong updating a fruit",
+			loggers.Fields{
+				"method":  "Service.update",
+				"fruitID": fruitID,
+				"error":   err,
+			},
+		)
+
+		return nil, ErrDataAccess
+	}
+
+	fruitToStore.Version++
+	s.indexFruit(fruitToStore)
+	s.notifyFruitUpdated(previous, fruitToStore)
+
+	s.logger.Info(
+		"fruit was updated successfully",
+		loggers.Fields{
+			"method":  "Service.update",
+			"fruitID": fruitID,
+			"version": fruitToStore.Version,
+		},
+	)
+
+	return transformFruitPortOuttoFruit(&fruitToStore), nil
+}
+
+// Delete deletes the fruit with the given id if its current version is the
+// given one, use AnyVersion to skip the version check.
+func (s *Service) Delete(ctx context.Context, fruitID string, version int64) error {
+	s.logger.Debug(
+		"deleting fruit",
+		loggers.Fields{
+			"method":  "Service.Delete",
+			"fruitID": fruitID,
+			"version": version,
+		},
+	)
+
+	if version == AnyVersion {
+		currentFruit, err := s.getExistingFruit(ctx, fruitID)
+		if err != nil {
+			return err
+		}
+
+		version = currentFruit.Version
+	}
+
+	previous := s.fruitToAnnounce(ctx, fruitID)
+
+	err := s.fruitRepository.Delete(ctx, repository.FruitID(fruitID), version)
+	if errors.Is(err, repository.ErrFruitNotFound) {
+		return ErrFruitNotFound
+	}
+
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrVersionConflict
+	}
+
+	if err != nil {
+		s.logger.Error(
+			"something goes wrong deleting a fruit",
+			loggers.Fields{
+				"method":  "Service.Delete",
+				"fruitID": fruitID,
+				"error":   err,
+			},
+		)
+
+		return ErrDataAccess
+	}
+
+	s.removeFromIndex(fruitID)
+	s.notifyFruitDeleted(previous)
+
+	s.logger.Info(
+		"fruit was deleted successfully",
+		loggers.Fields{
+			"method":  "Service.Delete",
+			"fruitID": fruitID,
+		},
+	)
+
+	return nil
+}
+
 // SearchFruits search fruits who match the given filters.
 func (s *Service) SearchFruits(ctx context.Context, givenFilter SearchFruitFilter) (*SearchFruitsResult, error) {
 	s.logger.Debug(
@@ -145,9 +468,29 @@ func (s *Service) SearchFruits(ctx context.Context, givenFilter SearchFruitFilte
 		},
 	)
 
+	err := givenFilter.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if givenFilter.Query != "" {
+		return s.searchText(ctx, givenFilter)
+	}
+
 	filters := givenFilter.toRepositoryFilters()
 
+	if givenFilter.Cursor != "" {
+		filters.After, err = s.cursors.decode(givenFilter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	repoResult, err := s.fruitRepository.SearchWithFilters(ctx, filters)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+
 	if err != nil {
 		s.logger.Error(
 			"something goes wrong searching fruits",
@@ -162,10 +505,15 @@ func (s *Service) SearchFruits(ctx context.Context, givenFilter SearchFruitFilte
 
 	result := toSearchFruitsResult(repoResult)
 
+	if repoResult.Next != nil {
+		result.NextCursor = s.cursors.encode(givenFilter, repoResult.Next)
+	}
+
 	return &result, nil
 }
 
-// DatasetStatus check the status of the fruit dataset.
+// DatasetStatus check the status of the fruit dataset. If a dataset was loaded
+// it reports the outcome of that load, otherwise the repository availability.
 func (s *Service) DatasetStatus(ctx context.Context) DatasetStatus {
 	s.logger.Debug(
 		"checking dataset status",
@@ -174,6 +522,10 @@ func (s *Service) DatasetStatus(ctx context.Context) DatasetStatus {
 		},
 	)
 
+	if s.datasetWasLoaded() {
+		return s.currentDatasetStatus()
+	}
+
 	currentState, err := s.fruitRepository.DatasetStatus(ctx)
 	if err != nil {
 		s.logger.Error(
@@ -3,7 +3,9 @@ package fruits_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
 	"github.com/fernandoocampo/fruits/internal/adapter/repository"
@@ -151,6 +153,172 @@ func TestSearchFruitsSuccessfully(t *testing.T) {
 	assert.Equal(t, &expectedResult, fruitsFound)
 }
 
+func TestSearchFruitsWithFilters(t *testing.T) {
+	t.Parallel()
+
+	maxPrice := float32(20)
+	givenFilter := fruits.SearchFruitFilter{
+		Start:    1,
+		Count:    10,
+		Country:  "Italy",
+		Variety:  "White Blend",
+		MaxPrice: &maxPrice,
+		Sort:     []fruits.SortField{{Name: "price", Descending: true}, {Name: "name"}},
+		Facets:   []string{"country", "variety"},
+	}
+	expectedFilter := repository.FruitFilter{
+		Start:    1,
+		Count:    10,
+		Country:  "Italy",
+		Variety:  "White Blend",
+		MaxPrice: &maxPrice,
+		Sort:     []repository.SortField{{Field: "price", Descending: true}, {Field: "name"}},
+		Facets:   []string{"country", "variety"},
+	}
+	expectedFacets := map[string][]fruits.FacetBucket{
+		"country": {{Value: "Italy", Count: 3}},
+		"variety": {{Value: "White Blend", Count: 2}, {Value: "Red Blend", Count: 1}},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+		searchResult: repository.FindFruitsResult{
+			Total: 3,
+			Facets: repository.Facets{
+				"country": {{Value: "Italy", Count: 3}},
+				"variety": {{Value: "White Blend", Count: 2}, {Value: "Red Blend", Count: 1}},
+			},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.SearchFruits(context.TODO(), givenFilter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedFilter, fruitRepository.searchFilter)
+	assert.Equal(t, expectedFacets, got.Facets)
+}
+
+func TestSearchFruitsWithInvalidFilters(t *testing.T) {
+	t.Parallel()
+
+	minYear, maxYear := 2015, 2010
+	negativePrice := float32(-1)
+	cases := map[string]struct {
+		filter fruits.SearchFruitFilter
+		want   fruits.InvalidFilterError
+	}{
+		"zero_start": {
+			filter: fruits.SearchFruitFilter{Start: 0, Count: 10},
+			want:   fruits.InvalidFilterError{Filter: "start", Reason: "must be greater than zero"},
+		},
+		"zero_count": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 0},
+			want:   fruits.InvalidFilterError{Filter: "count", Reason: "must be greater than zero"},
+		},
+		"inverted_years": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, MinYear: &minYear, MaxYear: &maxYear},
+			want:   fruits.InvalidFilterError{Filter: "min_year", Reason: "must not be greater than max_year"},
+		},
+		"negative_price": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, MaxPrice: &negativePrice},
+			want:   fruits.InvalidFilterError{Filter: "max_price", Reason: "must not be negative"},
+		},
+		"unknown_sort_field": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, Sort: []fruits.SortField{{Name: "colour"}}},
+			want:   fruits.InvalidFilterError{Filter: "sort", Reason: `unknown field "colour"`},
+		},
+		"repeated_sort_field": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, Sort: []fruits.SortField{{Name: "price"}, {Name: "price", Descending: true}}},
+			want:   fruits.InvalidFilterError{Filter: "sort", Reason: `field "price" is repeated`},
+		},
+		"unknown_facet": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, Facets: []string{"country", "name"}},
+			want:   fruits.InvalidFilterError{Filter: "facets", Reason: `unknown field "name"`},
+		},
+		"repeated_facet": {
+			filter: fruits.SearchFruitFilter{Start: 1, Count: 10, Facets: []string{"country", "country"}},
+			want:   fruits.InvalidFilterError{Filter: "facets", Reason: `field "country" is repeated`},
+		},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			st.Parallel()
+
+			fruitRepository := fruitRepoMock{
+				repo: make(map[string]repository.Fruit),
+			}
+			logger := loggers.NewLoggerWithStdout("", loggers.Error)
+			fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+			got, err := fruitService.SearchFruits(context.TODO(), test.filter)
+
+			assert.Nil(st, got)
+			assert.Equal(st, test.want, err)
+		})
+	}
+}
+
+func TestSearchFruitsWithCursor(t *testing.T) {
+	t.Parallel()
+
+	givenFilter := fruits.SearchFruitFilter{Start: 1, Count: 2, Country: "Italy"}
+	nextPosition := repository.Cursor{Key: "1240"}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+		searchResult: repository.FindFruitsResult{
+			Fruits: []repository.Fruit{{ID: "1234"}, {ID: "1240"}},
+			Total:  3,
+			Start:  1,
+			Count:  2,
+			Next:   &nextPosition,
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithCursorSecret([]byte("secret")))
+	ctx := context.TODO()
+
+	firstPage, err := fruitService.SearchFruits(ctx, givenFilter)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, firstPage.NextCursor)
+	assert.NotContains(t, firstPage.NextCursor, "1240")
+
+	givenFilter.Cursor = firstPage.NextCursor
+	givenFilter.Count = 5
+
+	_, err = fruitService.SearchFruits(ctx, givenFilter)
+	assert.NoError(t, err)
+	assert.Equal(t, &nextPosition, fruitRepository.searchFilter.After)
+
+	otherService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithCursorSecret([]byte("other")))
+	otherSearch := givenFilter
+	otherSearch.Country = "Portugal"
+	tamperedSearch := givenFilter
+	tamperedSearch.Cursor = "eyJwIjp7ImtleSI6IjEifX0" + givenFilter.Cursor[strings.Index(givenFilter.Cursor, "."):]
+
+	cases := map[string]struct {
+		service *fruits.Service
+		filter  fruits.SearchFruitFilter
+	}{
+		"other_secret":  {service: otherService, filter: givenFilter},
+		"other_filters": {service: fruitService, filter: otherSearch},
+		"tampered":      {service: fruitService, filter: tamperedSearch},
+		"garbage":       {service: fruitService, filter: fruits.SearchFruitFilter{Start: 1, Count: 2, Cursor: "garbage"}},
+	}
+
+	for name, test := range cases {
+		name, test := name, test
+		t.Run(name, func(st *testing.T) {
+			got, err := test.service.SearchFruits(ctx, test.filter)
+
+			assert.Nil(st, got)
+			assert.Equal(st, fruits.ErrInvalidCursor, err)
+		})
+	}
+}
+
 func TestDatasetOk(t *testing.T) {
 	t.Parallel()
 
@@ -199,9 +367,256 @@ func TestDatasetWithError(t *testing.T) {
 	assert.Greater(t, got.Timestamp, int64(0))
 }
 
+func TestUpdateFruitSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	expectedFruit := givenFruit.NewFruit(fruitID)
+	expectedFruit.Version = 4
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {ID: repository.FruitID(fruitID), Name: "Nicosia 2013 Vulka Bianco  (Etna)", Version: 3},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	updatedAfter := time.Now()
+
+	got, err := fruitService.Update(ctx, fruitID, 3, givenFruit)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedFruit.LastModified = got.LastModified
+
+	assert.Equal(t, &expectedFruit, got)
+	assert.False(t, got.LastModified.Before(updatedAfter))
+	assert.Equal(t, got.LastModified, fruitRepository.repo[fruitID].LastModified)
+	assert.Equal(t, givenFruit.Name, fruitRepository.repo[fruitID].Name)
+	assert.Equal(t, int64(4), fruitRepository.repo[fruitID].Version)
+}
+
+func TestUpdateFruitWithStaleVersion(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	existingFruit := repository.Fruit{ID: repository.FruitID(fruitID), Name: "Nicosia", Version: 3}
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: existingFruit,
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Update(context.TODO(), fruitID, 2, givenFruit)
+
+	assert.Nil(t, got)
+	assert.Equal(t, fruits.ErrVersionConflict, err)
+	assert.Equal(t, existingFruit, fruitRepository.repo[fruitID])
+}
+
+func TestUpdateFruitNotFound(t *testing.T) {
+	t.Parallel()
+
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2014 Vulka Bianco  (Etna)",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Update(context.TODO(), "1234", fruits.AnyVersion, givenFruit)
+
+	assert.Nil(t, got)
+	assert.Equal(t, fruits.ErrFruitNotFound, err)
+}
+
+func TestUpdateInvalidFruit(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	expectedErr := fruits.ValidationError{
+		Violations: []fruits.Violation{
+			{Field: "name", Reason: "is mandatory"},
+			{Field: "classification", Reason: "is mandatory"},
+			{Field: "country", Reason: "is mandatory"},
+			{Field: "vault", Reason: "is mandatory"},
+		},
+	}
+	existingFruit := repository.Fruit{ID: repository.FruitID(fruitID), Name: "Nicosia"}
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: existingFruit,
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Update(context.TODO(), fruitID, fruits.AnyVersion, fruits.NewFruit{})
+
+	assert.Nil(t, got)
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, existingFruit, fruitRepository.repo[fruitID])
+}
+
+func TestPatchFruitSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	newName := "Nicosia 2014 Vulka Bianco  (Etna)"
+	newPrice := float32(21)
+	expectedFruit := fruits.Fruit{
+		ID:             fruitID,
+		Name:           newName,
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Price:          newPrice,
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+		Version:        2,
+	}
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {
+				ID:             repository.FruitID(fruitID),
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Variety:        "White Blend",
+				Vault:          "Nicosia",
+				Country:        "Italy",
+				Classification: "Vulka Bianco",
+				Version:        repository.FirstVersion,
+			},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Patch(context.TODO(), fruitID, fruits.AnyVersion, fruits.FruitPatch{Name: &newName, Price: &newPrice})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedFruit.LastModified = got.LastModified
+
+	assert.Equal(t, &expectedFruit, got)
+	assert.False(t, got.LastModified.IsZero())
+	assert.Equal(t, newName, fruitRepository.repo[fruitID].Name)
+	assert.Equal(t, "White Blend", fruitRepository.repo[fruitID].Variety)
+}
+
+func TestPatchFruitNotFound(t *testing.T) {
+	t.Parallel()
+
+	newName := "Nicosia 2014 Vulka Bianco  (Etna)"
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Patch(context.TODO(), "1234", fruits.AnyVersion, fruits.FruitPatch{Name: &newName})
+
+	assert.Nil(t, got)
+	assert.Equal(t, fruits.ErrFruitNotFound, err)
+}
+
+func TestPatchFruitWithStaleVersion(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	newName := "Nicosia 2014 Vulka Bianco  (Etna)"
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {ID: repository.FruitID(fruitID), Name: "Nicosia", Version: 5},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Patch(context.TODO(), fruitID, 4, fruits.FruitPatch{Name: &newName})
+
+	assert.Nil(t, got)
+	assert.Equal(t, fruits.ErrVersionConflict, err)
+	assert.Equal(t, "Nicosia", fruitRepository.repo[fruitID].Name)
+}
+
+func TestPatchFruitClearingMandatoryField(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	emptyName := ""
+	expectedErr := fruits.ValidationError{
+		Violations: []fruits.Violation{{Field: "name", Reason: "is mandatory"}},
+	}
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {
+				ID:             repository.FruitID(fruitID),
+				Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+				Vault:          "Nicosia",
+				Country:        "Italy",
+				Classification: "Vulka Bianco",
+			},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.Patch(context.TODO(), fruitID, fruits.AnyVersion, fruits.FruitPatch{Name: &emptyName})
+
+	assert.Nil(t, got)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestDeleteFruit(t *testing.T) {
+	t.Parallel()
+
+	fruitID := "1234"
+	fruitRepository := fruitRepoMock{
+		repo: map[string]repository.Fruit{
+			fruitID: {ID: repository.FruitID(fruitID), Version: 2},
+		},
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Debug)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+	ctx := context.TODO()
+
+	err := fruitService.Delete(ctx, fruitID, 1)
+	assert.Equal(t, fruits.ErrVersionConflict, err)
+	assert.Len(t, fruitRepository.repo, 1)
+
+	err = fruitService.Delete(ctx, fruitID, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, fruitRepository.repo)
+
+	err = fruitService.Delete(ctx, fruitID, fruits.AnyVersion)
+	assert.Equal(t, fruits.ErrFruitNotFound, err)
+}
+
 type publisherMock struct{}
 
-func (p *publisherMock) Publish(_ context.Context, event repository.NewFruitEvent) error {
+func (p *publisherMock) Publish(_ context.Context, event repository.Event) error {
 	return nil
 }
 
@@ -209,6 +624,7 @@ type fruitRepoMock struct {
 	err           error
 	repo          map[string]repository.Fruit
 	searchResult  repository.FindFruitsResult
+	searchFilter  repository.FruitFilter
 	dataSetStatus repository.FruitDatasetStatus
 }
 
@@ -232,27 +648,77 @@ func (u *fruitRepoMock) Save(ctx context.Context, fruit repository.NewFruit) (re
 		return "", u.err
 	}
 	id := uuid.New().String()
+	if fruit.ID != "" {
+		id = repository.FruitIDValue(fruit.ID)
+	}
+	if _, ok := u.repo[id]; ok {
+		return "", repository.ErrFruitExists
+	}
 	newFruit := transformNewFruitToFruit(repository.FruitID(id), fruit)
 	u.repo[id] = newFruit
 	return repository.FruitID(id), nil
 }
 
+func (u *fruitRepoMock) SaveAll(ctx context.Context, fruits []repository.NewFruit) []repository.SaveResult {
+	results := make([]repository.SaveResult, 0, len(fruits))
+	for _, fruit := range fruits {
+		id, err := u.Save(ctx, fruit)
+		results = append(results, repository.SaveResult{ID: id, Err: err})
+	}
+	return results
+}
+
 func (u *fruitRepoMock) Update(ctx context.Context, fruit repository.Fruit) error {
 	if u.err != nil {
 		return u.err
 	}
+	storedFruit, ok := u.repo[repository.FruitIDValue(fruit.ID)]
+	if !ok {
+		return repository.ErrFruitNotFound
+	}
+	if storedFruit.Version != fruit.Version {
+		return repository.ErrVersionConflict
+	}
+	fruit.Version++
 	u.repo[repository.FruitIDValue(fruit.ID)] = fruit
 	return nil
 }
 
+func (u *fruitRepoMock) Delete(ctx context.Context, fruitID repository.FruitID, version int64) error {
+	if u.err != nil {
+		return u.err
+	}
+	storedFruit, ok := u.repo[repository.FruitIDValue(fruitID)]
+	if !ok {
+		return repository.ErrFruitNotFound
+	}
+	if storedFruit.Version != version {
+		return repository.ErrVersionConflict
+	}
+	delete(u.repo, repository.FruitIDValue(fruitID))
+	return nil
+}
+
 func (u *fruitRepoMock) SearchWithFilters(ctx context.Context, filter repository.FruitFilter) (repository.FindFruitsResult, error) {
 	var result repository.FindFruitsResult
 	if u.err != nil {
 		return result, u.err
 	}
+	u.searchFilter = filter
 	return u.searchResult, nil
 }
 
+func (u *fruitRepoMock) ListFruits(_ context.Context, _ *repository.Cursor, _ int) (repository.FruitPage, error) {
+	if u.err != nil {
+		return repository.FruitPage{}, u.err
+	}
+	page := repository.FruitPage{Fruits: make([]repository.Fruit, 0, len(u.repo))}
+	for _, fruit := range u.repo {
+		page.Fruits = append(page.Fruits, fruit)
+	}
+	return page, nil
+}
+
 func (u *fruitRepoMock) DatasetStatus(ctx context.Context) (repository.FruitDatasetStatus, error) {
 	var result repository.FruitDatasetStatus
 	if u.err != nil {
@@ -277,5 +743,6 @@ func transformNewFruitToFruit(fruitID repository.FruitID, newFruit repository.Ne
 		Classification: newFruit.Classification,
 		LocalName:      newFruit.LocalName,
 		WikiPage:       newFruit.WikiPage,
+		Version:        repository.FirstVersion,
 	}
 }
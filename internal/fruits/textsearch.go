@@ -0,0 +1,188 @@
+package fruits
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+)
+
+const (
+	// maxQueryLength is the longest full-text query allowed.
+	maxQueryLength = 200
+	// rebuildPageSize is the number of fruits read at once to rebuild the text index.
+	rebuildPageSize = 500
+)
+
+var errTextSearchUnavailable = InvalidFilterError{Filter: "q", Reason: "full-text search is not available"}
+
+// WithTextIndex sets the index of full-text searches, the service keeps it in
+// sync when fruits are created, changed or deleted.
+func WithTextIndex(index TextIndex) ServiceOption {
+	return func(s *Service) {
+		s.textIndex = index
+	}
+}
+
+// RebuildTextIndex indexes every fruit of the repository in a fresh index
+// that replaces the current one once every fruit was read, it returns the
+// number of fruits indexed.
+func (s *Service) RebuildTextIndex(ctx context.Context) (int, error) {
+	if s.textIndex == nil {
+		return 0, nil
+	}
+
+	var indexed int
+
+	err := s.textIndex.Rebuild(func(index func(fruit repository.Fruit)) error {
+		var after *repository.Cursor
+
+		for {
+			page, err := s.fruitRepository.ListFruits(ctx, after, rebuildPageSize)
+			if err != nil {
+				s.logger.Error(
+					"fruits could not be read to rebuild the text index",
+					loggers.Fields{
+						"method":  "Service.RebuildTextIndex",
+						"indexed": indexed,
+						"error":   err,
+					},
+				)
+
+				return ErrDataAccess
+			}
+
+			for _, fruit := range page.Fruits {
+				index(fruit)
+			}
+
+			indexed += len(page.Fruits)
+
+			if page.Next == nil {
+				return nil
+			}
+
+			after = page.Next
+		}
+	})
+	if err != nil {
+		s.logger.Error(
+			"text index was not rebuilt",
+			loggers.Fields{
+				"method":  "Service.RebuildTextIndex",
+				"indexed": indexed,
+				"error":   err,
+			},
+		)
+
+		return indexed, err
+	}
+
+	s.logger.Info(
+		"text index was rebuilt",
+		loggers.Fields{
+			"method":  "Service.RebuildTextIndex",
+			"indexed": indexed,
+		},
+	)
+
+	return indexed, nil
+}
+
+// searchText searches the fruits that match the filter query and the rest of
+// its filters. Results are paged by offset, so their cursors hold the position
+// of the next page, and the repository reads only the page if it can.
+func (s *Service) searchText(ctx context.Context, givenFilter SearchFruitFilter) (*SearchFruitsResult, error) {
+	if s.textIndex == nil {
+		return nil, errTextSearchUnavailable
+	}
+
+	offset, err := s.textSearchOffset(givenFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SearchFruitsResult{
+		Fruits: make([]FruitItem, 0),
+		Start:  givenFilter.Start,
+		Count:  givenFilter.Count,
+		Facets: toFacets(repository.NewFacetCounter(givenFilter.Facets).Facets()),
+	}
+
+	matches := s.textIndex.Search(givenFilter.Query)
+	if len(matches) == 0 {
+		return &result, nil
+	}
+
+	filters := givenFilter.toRepositoryFilters()
+	filters.Start = offset + 1
+	filters.Count = givenFilter.Count
+	filters.IDs = make([]repository.FruitID, len(matches))
+
+	for index, match := range matches {
+		filters.IDs[index] = match.ID
+	}
+
+	repoResult, err := s.fruitRepository.SearchWithFilters(ctx, filters)
+	if err != nil {
+		s.logger.Error(
+			"something goes wrong searching fruits by text",
+			loggers.Fields{
+				"method": "Service.searchText",
+				"filter": givenFilter,
+				"error":  err,
+			},
+		)
+
+		return nil, ErrDataAccess
+	}
+
+	result = toSearchFruitsResult(repository.FindFruitsResult{
+		Fruits: repoResult.Fruits,
+		Total:  repoResult.Total,
+		Start:  givenFilter.Start,
+		Count:  givenFilter.Count,
+		Facets: repoResult.Facets,
+	})
+
+	last := offset + givenFilter.Count
+	if last < repoResult.Total {
+		result.NextCursor = s.cursors.encode(givenFilter, &repository.Cursor{Key: strconv.Itoa(last)})
+	}
+
+	return &result, nil
+}
+
+// textSearchOffset returns the position of the first fruit of the page.
+func (s *Service) textSearchOffset(givenFilter SearchFruitFilter) (int, error) {
+	if givenFilter.Cursor == "" {
+		return givenFilter.Start - 1, nil
+	}
+
+	position, err := s.cursors.decode(givenFilter)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(position.Key)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return offset, nil
+}
+
+// indexFruit adds the given fruit to the text index if there is one.
+func (s *Service) indexFruit(fruit repository.Fruit) {
+	if s.textIndex != nil {
+		s.textIndex.Index(fruit)
+	}
+}
+
+// removeFromIndex removes the fruit with the given id from the text index if there is one.
+func (s *Service) removeFromIndex(fruitID string) {
+	if s.textIndex != nil {
+		s.textIndex.Remove(repository.FruitID(fruitID))
+	}
+}
@@ -0,0 +1,216 @@
+package fruits_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/fernandoocampo/fruits/internal/adapter/loggers"
+	"github.com/fernandoocampo/fruits/internal/adapter/repository"
+	"github.com/fernandoocampo/fruits/internal/fruits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextIndexFollowsFruitChanges(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	textIndex := newTextIndexMock()
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithTextIndex(textIndex))
+	ctx := context.TODO()
+	givenFruit := fruits.NewFruit{
+		Name:           "Nicosia 2013 Vulka Bianco  (Etna)",
+		Variety:        "White Blend",
+		Vault:          "Nicosia",
+		Country:        "Italy",
+		Classification: "Vulka Bianco",
+	}
+
+	fruitID, err := fruitService.Create(ctx, givenFruit)
+	assert.NoError(t, err)
+	assert.Equal(t, givenFruit.Name, textIndex.fruits[repository.FruitID(fruitID)].Name)
+	assert.Equal(t, repository.FirstVersion, textIndex.fruits[repository.FruitID(fruitID)].Version)
+
+	givenFruit.Name = "Nicosia 2014 Vulka Bianco  (Etna)"
+
+	_, err = fruitService.Update(ctx, fruitID, repository.FirstVersion, givenFruit)
+	assert.NoError(t, err)
+	assert.Equal(t, givenFruit.Name, textIndex.fruits[repository.FruitID(fruitID)].Name)
+	assert.Equal(t, repository.FirstVersion+1, textIndex.fruits[repository.FruitID(fruitID)].Version)
+
+	staleFruit := givenFruit
+	staleFruit.Name = "stale"
+
+	_, err = fruitService.Update(ctx, fruitID, repository.FirstVersion, staleFruit)
+	assert.Equal(t, fruits.ErrVersionConflict, err)
+	assert.Equal(t, givenFruit.Name, textIndex.fruits[repository.FruitID(fruitID)].Name)
+
+	err = fruitService.Delete(ctx, fruitID, fruits.AnyVersion)
+	assert.NoError(t, err)
+	assert.Empty(t, textIndex.fruits)
+}
+
+func TestSearchFruitsByText(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+		searchResult: repository.FindFruitsResult{
+			Fruits: []repository.Fruit{{ID: "3"}, {ID: "1"}},
+			Total:  3,
+			Start:  1,
+			Count:  2,
+		},
+	}
+	textIndex := newTextIndexMock()
+	textIndex.matches = []repository.TextMatch{{ID: "3", Score: 3}, {ID: "1", Score: 2}, {ID: "4", Score: 1.5}, {ID: "2", Score: 1}}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithTextIndex(textIndex))
+	ctx := context.TODO()
+	givenFilter := fruits.SearchFruitFilter{Start: 1, Count: 2, Query: "crisp apple", Country: "Italy"}
+
+	firstPage, err := fruitService.SearchFruits(ctx, givenFilter)
+	assert.NoError(t, err)
+	assert.Equal(t, "crisp apple", textIndex.query)
+	assert.Equal(t, []repository.FruitID{"3", "1", "4", "2"}, fruitRepository.searchFilter.IDs)
+	assert.Equal(t, "Italy", fruitRepository.searchFilter.Country)
+	assert.Equal(t, 1, fruitRepository.searchFilter.Start)
+	assert.Equal(t, 2, fruitRepository.searchFilter.Count)
+	assert.Equal(t, 3, firstPage.Total)
+	assert.Equal(t, []string{"3", "1"}, itemIDs(firstPage.Fruits))
+	assert.NotEmpty(t, firstPage.NextCursor)
+
+	givenFilter.Cursor = firstPage.NextCursor
+	fruitRepository.searchResult = repository.FindFruitsResult{
+		Fruits: []repository.Fruit{{ID: "2"}},
+		Total:  3,
+		Start:  3,
+		Count:  2,
+	}
+
+	secondPage, err := fruitService.SearchFruits(ctx, givenFilter)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fruitRepository.searchFilter.Start)
+	assert.Equal(t, []string{"2"}, itemIDs(secondPage.Fruits))
+	assert.Empty(t, secondPage.NextCursor)
+}
+
+func TestSearchFruitsByTextWithoutIndex(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := fruitRepoMock{
+		repo: make(map[string]repository.Fruit),
+	}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger)
+
+	got, err := fruitService.SearchFruits(context.TODO(), fruits.SearchFruitFilter{Start: 1, Count: 2, Query: "apple"})
+
+	assert.Nil(t, got)
+	assert.ErrorAs(t, err, new(fruits.InvalidFilterError))
+}
+
+func TestRebuildTextIndex(t *testing.T) {
+	t.Parallel()
+
+	fruitRepository := pagedRepoMock{
+		fruitRepoMock: fruitRepoMock{
+			repo: make(map[string]repository.Fruit),
+		},
+		pages: []repository.FruitPage{
+			{Fruits: []repository.Fruit{{ID: "1"}, {ID: "2"}}, Next: &repository.Cursor{Key: "2"}},
+			{Fruits: []repository.Fruit{{ID: "3"}}},
+		},
+	}
+	textIndex := newTextIndexMock()
+	// a fruit deleted before the rebuild is not kept.
+	textIndex.fruits["4"] = repository.Fruit{ID: "4"}
+	logger := loggers.NewLoggerWithStdout("", loggers.Error)
+	fruitService := fruits.NewService(&fruitRepository, &publisherMock{}, logger, fruits.WithTextIndex(textIndex))
+
+	got, err := fruitService.RebuildTextIndex(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got)
+	assert.Equal(t, []repository.FruitID{"1", "2", "3"}, indexedIDs(textIndex))
+	assert.Equal(t, []*repository.Cursor{nil, {Key: "2"}}, fruitRepository.afters)
+}
+
+func indexedIDs(textIndex *textIndexMock) []repository.FruitID {
+	ids := make([]repository.FruitID, 0, len(textIndex.fruits))
+	for id := range textIndex.fruits {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+	return ids
+}
+
+func itemIDs(items []fruits.FruitItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+type textIndexMock struct {
+	fruits  map[repository.FruitID]repository.Fruit
+	matches []repository.TextMatch
+	query   string
+}
+
+func newTextIndexMock() *textIndexMock {
+	return &textIndexMock{
+		fruits: make(map[repository.FruitID]repository.Fruit),
+	}
+}
+
+func (t *textIndexMock) Index(fruit repository.Fruit) {
+	t.fruits[fruit.ID] = fruit
+}
+
+func (t *textIndexMock) Remove(fruitID repository.FruitID) {
+	delete(t.fruits, fruitID)
+}
+
+func (t *textIndexMock) Rebuild(load func(index func(fruit repository.Fruit)) error) error {
+	rebuilt := make(map[repository.FruitID]repository.Fruit)
+
+	err := load(func(fruit repository.Fruit) {
+		rebuilt[fruit.ID] = fruit
+	})
+	if err != nil {
+		return err
+	}
+
+	t.fruits = rebuilt
+
+	return nil
+}
+
+func (t *textIndexMock) Search(query string) []repository.TextMatch {
+	t.query = query
+
+	return t.matches
+}
+
+// pagedRepoMock returns its pages one search after another.
+type pagedRepoMock struct {
+	fruitRepoMock
+	pages  []repository.FruitPage
+	afters []*repository.Cursor
+}
+
+func (p *pagedRepoMock) ListFruits(_ context.Context, after *repository.Cursor, _ int) (repository.FruitPage, error) {
+	p.afters = append(p.afters, after)
+	page := p.pages[0]
+	p.pages = p.pages[1:]
+
+	return page, nil
+}